@@ -7,13 +7,15 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/madflojo/mockitout/config"
 	"github.com/madflojo/mockitout/mocks"
-	"github.com/madflojo/testcerts"
+	"github.com/madflojo/mockitout/pact"
+	"github.com/madflojo/mockitout/variable"
 	"github.com/sirupsen/logrus"
 )
 
@@ -31,6 +33,10 @@ var cfg config.Config
 // log is used across the app package for logging.
 var log *logrus.Logger
 
+// grpcSrv is the global reference for the gRPC Server, this is only set when the gRPC
+// listener is enabled.
+var grpcSrv *grpcServer
+
 // mocked is the defined server mocks loaded from config.
 var mocked mocks.Mocks
 
@@ -51,6 +57,27 @@ func Run(c config.Config) error {
 		log.Level = logrus.FatalLevel
 	}
 
+	// Seed random variables
+	variable.Seed = cfg.RandomSeed
+	if cfg.RandomSeed != 0 {
+		log.Infof("Random variables seeded with %d", cfg.RandomSeed)
+	}
+	if cfg.MaxBodySize > 0 {
+		variable.MaxBodySize = cfg.MaxBodySize
+	}
+
+	// Setup the virtual clock
+	err = configureClock()
+	if err != nil {
+		return err
+	}
+
+	// Setup the JWT signing key
+	err = configureJWT()
+	if err != nil {
+		return err
+	}
+
 	// Setup the HTTP Server
 	srv = &server{
 		httpRouter: httprouter.New(),
@@ -65,15 +92,11 @@ func Run(c config.Config) error {
 	if cfg.EnableTLS {
 		if cfg.GenCerts {
 			// Create Test Certs
-			cfg.CertFile = "/tmp/cert"
-			cfg.KeyFile = "/tmp/key"
-			log.Infof("Certificate Generation was enabled, creating new test certs at %s and %s", cfg.CertFile, cfg.KeyFile)
-			err := testcerts.GenerateCertsToFile(cfg.CertFile, cfg.KeyFile)
+			dir, err := generateCerts()
 			if err != nil {
-				return fmt.Errorf("Could not generate test certificates - %s", err)
+				return err
 			}
-			defer os.Remove(cfg.CertFile)
-			defer os.Remove(cfg.KeyFile)
+			defer os.RemoveAll(dir)
 		}
 
 		srv.httpServer.TLSConfig = &tls.Config{
@@ -81,24 +104,107 @@ func Run(c config.Config) error {
 			CipherSuites: []uint16{
 				tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
 				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
 			},
 		}
+		if srv.sni != nil {
+			srv.httpServer.TLSConfig.GetCertificate = srv.sni.GetCertificate
+		}
+		err = configureClientAuth(srv.httpServer.TLSConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Setup HTTP/2, h2c and HTTP/3
+	err = configureProtocols()
+	if err != nil {
+		return err
 	}
 
 	// Register Health Check Handler
 	srv.httpRouter.GET("/health", srv.middleware(srv.Health))
 
+	// Register Virtual Clock Control Handlers
+	if cfg.ClockPath != "" {
+		err = srv.handle("GET", cfg.ClockPath, srv.ClockState)
+		if err != nil {
+			return err
+		}
+		err = srv.handle("POST", cfg.ClockPath+"/:action", srv.ClockControl)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Start Registering Custom Mock HTTP Routes
-	mocked, err = mocks.FromFile(cfg.MocksFile)
+	mocked, err = loadMocks()
 	if err != nil {
 		return err
 	}
-	for m, r := range mocked.Routes {
-		log.Infof("Registering mock %s with path %s", m, r.Path)
-		srv.httpRouter.GET(r.Path, srv.middleware(srv.MockHandler))
-		srv.httpRouter.POST(r.Path, srv.middleware(srv.MockHandler))
-		srv.httpRouter.PUT(r.Path, srv.middleware(srv.MockHandler))
-		srv.httpRouter.DELETE(r.Path, srv.middleware(srv.MockHandler))
+	err = srv.registerMocks()
+	if err != nil {
+		return err
+	}
+	requestClientCerts()
+
+	// Register JWKS Handler, unless mocked
+	if _, ok := mocked.Paths[cfg.JWKSPath]; cfg.JWKSPath != "" && !ok {
+		err = srv.handle("GET", cfg.JWKSPath, srv.JWKS)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Register CA Handler, unless mocked
+	if _, ok := mocked.Paths[cfg.CAPath]; srv.ca != nil && cfg.CAPath != "" && !ok {
+		err = srv.handle("GET", cfg.CAPath, srv.CA)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Register OpenID Connect Provider
+	if mocked.OIDC != nil {
+		err = srv.registerOIDC(mocked.OIDC)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Start gRPC Listener
+	grpcSrv = nil
+	if cfg.GRPCListenAddr != "" {
+		grpcSrv, err = newGRPCServer()
+		if err != nil {
+			return err
+		}
+		lis, err := net.Listen("tcp", cfg.GRPCListenAddr)
+		if err != nil {
+			return fmt.Errorf("could not start gRPC listener - %s", err)
+		}
+		log.Infof("Starting gRPC Listener on %s", cfg.GRPCListenAddr)
+		go func() {
+			err := grpcSrv.server.Serve(lis)
+			if err != nil {
+				log.Errorf("gRPC listener stopped - %s", err)
+			}
+		}()
+	}
+
+	// Start HTTP/3 Listener
+	if srv.http3Server != nil {
+		err = startHTTP3()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Start TLS Profile Listeners
+	err = startTLSProfiles()
+	if err != nil {
+		return err
 	}
 
 	// Start HTTP Listener
@@ -123,7 +229,54 @@ func Run(c config.Config) error {
 	return nil
 }
 
+// loadMocks will load the mocks file and any Pact contracts defined within the
+// configuration.
+func loadMocks() (mocks.Mocks, error) {
+	var m mocks.Mocks
+	var err error
+
+	if cfg.MocksFile != "" || len(cfg.PactFiles) == 0 {
+		m, err = mocks.FromFile(cfg.MocksFile)
+		if err != nil {
+			return m, err
+		}
+	}
+
+	for _, f := range cfg.PactFiles {
+		p, err := pact.FromFile(f)
+		if err != nil {
+			return m, err
+		}
+		routes, err := p.Routes()
+		if err != nil {
+			return m, fmt.Errorf("could not load Pact file %s - %s", f, err)
+		}
+		log.Infof("Loaded %d interactions from Pact file %s", len(routes), f)
+		for k, r := range routes {
+			if _, ok := m.Routes[k]; ok {
+				return m, fmt.Errorf("could not load Pact file %s - route %s is already defined", f, k)
+			}
+			err = m.AddRoute(k, r)
+			if err != nil {
+				return m, fmt.Errorf("could not load Pact file %s - invalid route %s - %s", f, k, err)
+			}
+		}
+	}
+
+	return m, nil
+}
+
 // Stop is used to gracefully shutdown the server.
 func Stop() {
+	if grpcSrv != nil {
+		grpcSrv.server.Stop()
+	}
+	if srv.http3Server != nil {
+		srv.http3Server.Close()
+	}
+	for _, p := range srv.tlsProfiles {
+		p.server.Close()
+	}
+	srv.sessions.closeAll()
 	defer srv.httpServer.Shutdown(context.Background())
 }
@@ -1,13 +1,17 @@
 package app
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"github.com/madflojo/mockitout/config"
 	"github.com/madflojo/mockitout/mocks"
 	"github.com/madflojo/testcerts"
+	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -33,6 +37,20 @@ func TestBadConfigs(t *testing.T) {
 		DisableLogging: true,
 		MocksFile:      "./somefile/hello_world.yml",
 	}
+	cfgs["HTTP/3 without TLS"] = config.Config{
+		EnableTLS:       false,
+		ListenAddr:      "localhost:9000",
+		HTTP3ListenAddr: "localhost:9443",
+		DisableLogging:  true,
+		MocksFile:       "../examples/hello_world.yml",
+	}
+	cfgs["clock path conflicting with mocks"] = config.Config{
+		EnableTLS:      false,
+		ListenAddr:     "localhost:9000",
+		DisableLogging: true,
+		MocksFile:      "../examples/hello_var.yml",
+		ClockPath:      "/echo/param/clock",
+	}
 
 	// Loop through bad configs, creating sub-tests as we go
 	for k, v := range cfgs {
@@ -149,6 +167,7 @@ func TestGenTLSServer(t *testing.T) {
 		t.Fatalf("Could not generate example Mocks file - %s", err)
 	}
 	defer os.Remove(fh.Name())
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
 
 	// Disable Host Checking globally
 	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
@@ -159,6 +178,9 @@ func TestGenTLSServer(t *testing.T) {
 			Debug:          true,
 			EnableTLS:      true,
 			GenCerts:       true,
+			CertHosts:      []string{"localhost", "127.0.0.1", "*.mock.test"},
+			CAFile:         caFile,
+			CAPath:         "/_mockitout/ca.pem",
 			ListenAddr:     "localhost:9000",
 			DisableLogging: true,
 			MocksFile:      fh.Name(),
@@ -183,4 +205,95 @@ func TestGenTLSServer(t *testing.T) {
 			t.Errorf("Unexpected http status code when checking health - %d", r.StatusCode)
 		}
 	})
+
+	t.Run("Check CA", func(t *testing.T) {
+		r, err := http.Get("https://localhost:9000/_mockitout/ca.pem")
+		if err != nil {
+			t.Fatalf("Unexpected error when requesting CA - %s", err)
+		}
+		defer r.Body.Close()
+		served, err := io.ReadAll(r.Body)
+		if err != nil || r.StatusCode != 200 {
+			t.Fatalf("Unexpected response when requesting CA - %d %s", r.StatusCode, err)
+		}
+		written, err := os.ReadFile(caFile)
+		if err != nil {
+			t.Fatalf("Unable to read CA file - %s", err)
+		}
+		if !bytes.Equal(served, written) {
+			t.Fatalf("Served CA does not match the CA file")
+		}
+
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(served) {
+			t.Fatalf("Unable to parse served CA")
+		}
+		cases := map[string]string{
+			"localhost":     "localhost",
+			"api.mock.test": "*.mock.test",
+			"":              "localhost",
+		}
+		for name, cn := range cases {
+			t.Run("SNI "+name, func(t *testing.T) {
+				conn, err := tls.Dial("tcp", "127.0.0.1:9000", &tls.Config{RootCAs: roots, ServerName: name, InsecureSkipVerify: name == ""})
+				if err != nil {
+					t.Fatalf("Unexpected error verifying server certificate - %s", err)
+				}
+				defer conn.Close()
+				leaf := conn.ConnectionState().PeerCertificates[0]
+				if leaf.Subject.CommonName != cn {
+					t.Errorf("Unexpected certificate %s, expected %s", leaf.Subject.CommonName, cn)
+				}
+			})
+		}
+
+		_, err = tls.Dial("tcp", "127.0.0.1:9000", &tls.Config{RootCAs: roots, ServerName: "other.test"})
+		if err == nil {
+			t.Errorf("Expected verification of unknown host to fail, got nil")
+		}
+	})
+}
+
+func TestRunningOIDCServer(t *testing.T) {
+	fh, err := os.CreateTemp("", "mocks_oidc")
+	if err != nil {
+		t.Fatalf("Error creating temp file - %s", err)
+	}
+	defer os.Remove(fh.Name())
+	_, _ = fh.WriteString(`
+oidc:
+  path: "/realms/test"
+`)
+	fh.Close()
+
+	// The JWKS end-point shares the path of the provider's jwks end-point
+	go func() {
+		err := Run(config.Config{
+			ListenAddr:     "localhost:9000",
+			DisableLogging: true,
+			MocksFile:      fh.Name(),
+			JWKSPath:       "/realms/test/oauth2/jwks",
+		})
+		if err != nil && err != ErrShutdown {
+			t.Errorf("Run unexpectedly stopped - %s", err)
+		}
+	}()
+	// Clean up
+	defer Stop()
+
+	// Wait for app to start
+	time.Sleep(10 * time.Second)
+
+	for _, path := range []string{"/realms/test/.well-known/openid-configuration", "/realms/test/oauth2/jwks"} {
+		t.Run(path, func(t *testing.T) {
+			r, err := http.Get("http://localhost:9000" + path)
+			if err != nil {
+				t.Fatalf("Unexpected error when requesting %s - %s", path, err)
+			}
+			defer r.Body.Close()
+			if r.StatusCode != 200 {
+				t.Errorf("Unexpected http status code when requesting %s - %d", path, r.StatusCode)
+			}
+		})
+	}
 }
@@ -0,0 +1,288 @@
+package app
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/madflojo/mockitout/clock"
+	"github.com/madflojo/mockitout/mocks"
+	"github.com/madflojo/mockitout/variable"
+	"github.com/sirupsen/logrus"
+)
+
+// authError is a failed authentication requirement.
+type authError struct {
+	// status is the HTTP status returned, either a 401 or 403.
+	status int
+
+	// code is the error code returned within the response body.
+	code string
+
+	// challenge is the WWW-Authenticate header value, when empty the header is not set.
+	challenge string
+
+	// reason describes the failure.
+	reason string
+}
+
+// authenticate will check the route's authentication requirements, responding with a
+// 401 or 403 and returning false if any are not met.
+func authenticate(w http.ResponseWriter, r *http.Request, route mocks.Route) bool {
+	a := route.Auth
+	if a == nil {
+		return true
+	}
+
+	var e *authError
+	if a.ClientCert != nil {
+		e = checkClientCert(r, a.ClientCert)
+	}
+	if e == nil && a.Basic != nil {
+		e = checkBasic(r, a)
+	}
+	if e == nil && a.APIKey != nil {
+		e = checkAPIKey(r, a)
+	}
+	if e == nil && a.Bearer != nil {
+		e = checkBearer(r, a)
+	}
+	if e == nil {
+		return true
+	}
+
+	log.WithFields(logrus.Fields{
+		"path":       route.Path,
+		"status":     e.status,
+		"request-id": variable.RequestID(r.Context()),
+	}).Infof("Request to %s failed authentication - %s", r.RequestURI, e.reason)
+
+	if e.challenge != "" {
+		w.Header().Set("WWW-Authenticate", e.challenge)
+	}
+	writeJSON(w, e.status, oauthError{e.code, e.reason})
+	return false
+}
+
+// checkClientCert requires a TLS client certificate with an accepted subject.
+func checkClientCert(r *http.Request, c *mocks.ClientCertAuth) *authError {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return &authError{status: http.StatusForbidden, code: "forbidden", reason: "client certificate required"}
+	}
+	subject := r.TLS.PeerCertificates[0].Subject
+	if len(c.Subjects) > 0 && !slices.Contains(c.Subjects, subject.CommonName) && !slices.Contains(c.Subjects, subject.String()) {
+		return &authError{status: http.StatusForbidden, code: "forbidden", reason: fmt.Sprintf("client certificate subject %s not allowed", subject)}
+	}
+	return nil
+}
+
+// checkBasic requires HTTP Basic credentials matching a user.
+func checkBasic(r *http.Request, a *mocks.Auth) *authError {
+	challenge := fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, a.RealmName())
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return &authError{status: http.StatusUnauthorized, code: "unauthorized", challenge: challenge, reason: "basic credentials required"}
+	}
+	want, ok := a.Basic.Users[user]
+	if !ok || subtle.ConstantTimeCompare([]byte(want), []byte(pass)) != 1 {
+		return &authError{status: http.StatusUnauthorized, code: "unauthorized", challenge: challenge, reason: "invalid basic credentials"}
+	}
+	return nil
+}
+
+// checkAPIKey requires an accepted API key within the configured header or query parameter.
+func checkAPIKey(r *http.Request, a *mocks.Auth) *authError {
+	k := a.APIKey
+	var key, in, name string
+	if k.Header == "" && k.Query == "" {
+		key, in, name = r.Header.Get(mocks.DefaultAPIKeyHeader), "header", mocks.DefaultAPIKeyHeader
+	}
+	if k.Header != "" {
+		key, in, name = r.Header.Get(k.Header), "header", k.Header
+	}
+	if key == "" && k.Query != "" {
+		key, in, name = r.URL.Query().Get(k.Query), "query", k.Query
+	}
+
+	challenge := fmt.Sprintf(`APIKey realm=%q, in=%q, name=%q`, a.RealmName(), in, name)
+	if key == "" {
+		return &authError{status: http.StatusUnauthorized, code: "unauthorized", challenge: challenge, reason: "api key required"}
+	}
+	for _, v := range k.Keys {
+		if subtle.ConstantTimeCompare([]byte(v), []byte(key)) == 1 {
+			return nil
+		}
+	}
+	return &authError{status: http.StatusUnauthorized, code: "unauthorized", challenge: challenge, reason: "invalid api key"}
+}
+
+// checkBearer requires an accepted bearer token or valid JWT, following RFC 6750 for
+// error responses.
+func checkBearer(r *http.Request, a *mocks.Auth) *authError {
+	challenge := fmt.Sprintf(`Bearer realm=%q`, a.RealmName())
+	// the scheme is case insensitive
+	var token string
+	if h := r.Header.Get("Authorization"); len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+		token = strings.TrimSpace(h[7:])
+	}
+	if token == "" {
+		return &authError{status: http.StatusUnauthorized, code: "unauthorized", challenge: challenge, reason: "bearer token required"}
+	}
+	invalid := func(reason string) *authError {
+		return &authError{
+			status:    http.StatusUnauthorized,
+			code:      "invalid_token",
+			challenge: fmt.Sprintf(`%s, error="invalid_token", error_description=%q`, challenge, reason),
+			reason:    reason,
+		}
+	}
+
+	for _, t := range a.Bearer.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return nil
+		}
+	}
+	j := a.Bearer.JWT
+	if j == nil {
+		return invalid("invalid token")
+	}
+	verifier := j.Verifier()
+	if verifier == nil {
+		return invalid("no key configured to validate tokens")
+	}
+
+	claims, err := verifier.Verify(token, clock.Now())
+	if err != nil {
+		return invalid(err.Error())
+	}
+	if j.Issuer != "" && claims["iss"] != j.Issuer {
+		return invalid("invalid issuer")
+	}
+	if j.Audience != "" && !claimContains(claims["aud"], j.Audience) {
+		return invalid("invalid audience")
+	}
+
+	scopes := strings.Fields(fmt.Sprint(claims["scope"]))
+	for _, s := range j.Scopes {
+		if !slices.Contains(scopes, s) {
+			return &authError{
+				status:    http.StatusForbidden,
+				code:      "insufficient_scope",
+				challenge: fmt.Sprintf(`%s, error="insufficient_scope", scope=%q`, challenge, strings.Join(j.Scopes, " ")),
+				reason:    "missing scope " + s,
+			}
+		}
+	}
+	for k, v := range j.Claims {
+		if !claimContains(claims[k], v) {
+			return &authError{
+				status:    http.StatusForbidden,
+				code:      "insufficient_scope",
+				challenge: fmt.Sprintf(`%s, error="insufficient_scope"`, challenge),
+				reason:    fmt.Sprintf("claim %s must be %s", k, v),
+			}
+		}
+	}
+	return nil
+}
+
+// claimContains returns true if the claim is the value, or a list including it.
+func claimContains(claim interface{}, value string) bool {
+	if list, ok := claim.([]interface{}); ok {
+		for _, c := range list {
+			if fmt.Sprint(c) == value {
+				return true
+			}
+		}
+		return false
+	}
+	return claim != nil && fmt.Sprint(claim) == value
+}
+
+// clientAuthTypes are the supported CLIENT_AUTH policies.
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"none":    tls.NoClientCert,
+	"request": tls.RequestClientCert,
+	"require": tls.RequireAnyClientCert,
+	"verify":  tls.RequireAndVerifyClientCert,
+}
+
+// configureClientAuth will set the client certificate policy and CAs of the TLS
+// configuration.
+func configureClientAuth(c *tls.Config) error {
+	mode := strings.ToLower(cfg.ClientAuth)
+	if mode == "" {
+		mode = "none"
+		if cfg.ClientCAFile != "" {
+			mode = "verify"
+		}
+	}
+	auth, ok := clientAuthTypes[mode]
+	if !ok {
+		return fmt.Errorf("invalid client auth %q, expected one of none, request, require or verify", cfg.ClientAuth)
+	}
+	c.ClientAuth = auth
+
+	if cfg.ClientCAFile == "" {
+		if auth == tls.RequireAndVerifyClientCert {
+			return fmt.Errorf("client auth verify requires a client CA file")
+		}
+		return nil
+	}
+	b, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return fmt.Errorf("could not read client CA file - %s", err)
+	}
+	c.ClientCAs = x509.NewCertPool()
+	if !c.ClientCAs.AppendCertsFromPEM(b) {
+		return fmt.Errorf("could not load client CA file %s, no PEM certificates found", cfg.ClientCAFile)
+	}
+	if auth != tls.RequireAndVerifyClientCert {
+		log.Warnf("Client certificates are only verified against %s when client auth is verify", cfg.ClientCAFile)
+	}
+	return nil
+}
+
+// clientCertFields will add details of the request's client certificate to the log
+// fields, fields are unchanged when no certificate was presented.
+func clientCertFields(r *http.Request, f logrus.Fields) logrus.Fields {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return f
+	}
+	cert := r.TLS.PeerCertificates[0]
+	f["client-cert-subject"] = cert.Subject.String()
+	f["client-cert-issuer"] = cert.Issuer.String()
+	f["client-cert-serial"] = cert.SerialNumber.String()
+	f["client-cert-verified"] = len(r.TLS.VerifiedChains) > 0
+	return f
+}
+
+// requestClientCerts will have TLS listeners request client certificates when any route
+// authenticates or matches them, unless client auth was explicitly configured. Routes are
+// warned about when the certificates they check are not verified.
+func requestClientCerts() {
+	c := srv.httpServer.TLSConfig
+	if c == nil {
+		return
+	}
+	for name, route := range mocked.Routes {
+		if (route.Auth == nil || route.Auth.ClientCert == nil) && len(route.Match.ClientCert) == 0 {
+			continue
+		}
+		switch {
+		case c.ClientAuth == tls.NoClientCert && cfg.ClientAuth == "":
+			c.ClientAuth = tls.RequestClientCert
+			log.Warnf("Requesting client certificates for route %s, certificates are not verified unless CLIENT_AUTH is verify", name)
+		case c.ClientAuth == tls.NoClientCert:
+			log.Warnf("Route %s checks client certificates but CLIENT_AUTH is none, certificates are not requested", name)
+		case c.ClientAuth != tls.RequireAndVerifyClientCert:
+			log.Warnf("Route %s checks client certificates which are not verified unless CLIENT_AUTH is verify", name)
+		}
+		return
+	}
+}
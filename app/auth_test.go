@@ -0,0 +1,384 @@
+package app
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madflojo/mockitout/clock"
+	"github.com/madflojo/mockitout/config"
+	"github.com/madflojo/mockitout/jwt"
+	"github.com/madflojo/mockitout/mocks"
+)
+
+func TestAuth(t *testing.T) {
+	signer, err := jwt.Generate()
+	if err != nil {
+		t.Fatalf("Unable to generate signer - %s", err)
+	}
+	defer func(s *jwt.Signer) { jwt.Default = s }(jwt.Default)
+	jwt.Default = signer
+
+	sign := func(claims map[string]interface{}) string {
+		token, err := signer.Sign(claims)
+		if err != nil {
+			t.Fatalf("Unable to sign token - %s", err)
+		}
+		return token
+	}
+	exp := clock.Now().Add(time.Hour).Unix()
+	valid := sign(map[string]interface{}{"iss": "https://idp", "aud": []string{"orders", "billing"}, "scope": "orders:read", "role": "admin", "exp": exp})
+
+	m := mocks.Mocks{}
+	m.AddRoute("basic", mocks.Route{
+		Path: "/basic",
+		Auth: &mocks.Auth{Realm: "orders", Basic: &mocks.BasicAuth{Users: map[string]string{"alice": "s3cret"}}},
+		Body: "ok",
+	})
+	m.AddRoute("key", mocks.Route{
+		Path: "/key",
+		Auth: &mocks.Auth{APIKey: &mocks.APIKeyAuth{Keys: []string{"abc"}}},
+		Body: "ok",
+	})
+	m.AddRoute("query-key", mocks.Route{
+		Path: "/query-key",
+		Auth: &mocks.Auth{APIKey: &mocks.APIKeyAuth{Query: "api_key", Keys: []string{"abc"}}},
+		Body: "ok",
+	})
+	m.AddRoute("bearer", mocks.Route{
+		Path: "/bearer",
+		Auth: &mocks.Auth{Bearer: &mocks.BearerAuth{
+			Tokens: []string{"static-token"},
+			JWT: &mocks.JWTAuth{
+				Issuer:   "https://idp",
+				Audience: "orders",
+				Scopes:   []string{"orders:read"},
+				Claims:   map[string]string{"role": "admin"},
+			},
+		}},
+		Body: "ok",
+	})
+	m.AddRoute("websocket", mocks.Route{
+		Path:      "/ws",
+		Auth:      &mocks.Auth{Bearer: &mocks.BearerAuth{Tokens: []string{"static-token"}}},
+		WebSocket: &mocks.WebSocket{},
+	})
+	ts := newTestServer(t, m)
+	defer ts.Close()
+
+	cases := map[string]struct {
+		path      string
+		headers   map[string]string
+		user      string
+		pass      string
+		code      int
+		challenge string
+	}{
+		"Basic":                {path: "/basic", user: "alice", pass: "s3cret", code: 200},
+		"Basic Missing":        {path: "/basic", code: 401, challenge: `Basic realm="orders", charset="UTF-8"`},
+		"Basic Wrong Password": {path: "/basic", user: "alice", pass: "wrong", code: 401, challenge: `Basic realm="orders", charset="UTF-8"`},
+		"Basic Unknown User":   {path: "/basic", user: "bob", pass: "s3cret", code: 401, challenge: `Basic realm="orders", charset="UTF-8"`},
+		"API Key":              {path: "/key", headers: map[string]string{"X-API-Key": "abc"}, code: 200},
+		"API Key Missing":      {path: "/key", code: 401, challenge: `APIKey realm="MockItOut", in="header", name="X-API-Key"`},
+		"API Key Invalid":      {path: "/key", headers: map[string]string{"X-API-Key": "xyz"}, code: 401, challenge: `APIKey realm="MockItOut", in="header", name="X-API-Key"`},
+		"API Key Query":        {path: "/query-key?api_key=abc", code: 200},
+		"API Key Query Header": {path: "/query-key", headers: map[string]string{"X-API-Key": "abc"}, code: 401},
+		"Static Token":         {path: "/bearer", headers: map[string]string{"Authorization": "Bearer static-token"}, code: 200},
+		"JWT":                  {path: "/bearer", headers: map[string]string{"Authorization": "bearer " + valid}, code: 200},
+		"Bearer Missing":       {path: "/bearer", code: 401, challenge: `Bearer realm="MockItOut"`},
+		"Bearer Invalid":       {path: "/bearer", headers: map[string]string{"Authorization": "Bearer nope"}, code: 401, challenge: `Bearer realm="MockItOut", error="invalid_token", error_description="invalid token"`},
+		"JWT Expired": {
+			path:      "/bearer",
+			headers:   map[string]string{"Authorization": "Bearer " + sign(map[string]interface{}{"iss": "https://idp", "aud": "orders", "exp": clock.Now().Add(-time.Minute).Unix()})},
+			code:      401,
+			challenge: `Bearer realm="MockItOut", error="invalid_token", error_description="token is expired or not yet valid"`,
+		},
+		"JWT Wrong Issuer": {
+			path:    "/bearer",
+			headers: map[string]string{"Authorization": "Bearer " + sign(map[string]interface{}{"iss": "https://other", "aud": "orders", "exp": exp})},
+			code:    401,
+		},
+		"JWT Wrong Audience": {
+			path:    "/bearer",
+			headers: map[string]string{"Authorization": "Bearer " + sign(map[string]interface{}{"iss": "https://idp", "aud": "billing", "exp": exp})},
+			code:    401,
+		},
+		"JWT Missing Scope": {
+			path:      "/bearer",
+			headers:   map[string]string{"Authorization": "Bearer " + sign(map[string]interface{}{"iss": "https://idp", "aud": "orders", "scope": "orders:write", "role": "admin", "exp": exp})},
+			code:      403,
+			challenge: `Bearer realm="MockItOut", error="insufficient_scope", scope="orders:read"`,
+		},
+		"JWT Missing Claim": {
+			path:    "/bearer",
+			headers: map[string]string{"Authorization": "Bearer " + sign(map[string]interface{}{"iss": "https://idp", "aud": "orders", "scope": "orders:read", "role": "user", "exp": exp})},
+			code:    403,
+		},
+		"WebSocket": {path: "/ws", code: 401, challenge: `Bearer realm="MockItOut"`},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			req, err := http.NewRequest("GET", ts.URL+v.path, nil)
+			if err != nil {
+				t.Fatalf("Unable to create request - %s", err)
+			}
+			for h, val := range v.headers {
+				req.Header.Set(h, val)
+			}
+			if v.user != "" {
+				req.SetBasicAuth(v.user, v.pass)
+			}
+
+			r, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+			}
+			defer r.Body.Close()
+			if r.StatusCode != v.code {
+				t.Fatalf("Unexpected http status code - %d", r.StatusCode)
+			}
+			if v.challenge != "" && r.Header.Get("WWW-Authenticate") != v.challenge {
+				t.Errorf("Unexpected WWW-Authenticate header %q", r.Header.Get("WWW-Authenticate"))
+			}
+			if v.code != 200 && !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+				t.Errorf("Unexpected content type %q", r.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestClientCertAuth(t *testing.T) {
+	m := mocks.Mocks{}
+	m.AddRoute("mtls", mocks.Route{
+		Path: "/mtls",
+		Auth: &mocks.Auth{ClientCert: &mocks.ClientCertAuth{Subjects: []string{"orders-client"}}},
+		Body: "ok",
+	})
+	ts := newTestServer(t, m)
+	ts.Close()
+	ts = httptest.NewUnstartedServer(srv.httpRouter)
+	ts.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	cases := map[string]struct {
+		certs []tls.Certificate
+		code  int
+	}{
+		"Allowed Subject": {certs: []tls.Certificate{testCert("orders-client", nil)}, code: 200},
+		"Other Subject":   {certs: []tls.Certificate{testCert("billing-client", nil)}, code: 403},
+		"No Certificate":  {code: 403},
+	}
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			c := ts.Client()
+			transport := c.Transport.(*http.Transport).Clone()
+			transport.TLSClientConfig.Certificates = v.certs
+			c.Transport = transport
+
+			r, err := c.Get(ts.URL + "/mtls")
+			if err != nil {
+				t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+			}
+			defer r.Body.Close()
+			if r.StatusCode != v.code {
+				t.Errorf("Unexpected http status code - %d", r.StatusCode)
+			}
+		})
+	}
+}
+
+func TestConfigureClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := testCert("Test CA", nil)
+	caFile := filepath.Join(dir, "ca.pem")
+	err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]}), 0600)
+	if err != nil {
+		t.Fatalf("Unable to write CA file - %s", err)
+	}
+	badFile := filepath.Join(dir, "bad.pem")
+	err = os.WriteFile(badFile, []byte("not a certificate"), 0600)
+	if err != nil {
+		t.Fatalf("Unable to write CA file - %s", err)
+	}
+	defer func() { cfg = config.Config{} }()
+
+	cases := map[string]struct {
+		mode   string
+		caFile string
+		auth   tls.ClientAuthType
+		err    bool
+	}{
+		"Default":           {auth: tls.NoClientCert},
+		"Default With CA":   {caFile: caFile, auth: tls.RequireAndVerifyClientCert},
+		"None":              {mode: "none", auth: tls.NoClientCert},
+		"Request":           {mode: "request", auth: tls.RequestClientCert},
+		"Require":           {mode: "Require", auth: tls.RequireAnyClientCert},
+		"Verify":            {mode: "verify", caFile: caFile, auth: tls.RequireAndVerifyClientCert},
+		"Verify Without CA": {mode: "verify", err: true},
+		"Unknown Mode":      {mode: "optional", err: true},
+		"Missing CA File":   {mode: "verify", caFile: filepath.Join(dir, "missing.pem"), err: true},
+		"Invalid CA File":   {caFile: badFile, err: true},
+		"Request With CA":   {mode: "request", caFile: caFile, auth: tls.RequestClientCert},
+	}
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			cfg = config.Config{ClientAuth: v.mode, ClientCAFile: v.caFile}
+			c := &tls.Config{}
+			err := configureClientAuth(c)
+			if v.err {
+				if err == nil {
+					t.Fatalf("Expected error configuring client auth, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error configuring client auth - %s", err)
+			}
+			if c.ClientAuth != v.auth {
+				t.Errorf("Unexpected client auth %s, expected %s", c.ClientAuth, v.auth)
+			}
+			if (v.caFile != "") != (c.ClientCAs != nil) {
+				t.Errorf("Unexpected client CAs %v", c.ClientCAs)
+			}
+		})
+	}
+}
+
+func TestRequestClientCerts(t *testing.T) {
+	defer func(s *server, m mocks.Mocks) { srv, mocked, cfg = s, m, config.Config{} }(srv, mocked)
+
+	cases := map[string]struct {
+		mode  string
+		route mocks.Route
+		auth  tls.ClientAuthType
+	}{
+		"Client Cert Auth":  {route: mocks.Route{Path: "/mtls", Auth: &mocks.Auth{ClientCert: &mocks.ClientCertAuth{}}}, auth: tls.RequestClientCert},
+		"Client Cert Match": {route: mocks.Route{Path: "/mtls", Match: mocks.Match{ClientCert: map[string]mocks.Matcher{"cn": {Value: "client"}}}}, auth: tls.RequestClientCert},
+		"Explicit None":     {mode: "none", route: mocks.Route{Path: "/mtls", Auth: &mocks.Auth{ClientCert: &mocks.ClientCertAuth{}}}, auth: tls.NoClientCert},
+		"No Client Certs":   {route: mocks.Route{Path: "/hello"}, auth: tls.NoClientCert},
+	}
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			cfg = config.Config{ClientAuth: v.mode}
+			mocked = mocks.Mocks{}
+			mocked.AddRoute("route", v.route)
+			srv = &server{httpServer: &http.Server{TLSConfig: &tls.Config{}}}
+			err := configureClientAuth(srv.httpServer.TLSConfig)
+			if err != nil {
+				t.Fatalf("Unexpected error configuring client auth - %s", err)
+			}
+
+			requestClientCerts()
+			if c := srv.httpServer.TLSConfig.ClientAuth; c != v.auth {
+				t.Errorf("Unexpected client auth %s, expected %s", c, v.auth)
+			}
+		})
+	}
+}
+
+func TestVerifiedClientCert(t *testing.T) {
+	ca := testCert("Test CA", nil)
+	dir := t.TempDir()
+	cfg = config.Config{ClientAuth: "verify", ClientCAFile: filepath.Join(dir, "ca.pem")}
+	defer func() { cfg = config.Config{} }()
+	err := os.WriteFile(cfg.ClientCAFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]}), 0600)
+	if err != nil {
+		t.Fatalf("Unable to write CA file - %s", err)
+	}
+
+	m := mocks.Mocks{}
+	m.AddRoute("a-admin", mocks.Route{
+		Path:  "/whoami",
+		Match: mocks.Match{ClientCert: map[string]mocks.Matcher{"cn": {Value: "admin-client"}, "verified": {Value: true}}},
+		Body:  "admin {{ request.tls.client_verified }}",
+	})
+	m.AddRoute("b-other", mocks.Route{
+		Path: "/whoami",
+		Body: "{{ request.tls.client_cn }}",
+	})
+	ts := newTestServer(t, m)
+	ts.Close()
+	ts = httptest.NewUnstartedServer(srv.httpRouter)
+	ts.TLS = &tls.Config{}
+	err = configureClientAuth(ts.TLS)
+	if err != nil {
+		t.Fatalf("Unable to configure client auth - %s", err)
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	cases := map[string]struct {
+		certs []tls.Certificate
+		body  string
+		err   bool
+	}{
+		"Admin":          {certs: []tls.Certificate{testCert("admin-client", &ca)}, body: "admin true"},
+		"Other":          {certs: []tls.Certificate{testCert("orders-client", &ca)}, body: "orders-client"},
+		"Untrusted":      {certs: []tls.Certificate{testCert("admin-client", nil)}, err: true},
+		"No Certificate": {err: true},
+	}
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			c := ts.Client()
+			transport := c.Transport.(*http.Transport).Clone()
+			transport.TLSClientConfig.Certificates = v.certs
+			c.Transport = transport
+
+			r, err := c.Get(ts.URL + "/whoami")
+			if v.err {
+				if err == nil {
+					r.Body.Close()
+					t.Fatalf("Expected TLS handshake to fail, got %d", r.StatusCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+			}
+			defer r.Body.Close()
+			b, _ := io.ReadAll(r.Body)
+			if string(b) != v.body {
+				t.Errorf("Unexpected body %q, expected %q", b, v.body)
+			}
+		})
+	}
+}
+
+// testCert will create a client certificate with the common name, signed by the parent
+// or self-signed when the parent is nil.
+func testCert(cn string, parent *tls.Certificate) tls.Certificate {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	signer, signerKey := tmpl, interface{}(key)
+	if parent != nil {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
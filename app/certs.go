@@ -0,0 +1,86 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/madflojo/mockitout/certs"
+)
+
+// CA is used to publish the PEM encoded CA of generated certificates, allowing clients
+// to trust it.
+func (s *server) CA(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(s.ca.PEM())
+}
+
+// generateCerts will create a CA and issue certificates for the configured hosts within
+// a temporary directory, which is returned for clean up. A certificate including every
+// host is used as the CertFile and KeyFile, and one per host is selected by SNI.
+func generateCerts() (string, error) {
+	hosts := certHosts()
+	ca, err := certs.NewCA()
+	if err != nil {
+		return "", fmt.Errorf("Could not generate test certificates - %s", err)
+	}
+	cert, err := ca.Issue(hosts...)
+	if err != nil {
+		return "", fmt.Errorf("Could not generate test certificates - %s", err)
+	}
+	sni := certs.NewSNI(cert)
+	for _, h := range hosts {
+		c, err := ca.Issue(h)
+		if err != nil {
+			return "", fmt.Errorf("Could not generate test certificate for %s - %s", h, err)
+		}
+		sni.Add(h, c)
+	}
+
+	dir, err := os.MkdirTemp("", "mockitout-certs-")
+	if err != nil {
+		return "", fmt.Errorf("Could not create certificate directory - %s", err)
+	}
+	cfg.CertFile = filepath.Join(dir, "cert.pem")
+	cfg.KeyFile = filepath.Join(dir, "key.pem")
+	err = certs.WriteFiles(cert, cfg.CertFile, cfg.KeyFile)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, "ca.pem"), ca.PEM(), 0644)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("Could not write test certificates - %s", err)
+	}
+	log.Infof("Certificate Generation was enabled, issued certificates for %s from %s within %s", strings.Join(hosts, ", "), ca.Certificate().Subject.CommonName, dir)
+
+	if cfg.CAFile != "" {
+		err = os.WriteFile(cfg.CAFile, ca.PEM(), 0644)
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("Could not write CA file - %s", err)
+		}
+		log.Infof("Wrote CA of generated certificates to %s", cfg.CAFile)
+	}
+
+	srv.ca = ca
+	srv.sni = sni
+	return dir, nil
+}
+
+// certHosts returns the hosts of generated certificates, defaulting to localhost.
+func certHosts() []string {
+	hosts := make([]string, 0, len(cfg.CertHosts))
+	for _, h := range cfg.CertHosts {
+		if h = strings.TrimSpace(h); h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+	if len(hosts) == 0 {
+		hosts = []string{"localhost"}
+	}
+	return hosts
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/madflojo/mockitout/clock"
+)
+
+// clockRequest is the optional body of clock control requests.
+type clockRequest struct {
+	// Now is the RFC 3339 time the clock is set to.
+	Now string `json:"now"`
+
+	// Freeze stops the clock once set.
+	Freeze bool `json:"freeze"`
+
+	// Duration is the amount of time the clock is advanced by, e.g. 24h.
+	Duration string `json:"duration"`
+}
+
+// ClockState is used to return the current state of the virtual clock.
+func (s *server) ClockState(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	writeClockState(w)
+}
+
+// ClockControl is used to set, freeze, resume, advance and reset the virtual clock.
+func (s *server) ClockControl(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var req clockRequest
+	err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req)
+	if err != nil && err != io.EOF {
+		http.Error(w, fmt.Sprintf("invalid clock request - %s", err), http.StatusBadRequest)
+		return
+	}
+
+	switch ps.ByName("action") {
+	case "set":
+		t, err := time.Parse(time.RFC3339Nano, req.Now)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid clock time - %s", err), http.StatusBadRequest)
+			return
+		}
+		// freeze first so the clock holds exactly the given time
+		if req.Freeze {
+			clock.Default.Freeze()
+		}
+		clock.Default.Set(t)
+	case "freeze":
+		clock.Default.Freeze()
+	case "resume":
+		clock.Default.Resume()
+	case "advance":
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d < 0 {
+			http.Error(w, fmt.Sprintf("invalid clock duration %q", req.Duration), http.StatusBadRequest)
+			return
+		}
+		clock.Default.Advance(d)
+	case "reset":
+		clock.Default.Reset()
+	default:
+		http.Error(w, fmt.Sprintf("unknown clock action %q", ps.ByName("action")), http.StatusNotFound)
+		return
+	}
+
+	log.Infof("Virtual clock %s, now %s", ps.ByName("action"), clock.Now().Format(time.RFC3339))
+	writeClockState(w)
+}
+
+// writeClockState will respond with the state of the virtual clock as JSON.
+func writeClockState(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, clock.Default.State())
+}
+
+// configureClock will set the initial state of the virtual clock from the configuration.
+func configureClock() error {
+	clock.Default.Reset()
+	if cfg.ClockFrozen {
+		clock.Default.Freeze()
+	}
+	if cfg.ClockTime != "" {
+		t, err := time.Parse(time.RFC3339Nano, cfg.ClockTime)
+		if err != nil {
+			return fmt.Errorf("could not parse clock time %s - %s", cfg.ClockTime, err)
+		}
+		clock.Default.Set(t)
+	}
+	return nil
+}
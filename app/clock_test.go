@@ -0,0 +1,142 @@
+package app
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madflojo/mockitout/clock"
+	"github.com/madflojo/mockitout/mocks"
+)
+
+func TestClockControl(t *testing.T) {
+	defer clock.Default.Reset()
+
+	m := mocks.Mocks{}
+	m.AddRoute("now", mocks.Route{
+		Path: "/now",
+		Body: `{{ now }} {{ $timestamp }}`,
+	})
+	m.AddRoute("delayed", mocks.Route{
+		Path:   "/delayed",
+		Chunks: []mocks.Chunk{{Body: "a"}, {Body: "b", Delay: time.Hour}},
+	})
+	ts := newTestServer(t, m)
+	defer ts.Close()
+	srv.httpRouter.GET("/_mockitout/clock", srv.middleware(srv.ClockState))
+	srv.httpRouter.POST("/_mockitout/clock/:action", srv.middleware(srv.ClockControl))
+
+	control := func(t *testing.T, action, body string) (int, clock.State) {
+		r, err := ts.Client().Post(ts.URL+"/_mockitout/clock/"+action, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Unexpected error when requesting clock control - %s", err)
+		}
+		defer r.Body.Close()
+		var s clock.State
+		if r.StatusCode == 200 {
+			err = json.NewDecoder(r.Body).Decode(&s)
+			if err != nil {
+				t.Fatalf("Unable to parse clock state - %s", err)
+			}
+		}
+		return r.StatusCode, s
+	}
+
+	get := func(t *testing.T, path string) string {
+		r, err := ts.Client().Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+		}
+		defer r.Body.Close()
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Unable to read HTTP response body - %s", err)
+		}
+		return string(body)
+	}
+
+	t.Run("Set And Freeze", func(t *testing.T) {
+		code, s := control(t, "set", `{"now": "2030-01-01T23:59:59Z", "freeze": true}`)
+		if code != 200 || !s.Frozen || s.Now.Format(time.RFC3339) != "2030-01-01T23:59:59Z" {
+			t.Fatalf("Unexpected clock control response %d %+v", code, s)
+		}
+		body := get(t, "/now")
+		if body != "2030-01-01T23:59:59Z 1893542399" {
+			t.Errorf("Unexpected body %q", body)
+		}
+	})
+
+	t.Run("Advance", func(t *testing.T) {
+		code, s := control(t, "advance", `{"duration": "1s"}`)
+		if code != 200 || s.Now.Format(time.RFC3339) != "2030-01-02T00:00:00Z" {
+			t.Fatalf("Unexpected clock control response %d %+v", code, s)
+		}
+		body := get(t, "/now")
+		if body != "2030-01-02T00:00:00Z 1893542400" {
+			t.Errorf("Unexpected body %q", body)
+		}
+	})
+
+	t.Run("State", func(t *testing.T) {
+		r, err := ts.Client().Get(ts.URL + "/_mockitout/clock")
+		if err != nil {
+			t.Fatalf("Unexpected error when requesting clock state - %s", err)
+		}
+		defer r.Body.Close()
+		var s clock.State
+		err = json.NewDecoder(r.Body).Decode(&s)
+		if err != nil || !s.Frozen || s.Now.Format(time.RFC3339) != "2030-01-02T00:00:00Z" {
+			t.Errorf("Unexpected clock state %+v - %v", s, err)
+		}
+	})
+
+	t.Run("Advance Releases Delays", func(t *testing.T) {
+		done := make(chan string, 1)
+		go func() {
+			done <- get(t, "/delayed")
+		}()
+		time.Sleep(100 * time.Millisecond)
+		control(t, "advance", `{"duration": "1h"}`)
+		select {
+		case body := <-done:
+			if body != "ab" {
+				t.Errorf("Unexpected body %q", body)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("Delayed chunk was not released by advancing the clock")
+		}
+	})
+
+	t.Run("Resume And Reset", func(t *testing.T) {
+		code, s := control(t, "resume", "")
+		if code != 200 || s.Frozen {
+			t.Errorf("Unexpected clock control response %d %+v", code, s)
+		}
+		code, s = control(t, "reset", "")
+		if code != 200 || s.Frozen || s.Now.Year() != time.Now().Year() {
+			t.Errorf("Unexpected clock control response %d %+v", code, s)
+		}
+	})
+
+	cases := map[string]struct {
+		action string
+		body   string
+		code   int
+	}{
+		"Invalid Time":     {action: "set", body: `{"now": "tomorrow"}`, code: 400},
+		"Invalid Duration": {action: "advance", body: `{"duration": "1 day"}`, code: 400},
+		"Negative":         {action: "advance", body: `{"duration": "-1h"}`, code: 400},
+		"Invalid Body":     {action: "freeze", body: `{`, code: 400},
+		"Unknown Action":   {action: "rewind", code: 404},
+	}
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			code, _ := control(t, v.action, v.body)
+			if code != v.code {
+				t.Errorf("Unexpected http status code - %d", code)
+			}
+		})
+	}
+}
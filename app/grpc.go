@@ -0,0 +1,277 @@
+package app
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/madflojo/mockitout/descriptors"
+	"github.com/madflojo/mockitout/variable"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// grpcServer is used as an interface for managing the gRPC server.
+type grpcServer struct {
+	// server is the primary gRPC server.
+	server *grpc.Server
+
+	// registry holds the descriptors of the mocked services.
+	registry *descriptors.Registry
+}
+
+// newGRPCServer will load the service descriptors, validate the mocked gRPC methods
+// and create a gRPC server which handles all methods dynamically.
+func newGRPCServer() (*grpcServer, error) {
+	var reg *descriptors.Registry
+	var err error
+	switch {
+	case cfg.ProtoDescriptorSet != "":
+		reg, err = descriptors.FromDescriptorSet(cfg.ProtoDescriptorSet)
+	case len(cfg.ProtoFiles) > 0:
+		reg, err = descriptors.FromProtoFiles(cfg.ProtoFiles, cfg.ProtoImportPaths)
+	default:
+		err = fmt.Errorf("gRPC listener requires proto files or a descriptor set")
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Loaded gRPC services %v", reg.Services())
+
+	for k, g := range mocked.GRPC {
+		md, err := reg.Method(g.Method)
+		if err != nil {
+			return nil, fmt.Errorf("invalid grpc mock %s - %s", k, err)
+		}
+		if md.IsStreamingClient() {
+			return nil, fmt.Errorf("invalid grpc mock %s - client streaming methods are not supported", k)
+		}
+		if g.Status != nil {
+			_, err := statusCode(g.Status.Code)
+			if err != nil {
+				return nil, fmt.Errorf("invalid grpc mock %s - %s", k, err)
+			}
+		}
+		log.Infof("Registering gRPC mock %s with method %s", k, g.Method)
+	}
+
+	g := &grpcServer{registry: reg}
+	opts := []grpc.ServerOption{grpc.UnknownServiceHandler(g.handler)}
+	if cfg.EnableTLS {
+		c, err := grpcTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(c)))
+	}
+	g.server = grpc.NewServer(opts...)
+	return g, nil
+}
+
+// grpcTLSConfig returns a copy of the HTTP server's TLS configuration, sharing its
+// certificates, cipher suites and client certificate policy with the gRPC listener.
+func grpcTLSConfig() (*tls.Config, error) {
+	c := srv.httpServer.TLSConfig.Clone()
+	c.NextProtos = nil
+	if c.GetCertificate == nil && len(c.Certificates) == 0 {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load gRPC TLS certificates - %s", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	return c, nil
+}
+
+// handler is used to handle all gRPC requests to the Mock Server. Requests are
+// decoded using the service descriptors and converted to JSON for matching and
+// variables.
+func (g *grpcServer) handler(_ interface{}, stream grpc.ServerStream) error {
+	method, ok := grpc.MethodFromServerStream(stream)
+	if !ok {
+		return status.Error(codes.Internal, "unable to determine method")
+	}
+
+	md, err := g.registry.Method(method)
+	if err != nil {
+		return status.Errorf(codes.Unimplemented, "method %s not found - %s", method, err)
+	}
+
+	// Read and convert request message to JSON
+	in := dynamicpb.NewMessage(md.Input())
+	err = stream.RecvMsg(in)
+	if err != nil {
+		return err
+	}
+	body, err := protojson.Marshal(in)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to convert request to JSON - %s", err)
+	}
+
+	// Create an HTTP request representation for matching and variables
+	r, err := http.NewRequestWithContext(stream.Context(), "POST", method, bytes.NewReader(body))
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to create request - %s", err)
+	}
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &info.State
+		}
+	}
+	if meta, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		for k, v := range meta {
+			for _, val := range v {
+				r.Header.Add(k, val)
+			}
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"method":  method,
+		"headers": r.Header,
+	}).Debugf("gRPC Request to %s", method)
+
+	name, mock, ok := mocked.LookupGRPC(method, r)
+	if !ok {
+		log.Errorf("gRPC method %s not matched by any mock within Mocks file", method)
+		return status.Errorf(codes.Unimplemented, "method %s not mocked", method)
+	}
+
+	log.WithFields(logrus.Fields{
+		"method": method,
+		"mock":   name,
+	}).Infof("Mocked gRPC method found for %s", method)
+
+	ctx := variable.NewVariableInstance(r, nil, nil)
+	ctx.SetStrict(mock.IsStrict(cfg.StrictVariables))
+
+	// Send response metadata
+	if len(mock.Headers) > 0 {
+		meta := metadata.MD{}
+		var errs []error
+		for k, v := range mock.Headers {
+			val, err := renderGRPC(method, ctx, v, mock.CompiledHeader(k))
+			if err != nil {
+				log.WithFields(logrus.Fields{
+					"method": method,
+				}).Errorf("Error parsing header variable %s - %s", v, err)
+				errs = append(errs, err)
+				continue
+			}
+			meta.Set(k, val)
+		}
+		if err := unresolvedStatus(errors.Join(errs...)); err != nil {
+			return err
+		}
+		err := stream.SetHeader(meta)
+		if err != nil {
+			return err
+		}
+	}
+
+	if mock.Status != nil {
+		code, _ := statusCode(mock.Status.Code)
+		msg, err := renderGRPC(method, ctx, mock.Status.Message, mock.Status.CompiledMessage())
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"method": method,
+			}).Errorf("Error parsing status message variable %s - %s", mock.Status.Message, err)
+		}
+		if err := unresolvedStatus(err); err != nil {
+			return err
+		}
+		return status.Error(code, msg)
+	}
+
+	if !md.IsStreamingServer() {
+		return g.send(stream, md, method, mock.Response, mock.CompiledResponse(), ctx)
+	}
+
+	for _, m := range mock.Stream {
+		if !sleep(r, m.Delay) {
+			return stream.Context().Err()
+		}
+		err := g.send(stream, md, method, m.Response, m.CompiledResponse(), ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send will replace any variables within the JSON response using its compiled template,
+// convert it to the method's output message and send it to the client.
+func (g *grpcServer) send(stream grpc.ServerStream, md protoreflect.MethodDescriptor, method, response string, t *variable.Template, ctx replacer) error {
+	data, err := renderGRPC(method, ctx, response, t)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"method": method,
+		}).Errorf("Error parsing response variable %s - %s", response, err)
+	}
+	if err := unresolvedStatus(err); err != nil {
+		return err
+	}
+
+	out := dynamicpb.NewMessage(md.Output())
+	if strings.TrimSpace(data) != "" {
+		err = protojson.Unmarshal([]byte(data), out)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"method": method,
+			}).Errorf("Unable to convert response JSON to %s - %s", md.Output().FullName(), err)
+			return status.Errorf(codes.Internal, "invalid mock response for %s - %s", method, err)
+		}
+	}
+	return stream.SendMsg(out)
+}
+
+// renderGRPC will replace the variables of one of the mock's response values using its
+// template compiled when the mocks were loaded, the value is compiled when t is nil.
+// Variables left as is outside strict mode are logged at debug level.
+func renderGRPC(method string, ctx replacer, v string, t *variable.Template) (string, error) {
+	if t == nil {
+		t = variable.Compile(v)
+	}
+	s, err := ctx.Render(t)
+	if u := ctx.Unresolved(); err == nil && len(u) > 0 {
+		log.WithFields(logrus.Fields{
+			"method": method,
+		}).Debugf("Unresolved variables left as is - %s", &variable.UnresolvedError{Variables: u})
+	}
+	return s, err
+}
+
+// unresolvedStatus will return an INTERNAL status listing the unresolved variables
+// held by err, nil is returned if there are none.
+func unresolvedStatus(err error) error {
+	var u unresolved
+	if !u.add(err) {
+		return nil
+	}
+	return status.Error(codes.Internal, (&variable.UnresolvedError{Variables: u}).Error())
+}
+
+// statusCode will convert a status code name or number into a gRPC status code.
+func statusCode(c string) (codes.Code, error) {
+	var code codes.Code
+	if _, err := strconv.Atoi(c); err != nil {
+		c = strconv.Quote(strings.ToUpper(c))
+	}
+	err := code.UnmarshalJSON([]byte(c))
+	if err != nil {
+		return code, fmt.Errorf("invalid grpc status code %s", c)
+	}
+	return code, nil
+}
@@ -0,0 +1,410 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madflojo/mockitout/certs"
+	"github.com/madflojo/mockitout/config"
+	"github.com/madflojo/mockitout/descriptors"
+	"github.com/madflojo/mockitout/mocks"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const usersProto = `
+syntax = "proto3";
+package users.v1;
+
+message GetUserRequest {
+  string id = 1;
+}
+
+message User {
+  string id = 1;
+  string name = 2;
+}
+
+service UserService {
+  rpc GetUser(GetUserRequest) returns (User);
+  rpc WatchUsers(GetUserRequest) returns (stream User);
+  rpc Upload(stream User) returns (User);
+}
+`
+
+// startGRPCServer will start a gRPC mock server with the provided mocks, returning a
+// client connection.
+func startGRPCServer(t *testing.T, m mocks.Mocks) (*grpc.ClientConn, func()) {
+	return startGRPCServerWith(t, m, config.Config{}, insecure.NewCredentials())
+}
+
+// startGRPCServerWith will start a gRPC mock server with the provided mocks and config,
+// returning a client connection using the credentials.
+func startGRPCServerWith(t *testing.T, m mocks.Mocks, c config.Config, creds credentials.TransportCredentials) (*grpc.ClientConn, func()) {
+	dir, err := ioutil.TempDir("", "protos")
+	if err != nil {
+		t.Fatalf("Error creating temp dir - %s", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "users.proto"), []byte(usersProto), 0644)
+	if err != nil {
+		t.Fatalf("Error writing proto file - %s", err)
+	}
+
+	log = logrus.New()
+	log.Level = logrus.FatalLevel
+	c.ProtoFiles, c.ProtoImportPaths = []string{"users.proto"}, []string{dir}
+	cfg = c
+	mocked = m
+
+	g, err := newGRPCServer()
+	if err != nil {
+		t.Fatalf("Unexpected error creating gRPC server - %s", err)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to start listener - %s", err)
+	}
+	go g.server.Serve(lis)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(creds))
+	if err != nil {
+		t.Fatalf("Unable to create gRPC client - %s", err)
+	}
+
+	return conn, func() {
+		conn.Close()
+		g.server.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestGRPCHandler(t *testing.T) {
+	strict := true
+	m := mocks.Mocks{
+		GRPC: map[string]mocks.GRPCMethod{
+			"get_andre": {
+				Method:   "users.v1.UserService/GetUser",
+				Match:    mocks.Match{Body: map[string]mocks.Matcher{"$.id": {Value: "1"}}},
+				Headers:  map[string]string{"x-served-by": "MockItOut"},
+				Response: `{"id": "{{ body.id }}", "name": "Andre"}`,
+			},
+			"get_strict": {
+				Method:          "users.v1.UserService/GetUser",
+				Match:           mocks.Match{Body: map[string]mocks.Matcher{"$.id": {Value: "3"}}},
+				Response:        `{"id": "{{ body.id }}", "name": "{{ header.x-tenant }}"}`,
+				StrictVariables: &strict,
+			},
+			"get_missing": {
+				Method: "users.v1.UserService/GetUser",
+				Status: &mocks.GRPCStatus{Code: "NOT_FOUND", Message: "user {{ header.x-user }} not found"},
+			},
+			"watch": {
+				Method: "/users.v1.UserService/WatchUsers",
+				Stream: []mocks.GRPCMessage{
+					{Response: `{"id": "1"}`},
+					{Response: `{"id": "2"}`, Delay: 10 * time.Millisecond},
+				},
+			},
+		},
+		GRPCMethods: map[string][]string{
+			"users.v1.UserService/GetUser":    {"get_andre", "get_strict", "get_missing"},
+			"users.v1.UserService/WatchUsers": {"watch"},
+		},
+	}
+	conn, stop := startGRPCServer(t, m)
+	defer stop()
+
+	md, err := testDescriptors(t).Method("users.v1.UserService/GetUser")
+	if err != nil {
+		t.Fatalf("Unable to find method - %s", err)
+	}
+
+	t.Run("Unary", func(t *testing.T) {
+		in := dynamicpb.NewMessage(md.Input())
+		_ = protojson.Unmarshal([]byte(`{"id": "1"}`), in)
+		out := dynamicpb.NewMessage(md.Output())
+
+		var header metadata.MD
+		err := conn.Invoke(context.Background(), "/users.v1.UserService/GetUser", in, out, grpc.Header(&header))
+		if err != nil {
+			t.Fatalf("Unexpected error calling method - %s", err)
+		}
+		fields := md.Output().Fields()
+		if out.Get(fields.ByName("id")).String() != "1" || out.Get(fields.ByName("name")).String() != "Andre" {
+			t.Errorf("Unexpected response %v", out)
+		}
+		if v := header.Get("x-served-by"); len(v) != 1 || v[0] != "MockItOut" {
+			t.Errorf("Unexpected response metadata %v", header)
+		}
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		in := dynamicpb.NewMessage(md.Input())
+		_ = protojson.Unmarshal([]byte(`{"id": "2"}`), in)
+		out := dynamicpb.NewMessage(md.Output())
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-user", "2")
+		err := conn.Invoke(ctx, "/users.v1.UserService/GetUser", in, out)
+		s, _ := status.FromError(err)
+		if s.Code() != codes.NotFound || s.Message() != "user 2 not found" {
+			t.Errorf("Unexpected status %s", err)
+		}
+	})
+
+	t.Run("Strict Variables", func(t *testing.T) {
+		in := dynamicpb.NewMessage(md.Input())
+		_ = protojson.Unmarshal([]byte(`{"id": "3"}`), in)
+		out := dynamicpb.NewMessage(md.Output())
+
+		err := conn.Invoke(context.Background(), "/users.v1.UserService/GetUser", in, out)
+		s, _ := status.FromError(err)
+		if s.Code() != codes.Internal || !strings.Contains(s.Message(), "{{ header.x-tenant }}") {
+			t.Errorf("Unexpected status %s", err)
+		}
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant", "acme")
+		err = conn.Invoke(ctx, "/users.v1.UserService/GetUser", in, out)
+		if err != nil {
+			t.Fatalf("Unexpected error calling method - %s", err)
+		}
+		if out.Get(md.Output().Fields().ByName("name")).String() != "acme" {
+			t.Errorf("Unexpected response %v", out)
+		}
+	})
+
+	t.Run("Server Streaming", func(t *testing.T) {
+		wmd, _ := testDescriptors(t).Method("users.v1.UserService/WatchUsers")
+		stream, err := conn.NewStream(context.Background(), &grpc.StreamDesc{ServerStreams: true}, "/users.v1.UserService/WatchUsers")
+		if err != nil {
+			t.Fatalf("Unexpected error opening stream - %s", err)
+		}
+		in := dynamicpb.NewMessage(wmd.Input())
+		if err := stream.SendMsg(in); err != nil {
+			t.Fatalf("Unexpected error sending request - %s", err)
+		}
+		_ = stream.CloseSend()
+
+		var ids []string
+		for {
+			out := dynamicpb.NewMessage(wmd.Output())
+			err := stream.RecvMsg(out)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error receiving message - %s", err)
+			}
+			ids = append(ids, out.Get(wmd.Output().Fields().ByName("id")).String())
+		}
+		if len(ids) != 2 || ids[0] != "1" || ids[1] != "2" {
+			t.Errorf("Unexpected streamed messages %v", ids)
+		}
+	})
+
+	t.Run("Unknown Method", func(t *testing.T) {
+		in := dynamicpb.NewMessage(md.Input())
+		out := dynamicpb.NewMessage(md.Output())
+		err := conn.Invoke(context.Background(), "/users.v1.UserService/DeleteUser", in, out)
+		if s, _ := status.FromError(err); s.Code() != codes.Unimplemented {
+			t.Errorf("Unexpected status %s", err)
+		}
+	})
+}
+
+// testDescriptors will load the test descriptors for building client messages.
+func testDescriptors(t *testing.T) *descriptors.Registry {
+	r, err := descriptors.FromProtoFiles(cfg.ProtoFiles, cfg.ProtoImportPaths)
+	if err != nil {
+		t.Fatalf("Unable to load descriptors - %s", err)
+	}
+	return r
+}
+
+func TestNewGRPCServerErrors(t *testing.T) {
+	cases := map[string]mocks.GRPCMethod{
+		"unknown method":   {Method: "users.v1.UserService/Missing"},
+		"client streaming": {Method: "users.v1.UserService/Upload"},
+		"bad status":       {Method: "users.v1.UserService/GetUser", Status: &mocks.GRPCStatus{Code: "NOPE"}},
+	}
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			dir, _ := ioutil.TempDir("", "protos")
+			defer os.RemoveAll(dir)
+			_ = ioutil.WriteFile(filepath.Join(dir, "users.proto"), []byte(usersProto), 0644)
+
+			log = logrus.New()
+			log.Level = logrus.FatalLevel
+			cfg = config.Config{ProtoFiles: []string{"users.proto"}, ProtoImportPaths: []string{dir}}
+			mocked = mocks.Mocks{GRPC: map[string]mocks.GRPCMethod{k: v}}
+
+			_, err := newGRPCServer()
+			if err == nil {
+				t.Errorf("Expected error creating gRPC server")
+			}
+		})
+	}
+
+	t.Run("no descriptors", func(t *testing.T) {
+		cfg = config.Config{}
+		_, err := newGRPCServer()
+		if err == nil {
+			t.Errorf("Expected error creating gRPC server")
+		}
+	})
+}
+
+func TestGRPCTLS(t *testing.T) {
+	ca, err := certs.NewCA()
+	if err != nil {
+		t.Fatalf("Unable to create CA - %s", err)
+	}
+	cert, err := ca.Issue("127.0.0.1")
+	if err != nil {
+		t.Fatalf("Unable to issue certificate - %s", err)
+	}
+	defer func(s *server) { srv, cfg = s, config.Config{} }(srv)
+
+	m := mocks.Mocks{
+		GRPC: map[string]mocks.GRPCMethod{
+			"get_user": {
+				Method:   "users.v1.UserService/GetUser",
+				Response: `{"id": "{{ body.id }}", "name": "Alice"}`,
+			},
+		},
+		GRPCMethods: map[string][]string{"users.v1.UserService/GetUser": {"get_user"}},
+	}
+
+	t.Run("Shared Certificates", func(t *testing.T) {
+		// the gRPC listener shares the TLS configuration of the HTTP server
+		srv = &server{httpServer: &http.Server{TLSConfig: &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+			NextProtos:   []string{"h2", "http/1.1"},
+		}}}
+		pool := x509.NewCertPool()
+		pool.AddCert(ca.Certificate())
+		creds := credentials.NewTLS(&tls.Config{RootCAs: pool})
+		conn, stop := startGRPCServerWith(t, m, config.Config{EnableTLS: true}, creds)
+		defer stop()
+		md, err := testDescriptors(t).Method("users.v1.UserService/GetUser")
+		if err != nil {
+			t.Fatalf("Unable to find method - %s", err)
+		}
+
+		in := dynamicpb.NewMessage(md.Input())
+		_ = protojson.Unmarshal([]byte(`{"id": "1"}`), in)
+		out := dynamicpb.NewMessage(md.Output())
+		err = conn.Invoke(context.Background(), "/users.v1.UserService/GetUser", in, out)
+		if err != nil {
+			t.Fatalf("Unexpected error calling method - %s", err)
+		}
+		if name := out.Get(md.Output().Fields().ByName("name")).String(); name != "Alice" {
+			t.Errorf("Unexpected response name %q", name)
+		}
+	})
+
+	t.Run("Missing Certificates", func(t *testing.T) {
+		srv = &server{httpServer: &http.Server{TLSConfig: &tls.Config{MinVersion: tls.VersionTLS12}}}
+		cfg = config.Config{EnableTLS: true, CertFile: "/doesntexist", KeyFile: "/doesntexist"}
+		_, err := grpcTLSConfig()
+		if err == nil {
+			t.Errorf("Expected error loading missing certificates")
+		}
+	})
+}
+
+func TestGRPCClientAuth(t *testing.T) {
+	ca := testCert("Test CA", nil)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]}), 0600)
+	if err != nil {
+		t.Fatalf("Unable to write CA file - %s", err)
+	}
+	serverCA, err := certs.NewCA()
+	if err != nil {
+		t.Fatalf("Unable to create CA - %s", err)
+	}
+	serverCert, err := serverCA.Issue("127.0.0.1")
+	if err != nil {
+		t.Fatalf("Unable to issue certificate - %s", err)
+	}
+	defer func(s *server) { srv, cfg = s, config.Config{} }(srv)
+
+	// the gRPC listener shares the TLS configuration of the HTTP server
+	c := config.Config{EnableTLS: true, ClientAuth: "verify", ClientCAFile: caFile}
+	cfg = c
+	srv = &server{httpServer: &http.Server{TLSConfig: &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{serverCert}}}}
+	err = configureClientAuth(srv.httpServer.TLSConfig)
+	if err != nil {
+		t.Fatalf("Unable to configure client auth - %s", err)
+	}
+
+	m := mocks.Mocks{
+		GRPC: map[string]mocks.GRPCMethod{
+			"get_admin": {
+				Method:   "users.v1.UserService/GetUser",
+				Match:    mocks.Match{ClientCert: map[string]mocks.Matcher{"cn": {Value: "admin-client"}, "verified": {Value: true}}},
+				Response: `{"id": "{{ body.id }}", "name": "{{ request.tls.client_cn }}"}`,
+			},
+		},
+		GRPCMethods: map[string][]string{"users.v1.UserService/GetUser": {"get_admin"}},
+	}
+	cases := map[string]struct {
+		certs []tls.Certificate
+		err   bool
+	}{
+		"Verified":       {certs: []tls.Certificate{testCert("admin-client", &ca)}},
+		"Untrusted":      {certs: []tls.Certificate{testCert("admin-client", nil)}, err: true},
+		"No Certificate": {err: true},
+	}
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			pool := x509.NewCertPool()
+			pool.AddCert(serverCA.Certificate())
+			creds := credentials.NewTLS(&tls.Config{RootCAs: pool, Certificates: v.certs})
+			conn, stop := startGRPCServerWith(t, m, c, creds)
+			defer stop()
+			md, err := testDescriptors(t).Method("users.v1.UserService/GetUser")
+			if err != nil {
+				t.Fatalf("Unable to find method - %s", err)
+			}
+
+			in := dynamicpb.NewMessage(md.Input())
+			_ = protojson.Unmarshal([]byte(`{"id": "1"}`), in)
+			out := dynamicpb.NewMessage(md.Output())
+			err = conn.Invoke(context.Background(), "/users.v1.UserService/GetUser", in, out)
+			if v.err {
+				if err == nil {
+					t.Fatalf("Expected TLS handshake to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error calling method - %s", err)
+			}
+			if name := out.Get(md.Output().Fields().ByName("name")).String(); name != "admin-client" {
+				t.Errorf("Unexpected response name %q", name)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/madflojo/mockitout/jwt"
+	"github.com/madflojo/mockitout/variable"
+)
+
+// JWKS is used to publish the public key of the token signing key as a JSON Web Key Set.
+func (s *server) JWKS(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	writeJSON(w, http.StatusOK, jwt.Default.JWKS())
+}
+
+// configureJWT will load or generate the key used to sign tokens created by jwt variables.
+func configureJWT() error {
+	var err error
+	if cfg.JWTKeyFile != "" {
+		jwt.Default, err = jwt.FromFile(cfg.JWTKeyFile)
+		if err != nil {
+			return fmt.Errorf("could not load JWT key - %s", err)
+		}
+	} else {
+		jwt.Default, err = jwt.Generate()
+		if err != nil {
+			return fmt.Errorf("could not generate JWT key - %s", err)
+		}
+	}
+	log.Infof("Signing JWTs with %s key %s", jwt.Default.Algorithm(), jwt.Default.KeyID())
+
+	if cfg.JWTIssuer != "" {
+		variable.JWTIssuer = cfg.JWTIssuer
+	}
+	if cfg.JWTExpiry > 0 {
+		variable.JWTExpiry = cfg.JWTExpiry
+	}
+	return nil
+}
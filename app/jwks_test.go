@@ -0,0 +1,77 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/madflojo/mockitout/jwt"
+	"github.com/madflojo/mockitout/mocks"
+)
+
+func TestJWKS(t *testing.T) {
+	signer, err := jwt.Generate()
+	if err != nil {
+		t.Fatalf("Unable to generate signer - %s", err)
+	}
+	defer func(s *jwt.Signer) { jwt.Default = s }(jwt.Default)
+	jwt.Default = signer
+
+	m := mocks.Mocks{}
+	m.AddRoute("token", mocks.Route{
+		Path: "/token",
+		Body: `{"access_token": "{{ jwt sub=query.user exp=+5m }}"}`,
+	})
+	ts := newTestServer(t, m)
+	defer ts.Close()
+	srv.httpRouter.GET("/.well-known/jwks.json", srv.middleware(srv.JWKS))
+
+	r, err := ts.Client().Get(ts.URL + "/.well-known/jwks.json")
+	if err != nil {
+		t.Fatalf("Unexpected error when requesting JWKS - %s", err)
+	}
+	defer r.Body.Close()
+	if r.StatusCode != 200 || r.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("Unexpected JWKS response %d %s", r.StatusCode, r.Header.Get("Content-Type"))
+	}
+	var set jwt.KeySet
+	err = json.NewDecoder(r.Body).Decode(&set)
+	if err != nil || len(set.Keys) != 1 || set.Keys[0].Kty != "RSA" || set.Keys[0].N == "" {
+		t.Fatalf("Unexpected JWKS %+v - %v", set, err)
+	}
+
+	r, err = ts.Client().Get(ts.URL + "/token?user=andre")
+	if err != nil {
+		t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+	}
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		t.Fatalf("Unable to read HTTP response body - %s", err)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	err = json.Unmarshal(body, &token)
+	if err != nil {
+		t.Fatalf("Unable to parse token response %q - %s", body, err)
+	}
+
+	parts := strings.Split(token.AccessToken, ".")
+	if len(parts) != 3 {
+		t.Fatalf("Unexpected token %s", token.AccessToken)
+	}
+	var header, claims map[string]interface{}
+	b, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	_ = json.Unmarshal(b, &header)
+	b, _ = base64.RawURLEncoding.DecodeString(parts[1])
+	_ = json.Unmarshal(b, &claims)
+	if header["kid"] != set.Keys[0].Kid || header["alg"] != "RS256" {
+		t.Errorf("Unexpected token header %+v", header)
+	}
+	if claims["sub"] != "andre" {
+		t.Errorf("Unexpected token claims %+v", claims)
+	}
+}
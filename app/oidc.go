@@ -0,0 +1,510 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/madflojo/mockitout/clock"
+	"github.com/madflojo/mockitout/jwt"
+	"github.com/madflojo/mockitout/mocks"
+	"github.com/sirupsen/logrus"
+)
+
+// codeExpiry is the lifetime of authorization codes.
+const codeExpiry = 10 * time.Minute
+
+// standardScopes are the OpenID Connect scopes every client may request.
+var standardScopes = []string{"openid", "profile", "email", "offline_access"}
+
+// oidcProvider serves the built-in OAuth 2.0 and OpenID Connect provider. Authorization
+// codes and refresh tokens are kept in memory so they can be exchanged by later requests.
+type oidcProvider struct {
+	cfg *mocks.OIDC
+
+	// mu protects the codes and refresh tokens.
+	mu sync.Mutex
+
+	// codes is a map of issued authorization codes to their grants.
+	codes map[string]oidcGrant
+
+	// refresh is a map of issued refresh tokens to their grants.
+	refresh map[string]oidcGrant
+}
+
+// oidcGrant is the authorization behind a code or refresh token.
+type oidcGrant struct {
+	client, user, scope, nonce string
+	redirectURI                string
+	challenge, method          string
+	authTime                   time.Time
+	expires                    time.Time
+}
+
+// oauthError is an OAuth 2.0 error response.
+type oauthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// tokenResponse is the response of the token end-point.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Scope        string `json:"scope,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+// registerOIDC will register the end-points of the OpenID Connect provider, skipping
+// any paths already used by mocked routes or registered by built-in handlers such as
+// the JWKS and virtual clock end-points.
+func (s *server) registerOIDC(cfg *mocks.OIDC) error {
+	p := &oidcProvider{
+		cfg:     cfg,
+		codes:   make(map[string]oidcGrant),
+		refresh: make(map[string]oidcGrant),
+	}
+	handlers := []struct {
+		method, name string
+		handle       httprouter.Handle
+	}{
+		{"GET", "discovery", p.Discovery},
+		{"GET", "authorize", p.Authorize},
+		{"POST", "token", p.Token},
+		{"GET", "userinfo", p.UserInfo},
+		{"POST", "userinfo", p.UserInfo},
+		{"GET", "jwks", s.JWKS},
+	}
+	for _, h := range handlers {
+		path := cfg.Endpoint(h.name)
+		if _, ok := mocked.Paths[path]; ok {
+			log.Warnf("Not registering OpenID Connect %s end-point, %s is already mocked", h.name, path)
+			continue
+		}
+		if handle, _, _ := s.httpRouter.Lookup(h.method, path); handle != nil {
+			log.Warnf("Not registering OpenID Connect %s end-point, %s %s is already registered", h.name, h.method, path)
+			continue
+		}
+		err := s.handle(h.method, path, h.handle)
+		if err != nil {
+			return err
+		}
+	}
+	log.Infof("Registered OpenID Connect provider at %s", cfg.Endpoint("discovery"))
+	return nil
+}
+
+// issuer returns the issuer of the provider, derived from the request when not configured.
+func (p *oidcProvider) issuer(r *http.Request) string {
+	if p.cfg.Issuer != "" {
+		return strings.TrimSuffix(p.cfg.Issuer, "/")
+	}
+	return p.origin(r) + strings.TrimSuffix(p.cfg.Path, "/")
+}
+
+// endpoint returns the URL of the named end-point, using the scheme and host of the
+// issuer with the path the end-point is registered at.
+func (p *oidcProvider) endpoint(r *http.Request, name string) string {
+	return p.origin(r) + p.cfg.Endpoint(name)
+}
+
+// origin returns the scheme and host of the provider, taken from the issuer when
+// configured or the request otherwise.
+func (p *oidcProvider) origin(r *http.Request) string {
+	if u, err := url.Parse(p.cfg.Issuer); p.cfg.Issuer != "" && err == nil {
+		return u.Scheme + "://" + u.Host
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+// expiry returns the lifetime of issued tokens.
+func (p *oidcProvider) expiry() time.Duration {
+	if p.cfg.TokenExpiry > 0 {
+		return p.cfg.TokenExpiry
+	}
+	return time.Hour
+}
+
+// Discovery is used to serve the OpenID Connect discovery document.
+func (p *oidcProvider) Discovery(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	iss := p.issuer(r)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                iss,
+		"authorization_endpoint":                p.endpoint(r, "authorize"),
+		"token_endpoint":                        p.endpoint(r, "token"),
+		"userinfo_endpoint":                     p.endpoint(r, "userinfo"),
+		"jwks_uri":                              p.endpoint(r, "jwks"),
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "client_credentials", "refresh_token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{jwt.Default.Algorithm()},
+		"scopes_supported":                      standardScopes,
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256", "plain"},
+	})
+}
+
+// Authorize is used to issue authorization codes. There is no login page, the user given
+// by the login_hint parameter, or the default user, is logged in and redirected back to
+// the client straight away.
+func (p *oidcProvider) Authorize(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	q := r.URL.Query()
+	clientID := q.Get("client_id")
+	client, ok := p.cfg.Clients[clientID]
+	if clientID == "" || (len(p.cfg.Clients) > 0 && !ok) {
+		writeJSON(w, http.StatusBadRequest, oauthError{"invalid_client", "unknown client " + clientID})
+		return
+	}
+
+	// Errors are only returned to valid redirect URIs
+	redirectURI := q.Get("redirect_uri")
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	u, err := url.Parse(redirectURI)
+	if redirectURI == "" || err != nil || !u.IsAbs() || (len(client.RedirectURIs) > 0 && !slices.Contains(client.RedirectURIs, redirectURI)) {
+		writeJSON(w, http.StatusBadRequest, oauthError{"invalid_request", "invalid redirect_uri " + redirectURI})
+		return
+	}
+	redirect := func(values url.Values) {
+		if state := q.Get("state"); state != "" {
+			values.Set("state", state)
+		}
+		query := u.Query()
+		for k, v := range values {
+			query[k] = v
+		}
+		u.RawQuery = query.Encode()
+		http.Redirect(w, r, u.String(), http.StatusFound)
+	}
+	fail := func(code, desc string) {
+		redirect(url.Values{"error": {code}, "error_description": {desc}})
+	}
+
+	if q.Get("response_type") != "code" {
+		fail("unsupported_response_type", "only the code response type is supported")
+		return
+	}
+	scope := q.Get("scope")
+	if !scopeAllowed(client, scope) {
+		fail("invalid_scope", "scope not allowed for client")
+		return
+	}
+	user := q.Get("login_hint")
+	if user == "" {
+		user = p.cfg.DefaultUser()
+	}
+	if _, ok := p.cfg.Users[user]; user == "" || (len(p.cfg.Users) > 0 && !ok) {
+		fail("access_denied", "unknown user "+user)
+		return
+	}
+	method := q.Get("code_challenge_method")
+	if q.Get("code_challenge") != "" && method == "" {
+		method = "plain"
+	}
+	if method != "" && method != "plain" && method != "S256" {
+		fail("invalid_request", "unsupported code_challenge_method "+method)
+		return
+	}
+	if method != "" && q.Get("code_challenge") == "" {
+		fail("invalid_request", "code_challenge is required")
+		return
+	}
+
+	code := randomToken()
+	now := clock.Now()
+	p.mu.Lock()
+	for k, g := range p.codes {
+		if now.After(g.expires) {
+			delete(p.codes, k)
+		}
+	}
+	p.codes[code] = oidcGrant{
+		client:      clientID,
+		user:        user,
+		scope:       scope,
+		nonce:       q.Get("nonce"),
+		redirectURI: q.Get("redirect_uri"),
+		challenge:   q.Get("code_challenge"),
+		method:      method,
+		authTime:    now,
+		expires:     now.Add(codeExpiry),
+	}
+	p.mu.Unlock()
+
+	log.WithFields(logrus.Fields{
+		"client": clientID,
+		"user":   user,
+	}).Debugf("Issued OpenID Connect authorization code")
+	redirect(url.Values{"code": {code}})
+}
+
+// Token is used to exchange grants for tokens, supporting the client_credentials,
+// authorization_code and refresh_token grant types.
+func (p *oidcProvider) Token(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Cache-Control", "no-store")
+	err := r.ParseForm()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, oauthError{"invalid_request", err.Error()})
+		return
+	}
+
+	clientID, ok := p.authenticate(r)
+	if !ok {
+		if _, _, basic := r.BasicAuth(); basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+		}
+		writeJSON(w, http.StatusUnauthorized, oauthError{"invalid_client", "client authentication failed"})
+		return
+	}
+	client := p.cfg.Clients[clientID]
+	now := clock.Now()
+
+	var g oidcGrant
+	switch r.PostForm.Get("grant_type") {
+	case "client_credentials":
+		if len(p.cfg.Clients) > 0 && client.Secret == "" {
+			writeJSON(w, http.StatusBadRequest, oauthError{"unauthorized_client", "public clients cannot use client_credentials"})
+			return
+		}
+		g = oidcGrant{client: clientID, scope: r.PostForm.Get("scope")}
+		if !scopeAllowed(client, g.scope) {
+			writeJSON(w, http.StatusBadRequest, oauthError{"invalid_scope", "scope not allowed for client"})
+			return
+		}
+	case "authorization_code":
+		code := r.PostForm.Get("code")
+		p.mu.Lock()
+		g, ok = p.codes[code]
+		delete(p.codes, code)
+		p.mu.Unlock()
+		if !ok || g.client != clientID || now.After(g.expires) {
+			writeJSON(w, http.StatusBadRequest, oauthError{"invalid_grant", "invalid or expired code"})
+			return
+		}
+		if g.redirectURI != "" && g.redirectURI != r.PostForm.Get("redirect_uri") {
+			writeJSON(w, http.StatusBadRequest, oauthError{"invalid_grant", "redirect_uri does not match"})
+			return
+		}
+		if !g.verifyChallenge(r.PostForm.Get("code_verifier")) {
+			writeJSON(w, http.StatusBadRequest, oauthError{"invalid_grant", "invalid code_verifier"})
+			return
+		}
+	case "refresh_token":
+		token := r.PostForm.Get("refresh_token")
+		p.mu.Lock()
+		g, ok = p.refresh[token]
+		if ok && g.client == clientID {
+			// refresh tokens are rotated on use
+			delete(p.refresh, token)
+		}
+		p.mu.Unlock()
+		if !ok || g.client != clientID {
+			writeJSON(w, http.StatusBadRequest, oauthError{"invalid_grant", "invalid refresh token"})
+			return
+		}
+	default:
+		writeJSON(w, http.StatusBadRequest, oauthError{"unsupported_grant_type", "unsupported grant_type " + r.PostForm.Get("grant_type")})
+		return
+	}
+
+	resp, err := p.issue(r, g, now)
+	if err != nil {
+		log.Errorf("Unable to issue OpenID Connect tokens - %s", err)
+		writeJSON(w, http.StatusInternalServerError, oauthError{"server_error", "unable to issue tokens"})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// authenticate returns the ID of the client making a token request, false is returned if
+// the client is unknown or its secret does not match.
+func (p *oidcProvider) authenticate(r *http.Request) (string, bool) {
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		// basic credentials are form encoded
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if id == "" {
+		return "", false
+	}
+	if len(p.cfg.Clients) == 0 {
+		return id, true
+	}
+	c, ok := p.cfg.Clients[id]
+	if !ok {
+		return "", false
+	}
+	if c.Secret != "" && subtle.ConstantTimeCompare([]byte(c.Secret), []byte(secret)) != 1 {
+		return "", false
+	}
+	return id, true
+}
+
+// issue will create the tokens for the grant, refresh and ID tokens are only issued to
+// users and ID tokens only for the openid scope.
+func (p *oidcProvider) issue(r *http.Request, g oidcGrant, now time.Time) (tokenResponse, error) {
+	iss := p.issuer(r)
+	exp := now.Add(p.expiry())
+	sub := g.client
+	if g.user != "" {
+		sub = p.subject(g.user)
+	}
+
+	access := map[string]interface{}{
+		"iss":       iss,
+		"sub":       sub,
+		"aud":       g.client,
+		"client_id": g.client,
+		"iat":       now.Unix(),
+		"exp":       exp.Unix(),
+		"jti":       randomToken(),
+	}
+	if g.scope != "" {
+		access["scope"] = g.scope
+	}
+	merge(access, p.cfg.Claims, p.cfg.Clients[g.client].Claims, p.cfg.Users[g.user].Claims)
+
+	var err error
+	resp := tokenResponse{TokenType: "Bearer", ExpiresIn: int64(p.expiry().Seconds()), Scope: g.scope}
+	resp.AccessToken, err = jwt.Default.Sign(access)
+	if err != nil {
+		return resp, err
+	}
+	if g.user == "" {
+		return resp, nil
+	}
+
+	if slices.Contains(strings.Fields(g.scope), "openid") {
+		id := map[string]interface{}{
+			"iss":       iss,
+			"sub":       sub,
+			"aud":       g.client,
+			"iat":       now.Unix(),
+			"exp":       exp.Unix(),
+			"auth_time": g.authTime.Unix(),
+		}
+		if g.nonce != "" {
+			id["nonce"] = g.nonce
+		}
+		merge(id, p.cfg.Claims, p.cfg.Users[g.user].Claims)
+		resp.IDToken, err = jwt.Default.Sign(id)
+		if err != nil {
+			return resp, err
+		}
+	}
+
+	resp.RefreshToken = randomToken()
+	g.nonce, g.challenge, g.method = "", "", ""
+	p.mu.Lock()
+	p.refresh[resp.RefreshToken] = g
+	p.mu.Unlock()
+	return resp, nil
+}
+
+// UserInfo is used to return the claims of the user holding the bearer access token.
+func (p *oidcProvider) UserInfo(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = r.FormValue("access_token")
+	}
+	claims, err := jwt.Default.Verify(token, clock.Now())
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeJSON(w, http.StatusUnauthorized, oauthError{"invalid_token", err.Error()})
+		return
+	}
+
+	for name, u := range p.cfg.Users {
+		if sub := p.subject(name); sub == claims["sub"] {
+			info := map[string]interface{}{"sub": sub}
+			merge(info, u.Claims)
+			writeJSON(w, http.StatusOK, info)
+			return
+		}
+	}
+	if len(p.cfg.Users) == 0 && claims["sub"] != claims["client_id"] {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"sub": claims["sub"]})
+		return
+	}
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	writeJSON(w, http.StatusUnauthorized, oauthError{"invalid_token", "token is not for a user"})
+}
+
+// subject returns the sub claim of the user.
+func (p *oidcProvider) subject(user string) string {
+	if sub, ok := p.cfg.Users[user].Claims["sub"].(string); ok {
+		return sub
+	}
+	return user
+}
+
+// scopeAllowed returns true if the client may request every scope within scope.
+func scopeAllowed(c mocks.OIDCClient, scope string) bool {
+	if len(c.Scopes) == 0 {
+		return true
+	}
+	for _, s := range strings.Fields(scope) {
+		if !slices.Contains(c.Scopes, s) && !slices.Contains(standardScopes, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// verifyChallenge checks the PKCE code verifier against the challenge of the grant.
+func (g oidcGrant) verifyChallenge(verifier string) bool {
+	switch g.method {
+	case "":
+		return true
+	case "S256":
+		sum := sha256.Sum256([]byte(verifier))
+		return base64.RawURLEncoding.EncodeToString(sum[:]) == g.challenge
+	default:
+		return verifier == g.challenge
+	}
+}
+
+// merge copies the claims into dst, later claims replace earlier ones.
+func merge(dst map[string]interface{}, claims ...map[string]interface{}) {
+	for _, c := range claims {
+		for k, v := range c {
+			dst[k] = v
+		}
+	}
+}
+
+// randomToken returns a random URL safe value used for codes and tokens.
+func randomToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// writeJSON will respond with v encoded as JSON.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,285 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madflojo/mockitout/clock"
+	"github.com/madflojo/mockitout/jwt"
+	"github.com/madflojo/mockitout/mocks"
+)
+
+func TestOIDCProvider(t *testing.T) {
+	signer, err := jwt.Generate()
+	if err != nil {
+		t.Fatalf("Unable to generate signer - %s", err)
+	}
+	defer func(s *jwt.Signer) { jwt.Default = s }(jwt.Default)
+	jwt.Default = signer
+	defer clock.Default.Reset()
+
+	provider := &mocks.OIDC{
+		Path:   "/realms/test",
+		Claims: map[string]interface{}{"tenant": "acme"},
+		Clients: map[string]mocks.OIDCClient{
+			"service": {Secret: "s3cret", Scopes: []string{"orders:read"}, Claims: map[string]interface{}{"service": true}},
+			"web":     {RedirectURIs: []string{"http://localhost:3000/callback"}},
+		},
+		Users: map[string]mocks.OIDCUser{
+			"alice": {Claims: map[string]interface{}{"email": "alice@example.com"}},
+			"bob":   {Claims: map[string]interface{}{"sub": "1234", "name": "Bob"}},
+		},
+	}
+	ts := newTestServer(t, mocks.Mocks{})
+	defer ts.Close()
+	err = srv.registerOIDC(provider)
+	if err != nil {
+		t.Fatalf("Unable to register provider - %s", err)
+	}
+	issuer := ts.URL + "/realms/test"
+
+	client := ts.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	token := func(t *testing.T, form url.Values, user, pass string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest("POST", issuer+"/oauth2/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if user != "" {
+			req.SetBasicAuth(user, pass)
+		}
+		r, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error when requesting token - %s", err)
+		}
+		defer r.Body.Close()
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		return r.StatusCode, body
+	}
+
+	verify := func(t *testing.T, token interface{}) map[string]interface{} {
+		s, _ := token.(string)
+		claims, err := signer.Verify(s, clock.Now())
+		if err != nil {
+			t.Fatalf("Invalid token %q - %s", s, err)
+		}
+		if claims["iss"] != issuer {
+			t.Errorf("Unexpected issuer %v", claims["iss"])
+		}
+		return claims
+	}
+
+	authorize := func(t *testing.T, query url.Values) *url.URL {
+		r, err := client.Get(issuer + "/oauth2/authorize?" + query.Encode())
+		if err != nil {
+			t.Fatalf("Unexpected error when requesting authorize - %s", err)
+		}
+		defer r.Body.Close()
+		if r.StatusCode != http.StatusFound {
+			t.Fatalf("Unexpected http status code - %d", r.StatusCode)
+		}
+		u, err := url.Parse(r.Header.Get("Location"))
+		if err != nil {
+			t.Fatalf("Invalid redirect - %s", err)
+		}
+		return u
+	}
+
+	t.Run("Discovery", func(t *testing.T) {
+		r, err := client.Get(issuer + "/.well-known/openid-configuration")
+		if err != nil {
+			t.Fatalf("Unexpected error when requesting discovery - %s", err)
+		}
+		defer r.Body.Close()
+		var d map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&d)
+		if d["issuer"] != issuer || d["token_endpoint"] != issuer+"/oauth2/token" || d["jwks_uri"] != issuer+"/oauth2/jwks" {
+			t.Errorf("Unexpected discovery document %+v", d)
+		}
+
+		r, err = client.Get(issuer + "/oauth2/jwks")
+		if err != nil {
+			t.Fatalf("Unexpected error when requesting JWKS - %s", err)
+		}
+		defer r.Body.Close()
+		var set jwt.KeySet
+		_ = json.NewDecoder(r.Body).Decode(&set)
+		if len(set.Keys) != 1 || set.Keys[0].Kid != signer.KeyID() {
+			t.Errorf("Unexpected JWKS %+v", set)
+		}
+	})
+
+	t.Run("Discovery With Issuer", func(t *testing.T) {
+		provider.Issuer = "https://auth.example.com/tenants/acme"
+		defer func() { provider.Issuer = "" }()
+
+		r, err := client.Get(issuer + "/.well-known/openid-configuration")
+		if err != nil {
+			t.Fatalf("Unexpected error when requesting discovery - %s", err)
+		}
+		defer r.Body.Close()
+		var d map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&d)
+		if d["issuer"] != provider.Issuer || d["authorization_endpoint"] != "https://auth.example.com/realms/test/oauth2/authorize" || d["jwks_uri"] != "https://auth.example.com/realms/test/oauth2/jwks" {
+			t.Errorf("Unexpected discovery document, end-points should use the registered paths %+v", d)
+		}
+	})
+
+	t.Run("Client Credentials", func(t *testing.T) {
+		code, body := token(t, url.Values{"grant_type": {"client_credentials"}, "scope": {"orders:read"}}, "service", "s3cret")
+		if code != 200 || body["token_type"] != "Bearer" || body["refresh_token"] != nil || body["id_token"] != nil {
+			t.Fatalf("Unexpected token response %d %+v", code, body)
+		}
+		claims := verify(t, body["access_token"])
+		if claims["sub"] != "service" || claims["scope"] != "orders:read" || claims["tenant"] != "acme" || claims["service"] != true {
+			t.Errorf("Unexpected access token claims %+v", claims)
+		}
+	})
+
+	t.Run("Authorization Code", func(t *testing.T) {
+		verifier := "a-long-random-code-verifier-value-for-pkce-testing"
+		sum := sha256.Sum256([]byte(verifier))
+		u := authorize(t, url.Values{
+			"response_type":         {"code"},
+			"client_id":             {"web"},
+			"redirect_uri":          {"http://localhost:3000/callback"},
+			"scope":                 {"openid email"},
+			"state":                 {"xyz"},
+			"nonce":                 {"n-0S6"},
+			"login_hint":            {"bob"},
+			"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+			"code_challenge_method": {"S256"},
+		})
+		if u.Host != "localhost:3000" || u.Query().Get("state") != "xyz" || u.Query().Get("code") == "" {
+			t.Fatalf("Unexpected redirect %s", u)
+		}
+
+		form := url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {"web"},
+			"code":          {u.Query().Get("code")},
+			"redirect_uri":  {"http://localhost:3000/callback"},
+			"code_verifier": {verifier},
+		}
+		code, body := token(t, form, "", "")
+		if code != 200 || body["refresh_token"] == nil {
+			t.Fatalf("Unexpected token response %d %+v", code, body)
+		}
+		access := body2string(body, "access_token")
+		id := verify(t, body["id_token"])
+		if id["sub"] != "1234" || id["aud"] != "web" || id["nonce"] != "n-0S6" || id["name"] != "Bob" {
+			t.Errorf("Unexpected ID token claims %+v", id)
+		}
+
+		// codes are single use
+		code, body = token(t, form, "", "")
+		if code != 400 || body["error"] != "invalid_grant" {
+			t.Errorf("Unexpected response reusing code %d %+v", code, body)
+		}
+
+		t.Run("User Info", func(t *testing.T) {
+			req, _ := http.NewRequest("GET", issuer+"/oauth2/userinfo", nil)
+			req.Header.Set("Authorization", "Bearer "+access)
+			r, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Unexpected error when requesting userinfo - %s", err)
+			}
+			defer r.Body.Close()
+			var info map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&info)
+			if r.StatusCode != 200 || info["sub"] != "1234" || info["name"] != "Bob" {
+				t.Errorf("Unexpected userinfo response %d %+v", r.StatusCode, info)
+			}
+		})
+	})
+
+	t.Run("Refresh Token", func(t *testing.T) {
+		u := authorize(t, url.Values{"response_type": {"code"}, "client_id": {"web"}, "scope": {"openid"}})
+		if u.Query().Get("code") == "" {
+			t.Fatalf("Unexpected redirect %s", u)
+		}
+		_, body := token(t, url.Values{"grant_type": {"authorization_code"}, "client_id": {"web"}, "code": {u.Query().Get("code")}}, "", "")
+		refresh := body2string(body, "refresh_token")
+		if claims := verify(t, body["id_token"]); claims["sub"] != "alice" || claims["email"] != "alice@example.com" {
+			t.Errorf("Unexpected ID token claims for default user %+v", claims)
+		}
+
+		clock.Default.Advance(2 * time.Hour)
+		code, body := token(t, url.Values{"grant_type": {"refresh_token"}, "client_id": {"web"}, "refresh_token": {refresh}}, "", "")
+		if code != 200 || body2string(body, "refresh_token") == refresh {
+			t.Fatalf("Unexpected refresh response %d %+v", code, body)
+		}
+		verify(t, body["access_token"])
+
+		// refresh tokens are rotated
+		code, _ = token(t, url.Values{"grant_type": {"refresh_token"}, "client_id": {"web"}, "refresh_token": {refresh}}, "", "")
+		if code != 400 {
+			t.Errorf("Unexpected http status code reusing refresh token - %d", code)
+		}
+	})
+
+	tokenErrors := map[string]struct {
+		form       url.Values
+		user, pass string
+		code       int
+		err        string
+	}{
+		"Bad Secret":        {form: url.Values{"grant_type": {"client_credentials"}}, user: "service", pass: "wrong", code: 401, err: "invalid_client"},
+		"Unknown Client":    {form: url.Values{"grant_type": {"client_credentials"}, "client_id": {"nobody"}}, code: 401, err: "invalid_client"},
+		"Public Client":     {form: url.Values{"grant_type": {"client_credentials"}, "client_id": {"web"}}, code: 400, err: "unauthorized_client"},
+		"Scope Not Allowed": {form: url.Values{"grant_type": {"client_credentials"}, "scope": {"orders:write"}}, user: "service", pass: "s3cret", code: 400, err: "invalid_scope"},
+		"Unsupported Grant": {form: url.Values{"grant_type": {"password"}}, user: "service", pass: "s3cret", code: 400, err: "unsupported_grant_type"},
+		"Invalid Code":      {form: url.Values{"grant_type": {"authorization_code"}, "code": {"bad"}, "client_id": {"web"}}, code: 400, err: "invalid_grant"},
+	}
+	for k, v := range tokenErrors {
+		t.Run(k, func(t *testing.T) {
+			code, body := token(t, v.form, v.user, v.pass)
+			if code != v.code || body["error"] != v.err {
+				t.Errorf("Unexpected token response %d %+v", code, body)
+			}
+		})
+	}
+
+	t.Run("Authorize Errors", func(t *testing.T) {
+		u := authorize(t, url.Values{"response_type": {"code"}, "client_id": {"web"}, "login_hint": {"mallory"}, "state": {"s"}})
+		if u.Query().Get("error") != "access_denied" || u.Query().Get("state") != "s" {
+			t.Errorf("Unexpected redirect %s", u)
+		}
+
+		r, err := client.Get(issuer + "/oauth2/authorize?response_type=code&client_id=web&redirect_uri=http://evil.example.com")
+		if err != nil {
+			t.Fatalf("Unexpected error when requesting authorize - %s", err)
+		}
+		r.Body.Close()
+		if r.StatusCode != 400 {
+			t.Errorf("Unexpected http status code for unregistered redirect - %d", r.StatusCode)
+		}
+	})
+
+	t.Run("Invalid User Info Token", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", issuer+"/oauth2/userinfo", nil)
+		req.Header.Set("Authorization", "Bearer invalid")
+		r, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error when requesting userinfo - %s", err)
+		}
+		r.Body.Close()
+		if r.StatusCode != 401 || r.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("Unexpected userinfo response %d", r.StatusCode)
+		}
+	})
+}
+
+// body2string returns the string value of key within the decoded JSON body.
+func body2string(body map[string]interface{}, key string) string {
+	s, _ := body[key].(string)
+	return s
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/madflojo/mockitout/certs"
+)
+
+// tlsProfile is a TLS listener which deliberately misbehaves, used to test clients
+// reject it.
+type tlsProfile struct {
+	// name is the name of the profile, e.g. expired.
+	name string
+
+	// server serves the profile.
+	server *http.Server
+
+	// listener is the TLS listener of the profile.
+	listener net.Listener
+}
+
+// tlsVersions are the profiles serving only a legacy TLS version.
+var tlsVersions = map[string]uint16{
+	"tls10": tls.VersionTLS10,
+	"tls11": tls.VersionTLS11,
+}
+
+// startTLSProfiles will open a listener for each configured TLS profile and serve
+// requests in the background. Profiles are given as name=address, e.g. expired=:9443.
+func startTLSProfiles() error {
+	if len(cfg.TLSProfiles) == 0 {
+		return nil
+	}
+	if srv.ca == nil {
+		return fmt.Errorf("TLS profiles require TLS and certificate generation to be enabled")
+	}
+
+	// Create every profile before listening, so a bad profile starts none of them
+	profiles := make([]*tlsProfile, 0, len(cfg.TLSProfiles))
+	for _, p := range cfg.TLSProfiles {
+		name, addr, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok || name == "" || addr == "" {
+			return fmt.Errorf("invalid TLS profile %q, expected name=address", p)
+		}
+		c, err := tlsProfileConfig(name)
+		if err != nil {
+			return fmt.Errorf("invalid TLS profile %s - %s", name, err)
+		}
+		profiles = append(profiles, &tlsProfile{
+			name: name,
+			server: &http.Server{
+				Addr:      addr,
+				Handler:   srv.httpRouter,
+				TLSConfig: c,
+				// HTTP/2 requires cipher suites profiles may not offer
+				TLSNextProto: make(map[string]func(*http.Server, *tls.Conn, http.Handler)),
+			},
+		})
+	}
+
+	for _, p := range profiles {
+		lis, err := net.Listen("tcp", p.server.Addr)
+		if err != nil {
+			for _, started := range srv.tlsProfiles {
+				started.server.Close()
+			}
+			srv.tlsProfiles = nil
+			return fmt.Errorf("could not start %s TLS profile listener - %s", p.name, err)
+		}
+		p.listener = lis
+		srv.tlsProfiles = append(srv.tlsProfiles, p)
+
+		log.Infof("Starting %s TLS profile Listener on %s", p.name, lis.Addr())
+		go func(p *tlsProfile) {
+			err := p.server.ServeTLS(p.listener, "", "")
+			if err != nil && err != http.ErrServerClosed {
+				log.Errorf("%s TLS profile listener stopped - %s", p.name, err)
+			}
+		}(p)
+	}
+	return nil
+}
+
+// tlsProfileConfig will create the TLS configuration of the named profile. Certificates
+// are issued for the CertHosts by the CA of generated certificates, so that each profile
+// only fails verification for the reason it is named after.
+func tlsProfileConfig(name string) (*tls.Config, error) {
+	hosts := certHosts()
+	c := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"http/1.1"},
+	}
+
+	var cert tls.Certificate
+	var err error
+	now := time.Now()
+	switch name {
+	case "expired":
+		cert, err = srv.ca.IssueWithOptions(certs.Options{NotBefore: now.Add(-48 * time.Hour), NotAfter: now.Add(-24 * time.Hour)}, hosts...)
+	case "not_yet_valid":
+		cert, err = srv.ca.IssueWithOptions(certs.Options{NotBefore: now.Add(24 * time.Hour)}, hosts...)
+	case "wrong_host":
+		cert, err = srv.ca.Issue("wrong-host.invalid")
+	case "self_signed":
+		cert, err = certs.SelfSigned(certs.Options{}, hosts...)
+	case "untrusted":
+		var ca *certs.CA
+		ca, err = certs.NewCA()
+		if err == nil {
+			cert, err = ca.Issue(hosts...)
+		}
+	case "tls10", "tls11":
+		// TLS 1.0 and 1.1 only support CBC cipher suites
+		c.MinVersion, c.MaxVersion = tlsVersions[name], tlsVersions[name]
+		c.CipherSuites = []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+		}
+		cert, err = srv.ca.Issue(hosts...)
+	default:
+		suites, ok := strings.CutPrefix(name, "cipher:")
+		if !ok {
+			return nil, fmt.Errorf("unknown profile, expected one of expired, not_yet_valid, wrong_host, self_signed, untrusted, tls10, tls11 or cipher:<suite>")
+		}
+		var rsa bool
+		c.CipherSuites, rsa, err = cipherSuites(suites)
+		if err != nil {
+			return nil, err
+		}
+		// TLS 1.3 cipher suites cannot be configured
+		c.MaxVersion = tls.VersionTLS12
+		cert, err = srv.ca.IssueWithOptions(certs.Options{RSA: rsa}, hosts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.Certificates = []tls.Certificate{cert}
+	return c, nil
+}
+
+// cipherSuites will look up the + separated cipher suite names, returning if they
+// require an RSA certificate. Every suite must use the same certificate type.
+func cipherSuites(names string) ([]uint16, bool, error) {
+	known := make(map[string]*tls.CipherSuite)
+	for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[s.Name] = s
+	}
+
+	var ids []uint16
+	var ecdsa, rsa bool
+	for _, n := range strings.Split(names, "+") {
+		s, ok := known[n]
+		if !ok {
+			return nil, false, fmt.Errorf("unknown cipher suite %q", n)
+		}
+		tls12 := false
+		for _, v := range s.SupportedVersions {
+			tls12 = tls12 || v == tls.VersionTLS12
+		}
+		if !tls12 {
+			return nil, false, fmt.Errorf("cipher suite %s is not supported by TLS 1.2", n)
+		}
+		if strings.Contains(n, "_ECDSA_") {
+			ecdsa = true
+		} else {
+			rsa = true
+		}
+		ids = append(ids, s.ID)
+	}
+	if ecdsa && rsa {
+		return nil, false, fmt.Errorf("cipher suites %s mix ECDSA and RSA certificates", names)
+	}
+	return ids, rsa, nil
+}
@@ -0,0 +1,181 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/madflojo/mockitout/certs"
+	"github.com/madflojo/mockitout/config"
+	"github.com/madflojo/mockitout/mocks"
+)
+
+func TestTLSProfiles(t *testing.T) {
+	m := mocks.Mocks{}
+	m.AddRoute("hi", mocks.Route{Path: "/hi", Body: "hi"})
+	ts := newTestServer(t, m)
+	ts.Close()
+
+	ca, err := certs.NewCA()
+	if err != nil {
+		t.Fatalf("Unable to create CA - %s", err)
+	}
+	srv.ca = ca
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Certificate())
+
+	cfg = config.Config{
+		CertHosts: []string{"localhost", "127.0.0.1"},
+		TLSProfiles: []string{
+			"expired=127.0.0.1:0",
+			"not_yet_valid=127.0.0.1:0",
+			"wrong_host=127.0.0.1:0",
+			"self_signed=127.0.0.1:0",
+			"untrusted=127.0.0.1:0",
+			"tls10=127.0.0.1:0",
+			"tls11=127.0.0.1:0",
+			"cipher:TLS_RSA_WITH_AES_128_CBC_SHA=127.0.0.1:0",
+			"cipher:TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA+TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA=127.0.0.1:0",
+		},
+	}
+	defer func() { cfg = config.Config{} }()
+	err = startTLSProfiles()
+	if err != nil {
+		t.Fatalf("Unable to start TLS profiles - %s", err)
+	}
+	defer func() {
+		for _, p := range srv.tlsProfiles {
+			p.server.Close()
+		}
+	}()
+	if len(srv.tlsProfiles) != len(cfg.TLSProfiles) {
+		t.Fatalf("Unexpected number of TLS profiles %d", len(srv.tlsProfiles))
+	}
+
+	cases := map[string]struct {
+		// reject is a substring of the error a default client returns, when empty the
+		// default client is expected to connect
+		reject string
+
+		// accept is a client configuration which can connect to the profile
+		accept *tls.Config
+
+		// version and cipher are the expected TLS version and cipher suite
+		version uint16
+		cipher  uint16
+	}{
+		"expired":       {reject: "expired"},
+		"not_yet_valid": {reject: "not yet valid"},
+		"wrong_host":    {reject: "not localhost"},
+		"self_signed":   {reject: "unknown authority"},
+		"untrusted":     {reject: "unknown authority"},
+		"tls10": {
+			reject:  "protocol version",
+			accept:  &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS10, CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA}},
+			version: tls.VersionTLS10,
+			cipher:  tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+		},
+		"tls11": {
+			reject:  "protocol version",
+			accept:  &tls.Config{MinVersion: tls.VersionTLS11, MaxVersion: tls.VersionTLS11, CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA}},
+			version: tls.VersionTLS11,
+			cipher:  tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+		},
+		"cipher:TLS_RSA_WITH_AES_128_CBC_SHA": {
+			reject:  "handshake failure",
+			accept:  &tls.Config{CipherSuites: []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA}, MaxVersion: tls.VersionTLS12},
+			version: tls.VersionTLS12,
+			cipher:  tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+		},
+		"cipher:TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA+TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA": {
+			accept:  &tls.Config{CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA}, MaxVersion: tls.VersionTLS12},
+			version: tls.VersionTLS12,
+			cipher:  tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+		},
+	}
+
+	for _, p := range srv.tlsProfiles {
+		v, ok := cases[p.name]
+		if !ok {
+			t.Fatalf("Unexpected TLS profile %s", p.name)
+		}
+		addr := p.listener.Addr().String()
+		t.Run(p.name, func(t *testing.T) {
+			c := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: "localhost"}}}
+			r, err := c.Get("https://" + addr + "/hi")
+			if v.reject == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error when requesting profile - %s", err)
+				}
+				r.Body.Close()
+			}
+			if v.reject != "" && (err == nil || !strings.Contains(err.Error(), v.reject)) {
+				t.Fatalf("Expected client to reject profile with %q, got %v", v.reject, err)
+			}
+			if v.accept == nil {
+				return
+			}
+
+			v.accept.RootCAs, v.accept.ServerName = roots, "localhost"
+			c = &http.Client{Transport: &http.Transport{TLSClientConfig: v.accept}}
+			r, err = c.Get("https://" + addr + "/hi")
+			if err != nil {
+				t.Fatalf("Unexpected error when requesting profile - %s", err)
+			}
+			defer r.Body.Close()
+			b, _ := io.ReadAll(r.Body)
+			if string(b) != "hi" {
+				t.Errorf("Unexpected body %q", b)
+			}
+			if r.TLS.Version != v.version {
+				t.Errorf("Unexpected TLS version %s", tls.VersionName(r.TLS.Version))
+			}
+			if v.cipher != 0 && r.TLS.CipherSuite != v.cipher {
+				t.Errorf("Unexpected cipher suite %s", tls.CipherSuiteName(r.TLS.CipherSuite))
+			}
+		})
+	}
+}
+
+func TestBadTLSProfiles(t *testing.T) {
+	ts := newTestServer(t, mocks.Mocks{})
+	ts.Close()
+	defer func() { cfg = config.Config{} }()
+
+	cases := map[string]struct {
+		profiles []string
+		noCA     bool
+	}{
+		"No CA":            {profiles: []string{"expired=127.0.0.1:0"}, noCA: true},
+		"Missing Address":  {profiles: []string{"expired"}},
+		"Unknown Profile":  {profiles: []string{"broken=127.0.0.1:0"}},
+		"Unknown Cipher":   {profiles: []string{"cipher:TLS_NOPE=127.0.0.1:0"}},
+		"TLS 1.3 Cipher":   {profiles: []string{"cipher:TLS_AES_128_GCM_SHA256=127.0.0.1:0"}},
+		"Mixed Ciphers":    {profiles: []string{"cipher:TLS_RSA_WITH_AES_128_CBC_SHA+TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA=127.0.0.1:0"}},
+		"Invalid Address":  {profiles: []string{"expired=pandasdonotbelonghere"}},
+		"Partially Failed": {profiles: []string{"expired=127.0.0.1:0", "tls10=pandasdonotbelonghere"}},
+	}
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			srv.ca = nil
+			if !v.noCA {
+				ca, err := certs.NewCA()
+				if err != nil {
+					t.Fatalf("Unable to create CA - %s", err)
+				}
+				srv.ca = ca
+			}
+			cfg = config.Config{TLSProfiles: v.profiles}
+			err := startTLSProfiles()
+			if err == nil {
+				t.Fatalf("Expected error starting TLS profiles, got nil")
+			}
+			if len(srv.tlsProfiles) != 0 {
+				t.Errorf("Expected no TLS profiles to be running, got %d", len(srv.tlsProfiles))
+			}
+		})
+	}
+}
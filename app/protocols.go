@@ -0,0 +1,87 @@
+package app
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// configureProtocols will setup the HTTP protocols offered by the HTTP server based
+// on configuration. HTTP/2 is negotiated via ALPN for TLS listeners, h2c is offered
+// to cleartext listeners and HTTP/3 is served from a separate UDP listener.
+func configureProtocols() error {
+	if cfg.HTTP3ListenAddr != "" && !cfg.EnableTLS {
+		return fmt.Errorf("HTTP/3 requires TLS to be enabled")
+	}
+	if cfg.EnableH2C && cfg.EnableTLS {
+		log.Warnf("h2c is only used when TLS is disabled, TLS listeners negotiate HTTP/2 via ALPN")
+	}
+
+	if cfg.EnableTLS {
+		if cfg.DisableHTTP2 {
+			// A non-nil empty map disables the automatic HTTP/2 upgrade
+			srv.httpServer.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+			srv.httpServer.TLSConfig.NextProtos = []string{"http/1.1"}
+		} else {
+			srv.httpServer.TLSConfig.NextProtos = []string{http2.NextProtoTLS, "http/1.1"}
+		}
+	}
+
+	if cfg.EnableH2C && !cfg.EnableTLS && !cfg.DisableHTTP2 {
+		log.Infof("Enabling HTTP/2 over cleartext (h2c)")
+		srv.httpServer.Handler = h2c.NewHandler(srv.httpServer.Handler, &http2.Server{})
+	}
+
+	if cfg.HTTP3ListenAddr != "" {
+		srv.http3Server = &http3.Server{
+			Addr:    cfg.HTTP3ListenAddr,
+			Handler: srv.httpRouter,
+		}
+
+		// Advertise HTTP/3 to TCP clients via the Alt-Svc header
+		next := srv.httpServer.Handler
+		srv.httpServer.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := srv.http3Server.SetQUICHeaders(w.Header())
+			if err != nil {
+				log.Debugf("Unable to set HTTP/3 Alt-Svc header - %s", err)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	return nil
+}
+
+// startHTTP3 will open the HTTP/3 UDP listener and serve requests in the background.
+func startHTTP3() error {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("could not load certificates for HTTP/3 listener - %s", err)
+	}
+	srv.http3Server.TLSConfig = http3.ConfigureTLSConfig(&tls.Config{
+		MinVersion:     tls.VersionTLS13,
+		Certificates:   []tls.Certificate{cert},
+		GetCertificate: srv.httpServer.TLSConfig.GetCertificate,
+		ClientAuth:     srv.httpServer.TLSConfig.ClientAuth,
+		ClientCAs:      srv.httpServer.TLSConfig.ClientCAs,
+	})
+
+	conn, err := net.ListenPacket("udp", cfg.HTTP3ListenAddr)
+	if err != nil {
+		return fmt.Errorf("could not start HTTP/3 listener - %s", err)
+	}
+
+	log.Infof("Starting HTTP/3 Listener on %s", cfg.HTTP3ListenAddr)
+	go func() {
+		err := srv.http3Server.Serve(conn)
+		if err != nil && err != http.ErrServerClosed {
+			log.Errorf("HTTP/3 listener stopped - %s", err)
+		}
+	}()
+	return nil
+}
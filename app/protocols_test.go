@@ -0,0 +1,167 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/madflojo/mockitout/config"
+	"github.com/madflojo/mockitout/mocks"
+	"github.com/madflojo/testcerts"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/http2"
+)
+
+// newProtocolServer will setup the HTTP server with the provided config, serving a
+// single mocked route.
+func newProtocolServer(t *testing.T, c config.Config) {
+	cfg = c
+	log = logrus.New()
+	log.Level = logrus.FatalLevel
+
+	mocked = mocks.Mocks{}
+	mocked.AddRoute("hello", mocks.Route{
+		Path: "/hello",
+		Body: "Hello World",
+	})
+
+	srv = &server{
+		httpRouter: httprouter.New(),
+	}
+	srv.httpServer = &http.Server{
+		Handler: srv.httpRouter,
+	}
+	if cfg.EnableTLS {
+		srv.httpServer.TLSConfig = &tls.Config{}
+	}
+	srv.httpRouter.SaveMatchedRoutePath = true
+	err := srv.registerMocks()
+	if err != nil {
+		t.Fatalf("Unable to register mocks - %s", err)
+	}
+
+	err = configureProtocols()
+	if err != nil {
+		t.Fatalf("Unexpected error configuring protocols - %s", err)
+	}
+}
+
+func TestHTTP2(t *testing.T) {
+	tc := map[string]struct {
+		cfg   config.Config
+		proto string
+	}{
+		"Enabled":  {cfg: config.Config{EnableTLS: true}, proto: "HTTP/2.0"},
+		"Disabled": {cfg: config.Config{EnableTLS: true, DisableHTTP2: true}, proto: "HTTP/1.1"},
+	}
+
+	for name, c := range tc {
+		t.Run(name, func(t *testing.T) {
+			newProtocolServer(t, c.cfg)
+			ts := httptest.NewUnstartedServer(srv.httpServer.Handler)
+			ts.Config = srv.httpServer
+			ts.TLS = srv.httpServer.TLSConfig
+			ts.EnableHTTP2 = !c.cfg.DisableHTTP2
+			ts.StartTLS()
+			defer ts.Close()
+
+			client := ts.Client()
+			client.Transport.(*http.Transport).ForceAttemptHTTP2 = true
+			r, err := client.Get(ts.URL + "/hello")
+			if err != nil {
+				t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+			}
+			defer r.Body.Close()
+			if r.Proto != c.proto {
+				t.Errorf("Unexpected protocol %s, expected %s", r.Proto, c.proto)
+			}
+		})
+	}
+}
+
+func TestH2C(t *testing.T) {
+	newProtocolServer(t, config.Config{EnableH2C: true})
+	ts := httptest.NewServer(srv.httpServer.Handler)
+	defer ts.Close()
+
+	client := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		},
+	}
+	r, err := client.Get(ts.URL + "/hello")
+	if err != nil {
+		t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+	}
+	defer r.Body.Close()
+	if r.Proto != "HTTP/2.0" {
+		t.Errorf("Unexpected protocol %s, expected HTTP/2.0", r.Proto)
+	}
+}
+
+func TestHTTP3(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mockitout")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir - %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cert, key := dir+"/cert", dir+"/key"
+	err = testcerts.GenerateCertsToFile(cert, key)
+	if err != nil {
+		t.Fatalf("Failed to create certs - %s", err)
+	}
+
+	newProtocolServer(t, config.Config{
+		EnableTLS:       true,
+		CertFile:        cert,
+		KeyFile:         key,
+		HTTP3ListenAddr: "localhost:9443",
+	})
+	err = startHTTP3()
+	if err != nil {
+		t.Fatalf("Unexpected error starting HTTP/3 listener - %s", err)
+	}
+	defer srv.http3Server.Close()
+
+	t.Run("Request", func(t *testing.T) {
+		tr := &http3.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+		defer tr.Close()
+
+		r, err := (&http.Client{Transport: tr}).Get("https://localhost:9443/hello")
+		if err != nil {
+			t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+		}
+		defer r.Body.Close()
+		if r.Proto != "HTTP/3.0" {
+			t.Errorf("Unexpected protocol %s, expected HTTP/3.0", r.Proto)
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Unexpected error reading body - %s", err)
+		}
+		if string(body) != "Hello World" {
+			t.Errorf("Unexpected body %q", body)
+		}
+	})
+
+	t.Run("Alt-Svc", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/hello", nil))
+		if w.Header().Get("Alt-Svc") == "" {
+			t.Errorf("Expected Alt-Svc header to be set")
+		}
+	})
+}
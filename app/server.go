@@ -1,12 +1,17 @@
 package app
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 
+	"github.com/brianvoe/gofakeit/v7"
 	"github.com/julienschmidt/httprouter"
+	"github.com/madflojo/mockitout/certs"
 	"github.com/madflojo/mockitout/mocks"
 	"github.com/madflojo/mockitout/variable"
+	"github.com/quic-go/quic-go/http3"
 	"github.com/sirupsen/logrus"
 )
 
@@ -15,8 +20,25 @@ type server struct {
 	// httpServer is the primary HTTP server.
 	httpServer *http.Server
 
+	// http3Server is the HTTP/3 server, this is only set when HTTP/3 is enabled.
+	http3Server *http3.Server
+
 	// httpRouter is used to store and access the HTTP Request Router.
 	httpRouter *httprouter.Router
+
+	// ca is the CA of generated certificates, this is only set when certificates are
+	// generated.
+	ca *certs.CA
+
+	// sni selects generated certificates by SNI hostname, this is only set when
+	// certificates are generated.
+	sni *certs.SNI
+
+	// sessions are the active WebSocket sessions.
+	sessions wsSessions
+
+	// tlsProfiles are the misbehaving TLS profile listeners.
+	tlsProfiles []*tlsProfile
 }
 
 // Health is used to handle HTTP Health requests to this service.
@@ -26,69 +48,190 @@ func (s *server) Health(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 
 // MockHandler is used to handle HTTP requests to the Mock Server.
 func (s *server) MockHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	var route mocks.Route
-	var path string
-	var ok bool
-	if path, ok = mocked.Paths[ps.MatchedRoutePath()]; !ok {
-		log.Errorf("Request URI %s not found within Mocks file - available paths %+v", r.RequestURI, mocked)
+	name, route, ok := mocked.Lookup(ps.MatchedRoutePath(), r)
+	if !ok {
+		log.Errorf("Request URI %s not matched by any route within Mocks file - available paths %+v", r.RequestURI, mocked.Paths)
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
-	if route, ok = mocked.Routes[path]; !ok {
-		log.Errorf("Request URI %s not found within Mocks file when looking for route named %s", r.RequestURI, path)
-		w.WriteHeader(http.StatusNotFound)
+
+	if !authenticate(w, r, route) {
 		return
 	}
 
-	ctx := variable.NewVariableInstance(r, w, ps)
+	ctx := newReplacer(route, r, w, ps)
 
 	// Verify Return Code is set if not default to 200
 	if route.ReturnCode == 0 {
 		route.ReturnCode = 200
 	}
 
-	log.WithFields(logrus.Fields{
-		"return-code": route.ReturnCode,
-		"path":        route.Path,
-	}).Infof("Mocked end-point found for %s", r.RequestURI)
+	log.WithFields(clientCertFields(r, logrus.Fields{
+		"return-code":   route.ReturnCode,
+		"path":          route.Path,
+		"route":         name,
+		"http-protocol": r.Proto,
+		"request-id":    variable.RequestID(r.Context()),
+	})).Infof("Mocked end-point found for %s", r.RequestURI)
+
+	if route.WebSocket != nil {
+		s.WebSocketHandler(w, r, ps, route)
+		return
+	}
+
+	// Render user defined headers, strict routes fail before the response is started
+	var u unresolved
+	headers := http.Header{}
+	if u.add(setHeaders(headers, route, ctx)) {
+		u.write(w, r, route)
+		return
+	}
+
+	if route.SSE != nil {
+		copyHeaders(w.Header(), headers)
+		s.SSEHandler(w, r, route, ctx)
+		return
+	}
 
-	// Add any user defined headers
+	if len(route.Chunks) > 0 || route.Stream != nil {
+		copyHeaders(w.Header(), headers)
+		s.StreamHandler(w, r, route, ctx)
+		return
+	}
+
+	// Render Body
+	varBody, err := render(route, ctx, route.Body, route.CompiledBody())
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"path": route.Path,
+		}).Errorf("Error parsing body variable %s - %s", route.Body, err)
+	}
+	if u.add(err) {
+		u.write(w, r, route)
+		return
+	}
+
+	// Write out user defined headers and response code
+	copyHeaders(w.Header(), headers)
+	w.WriteHeader(route.ReturnCode)
+
+	// Write Body to caller
+	_, _ = io.WriteString(w, varBody)
+}
+
+// replacer is used to replace variables within response values.
+type replacer interface {
+	ReplaceVariables(data string) (string, error)
+	Render(t *variable.Template) (string, error)
+	Unresolved() []variable.UnresolvedVariable
+}
+
+// render will replace the variables of one of the route's response values using its
+// template compiled when the route was loaded, the value is compiled when t is nil.
+// Variables left as is outside strict mode are logged at debug level.
+func render(route mocks.Route, ctx replacer, v string, t *variable.Template) (string, error) {
+	if t == nil {
+		var err error
+		t, err = route.Compiled(v)
+		if err != nil {
+			return v, err
+		}
+	}
+	s, err := ctx.Render(t)
+	if u := ctx.Unresolved(); err == nil && len(u) > 0 {
+		log.WithFields(logrus.Fields{
+			"path": route.Path,
+		}).Debugf("Unresolved variables left as is - %s", &variable.UnresolvedError{Variables: u})
+	}
+	return s, err
+}
+
+// newReplacer will create the replacer used to render the route's responses.
+func newReplacer(route mocks.Route, r *http.Request, w http.ResponseWriter, ps httprouter.Params) replacer {
+	if route.Template == mocks.TemplateGo {
+		t := variable.NewTemplateInstance(r, w, ps)
+		t.SetStrict(route.IsStrict(cfg.StrictVariables))
+		return t
+	}
+	v := variable.NewVariableInstance(r, w, ps)
+	v.SetStrict(route.IsStrict(cfg.StrictVariables))
+	return v
+}
+
+// setHeaders will add the route's user defined headers, replacing any variables. The
+// errors of headers which could not be rendered are returned.
+func setHeaders(h http.Header, route mocks.Route, ctx replacer) error {
+	var errs []error
 	for k, v := range route.ResponseHeaders {
-		v, err := ctx.ReplaceVariables(v)
+		v, err := render(route, ctx, v, route.CompiledHeader(k))
 		if err != nil {
 			log.WithFields(logrus.Fields{
 				"path": route.Path,
 			}).Errorf("Error parsing header variable %s - %s", v, err)
+			errs = append(errs, err)
 			continue
 		}
-		w.Header().Set(k, v)
+		h.Set(k, v)
 	}
+	return errors.Join(errs...)
+}
 
-	// Write out user defined response code
-	w.WriteHeader(route.ReturnCode)
+// copyHeaders will set the headers of src within dst.
+func copyHeaders(dst, src http.Header) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
 
-	// Write Body to caller
-	varBody, err := ctx.ReplaceVariables(route.Body)
-	if err != nil {
-		log.WithFields(logrus.Fields{
-			"path": route.Path,
-		}).Errorf("Error parsing body variable %s - %s", route.Body, err)
+// registerMocks is used to register the loaded mock routes with the HTTP router.
+func (s *server) registerMocks() error {
+	for p := range mocked.Paths {
+		log.Infof("Registering mocks %v with path %s", mocked.Paths[p], p)
+		for _, m := range mocked.Methods(p) {
+			err := s.handle(m, p, s.MockHandler)
+			if err != nil {
+				return err
+			}
+		}
 	}
-	fmt.Fprintf(w, "%s", varBody)
+	return nil
+}
+
+// handle will register the handler with the HTTP router, the router panics when a path
+// conflicts with a registered path which is returned as an error instead.
+func (s *server) handle(method, path string, h httprouter.Handle) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("could not register %s %s - %v", method, path, r)
+		}
+	}()
+	s.httpRouter.Handle(method, path, s.middleware(h))
+	return nil
 }
 
 // middleware is used to intercept incoming HTTP calls and apply general functions upon
 // them. e.g. Metrics, Logging...
 func (s *server) middleware(n httprouter.Handle) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		// Assign an ID to the request, used for logging and the request.id variable, a
+		// client provided ID is echoed back
+		id := r.Header.Get(variable.RequestIDHeader)
+		if id != "" {
+			w.Header().Set(variable.RequestIDHeader, id)
+		} else {
+			id = gofakeit.UUID()
+		}
+		r = r.WithContext(variable.WithRequestID(r.Context(), id))
+
 		// Log the basics
-		log.WithFields(logrus.Fields{
+		log.WithFields(clientCertFields(r, logrus.Fields{
+			"request-id":     id,
 			"method":         r.Method,
 			"remote-addr":    r.RemoteAddr,
 			"http-protocol":  r.Proto,
 			"headers":        r.Header,
 			"content-length": r.ContentLength,
-		}).Debugf("HTTP Request to %s", r.URL)
+		})).Debugf("HTTP Request to %s", r.URL)
 
 		// Call registered handler
 		n(w, r, ps)
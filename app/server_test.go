@@ -4,9 +4,12 @@ import (
 	"encoding/json"
 	"github.com/madflojo/mockitout/config"
 	"github.com/madflojo/mockitout/mocks"
+	"github.com/sirupsen/logrus"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -128,3 +131,286 @@ func TestMockServer(t *testing.T) {
 		}
 	})
 }
+
+func TestPactMockServer(t *testing.T) {
+	fh, err := ioutil.TempFile("", "pact_example")
+	if err != nil {
+		t.Fatalf("Could not create Pact file - %s", err)
+	}
+	defer os.Remove(fh.Name())
+	_, err = fh.Write([]byte(`{
+  "consumer": {"name": "frontend"},
+  "provider": {"name": "users"},
+  "interactions": [
+    {
+      "description": "user exists",
+      "providerStates": [{"name": "user exists"}],
+      "request": {"method": "GET", "path": "/users/1"},
+      "response": {"status": 200, "body": {"name": "Andre"}}
+    },
+    {
+      "description": "user missing",
+      "providerStates": [{"name": "user missing"}],
+      "request": {"method": "GET", "path": "/users/1"},
+      "response": {"status": 404}
+    },
+    {
+      "description": "update user",
+      "request": {"method": "PATCH", "path": "/users/1", "body": {"name": "Andre"}},
+      "response": {"status": 204}
+    }
+  ],
+  "metadata": {"pactSpecification": {"version": "3.0.0"}}
+}`))
+	if err != nil {
+		t.Fatalf("Could not write Pact file - %s", err)
+	}
+	fh.Close()
+
+	go func() {
+		err := Run(config.Config{
+			Debug:          true,
+			EnableTLS:      false,
+			ListenAddr:     "localhost:9000",
+			DisableLogging: true,
+			PactFiles:      []string{fh.Name()},
+		})
+		if err != nil && err != ErrShutdown {
+			t.Errorf("Run unexpectedly stopped - %s", err)
+		}
+	}()
+	// Clean up
+	defer Stop()
+
+	// Wait for app to start
+	time.Sleep(10 * time.Second)
+
+	states := map[string]int{
+		"":             200,
+		"user exists":  200,
+		"user missing": 404,
+	}
+	for k, v := range states {
+		t.Run("Check Pact Interaction with state "+k, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "http://localhost:9000/users/1", nil)
+			if k != "" {
+				req.Header.Set(mocks.ProviderStateHeader, k)
+			}
+			r, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+			}
+			if r.StatusCode != v {
+				t.Errorf("Unexpected http status code - %d", r.StatusCode)
+			}
+		})
+	}
+
+	t.Run("Check Pact Interaction with body", func(t *testing.T) {
+		req, _ := http.NewRequest("PATCH", "http://localhost:9000/users/1", strings.NewReader(`{"name": "Andre"}`))
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+		}
+		if r.StatusCode != 204 {
+			t.Errorf("Unexpected http status code - %d", r.StatusCode)
+		}
+	})
+
+	t.Run("Check Unmatched Pact Interaction", func(t *testing.T) {
+		req, _ := http.NewRequest("PATCH", "http://localhost:9000/users/1", strings.NewReader(`{"name": "Jim"}`))
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+		}
+		if r.StatusCode != 404 {
+			t.Errorf("Unexpected http status code - %d", r.StatusCode)
+		}
+	})
+}
+
+func TestLoadPactFiles(t *testing.T) {
+	write := func(consumer string) string {
+		fh, err := ioutil.TempFile(t.TempDir(), "pact_example")
+		if err != nil {
+			t.Fatalf("Could not create Pact file - %s", err)
+		}
+		defer fh.Close()
+		_, err = fh.Write([]byte(`{
+  "consumer": {"name": "` + consumer + `"},
+  "provider": {"name": "users"},
+  "interactions": [{"description": "get user", "request": {"method": "GET", "path": "/users/1"}, "response": {"status": 200}}],
+  "metadata": {"pactSpecification": {"version": "3.0.0"}}
+}`))
+		if err != nil {
+			t.Fatalf("Could not write Pact file - %s", err)
+		}
+		return fh.Name()
+	}
+	frontend, mobile := write("frontend"), write("mobile")
+	log = logrus.New()
+	log.Level = logrus.FatalLevel
+	defer func() { cfg = config.Config{} }()
+
+	t.Run("Consumers of one provider", func(t *testing.T) {
+		cfg = config.Config{PactFiles: []string{frontend, mobile}}
+		m, err := loadMocks()
+		if err != nil {
+			t.Fatalf("Unexpected error loading Pact files - %s", err)
+		}
+		if len(m.Routes) != 2 {
+			t.Errorf("Unexpected routes, each consumer's interactions should be kept - %+v", m.Routes)
+		}
+	})
+
+	t.Run("Duplicate routes", func(t *testing.T) {
+		cfg = config.Config{PactFiles: []string{frontend, frontend}}
+		_, err := loadMocks()
+		if err == nil {
+			t.Errorf("Expected error loading Pact files with duplicate routes")
+		}
+	})
+}
+
+func TestTemplateMockHandler(t *testing.T) {
+	m := mocks.Mocks{}
+	m.AddRoute("users", mocks.Route{
+		Path:     "/users/:id",
+		Template: mocks.TemplateGo,
+		ResponseHeaders: map[string]string{
+			"x-role": `{{ .Query.type | default "user" }}`,
+		},
+		Body: `{{ if eq .Query.type "admin" }}admin {{ .Params.id }}{{ else }}user {{ .Params.id }}{{ end }}`,
+	})
+	ts := newTestServer(t, m)
+	defer ts.Close()
+
+	cases := map[string]struct {
+		query string
+		role  string
+		body  string
+	}{
+		"Admin": {"?type=admin", "admin", "admin 10"},
+		"User":  {"", "user", "user 10"},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			r, err := ts.Client().Get(ts.URL + "/users/10" + v.query)
+			if err != nil {
+				t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+			}
+			defer r.Body.Close()
+
+			if r.Header.Get("x-role") != v.role {
+				t.Errorf("Unexpected header value %s", r.Header.Get("x-role"))
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("Unable to read HTTP response body - %s", err)
+			}
+			if string(body) != v.body {
+				t.Errorf("Unexpected body %q", body)
+			}
+		})
+	}
+}
+
+func TestRequestVariablesMockHandler(t *testing.T) {
+	m := mocks.Mocks{}
+	m.AddRoute("request", mocks.Route{
+		Path: "/request",
+		Body: "{{ request.id }} {{ request.method }} {{ cookie.session }}",
+	})
+	ts := newTestServer(t, m)
+	defer ts.Close()
+
+	cases := map[string]struct {
+		id string
+	}{
+		"Provided ID":  {"abc-123"},
+		"Generated ID": {""},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			req, err := http.NewRequest("POST", ts.URL+"/request", nil)
+			if err != nil {
+				t.Fatalf("Unable to create request - %s", err)
+			}
+			req.AddCookie(&http.Cookie{Name: "session", Value: "xyz"})
+			if v.id != "" {
+				req.Header.Set("X-Request-ID", v.id)
+			}
+
+			r, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+			}
+			defer r.Body.Close()
+
+			if id := r.Header.Get("X-Request-ID"); id != v.id {
+				t.Errorf("Unexpected request ID header %q, only provided IDs should be returned", id)
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("Unable to read HTTP response body - %s", err)
+			}
+			id, rest, _ := strings.Cut(string(body), " ")
+			if id == "" || (v.id != "" && id != v.id) || rest != "POST xyz" {
+				t.Errorf("Unexpected body %q", body)
+			}
+		})
+	}
+}
+
+func BenchmarkMockHandler(b *testing.B) {
+	m := mocks.Mocks{}
+	m.AddRoute("static", mocks.Route{
+		Path:            "/static",
+		ResponseHeaders: map[string]string{"content-type": "application/json"},
+		Body:            `{"greeting": "Hello", "name": "World"}`,
+	})
+	m.AddRoute("variables", mocks.Route{
+		Path:            "/variables/:id",
+		ResponseHeaders: map[string]string{"content-type": "application/json", "x-id": "{{ param.id }}"},
+		Body:            `{"id": "{{ param.id }}", "type": "{{ query.type }}", "name": "{{ $randomFirstName }}"}`,
+	})
+	m.AddRoute("body", mocks.Route{
+		Path:   "/body",
+		Method: "POST",
+		Body:   `{"name": "{{ body.name }}", "role": "{{ body.role }}"}`,
+	})
+	m.AddRoute("template", mocks.Route{
+		Path:     "/template/:id",
+		Template: mocks.TemplateGo,
+		Body:     `{"id": "{{ .Params.id }}", "type": "{{ .Query.type | default "user" }}"}`,
+	})
+	ts := newTestServer(b, m)
+	ts.Close()
+
+	benchmarks := map[string]struct {
+		method string
+		url    string
+		body   string
+	}{
+		"Static":    {"GET", "/static", ""},
+		"Variables": {"GET", "/variables/10?type=admin", ""},
+		"Body":      {"POST", "/body", `{"name": "Jim", "role": "admin"}`},
+		"Template":  {"GET", "/template/10?type=admin", ""},
+	}
+
+	for k, v := range benchmarks {
+		b.Run(k, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r := httptest.NewRequest(v.method, v.url, strings.NewReader(v.body))
+				w := httptest.NewRecorder()
+				srv.httpRouter.ServeHTTP(w, r)
+				if w.Code != http.StatusOK {
+					b.Fatalf("Unexpected http status code - %d", w.Code)
+				}
+			}
+		})
+	}
+}
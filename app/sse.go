@@ -0,0 +1,136 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/madflojo/mockitout/clock"
+	"github.com/madflojo/mockitout/mocks"
+	"github.com/madflojo/mockitout/variable"
+	"github.com/sirupsen/logrus"
+)
+
+// SSEHandler is used to stream Server-Sent Events to the client. Events are written
+// and flushed one at a time until the list is complete or the client disconnects.
+func (s *server) SSEHandler(w http.ResponseWriter, r *http.Request, route mocks.Route, ctx replacer) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.WithFields(logrus.Fields{
+			"path": route.Path,
+		}).Errorf("Unable to stream events, response writer does not support flushing")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Set default streaming headers unless defined by the user
+	for k, v := range map[string]string{
+		"Content-Type":  "text/event-stream",
+		"Cache-Control": "no-cache",
+		"Connection":    "keep-alive",
+	} {
+		if w.Header().Get(k) == "" {
+			w.Header().Set(k, v)
+		}
+	}
+
+	// Render the first pass of events before the response is started, allowing strict
+	// routes to fail
+	var u unresolved
+	events := make([][]byte, len(route.SSE.Events))
+	for i, e := range route.SSE.Events {
+		b, err := formatEvent(e, route, ctx)
+		u.add(err)
+		events[i] = b
+	}
+	if len(u) > 0 {
+		u.write(w, r, route)
+		return
+	}
+
+	w.WriteHeader(route.ReturnCode)
+	flusher.Flush()
+
+	for pass := 0; ; pass++ {
+		for i, e := range route.SSE.Events {
+			if !sleep(r, e.Delay) {
+				return
+			}
+
+			event := events[i]
+			if pass > 0 {
+				b, err := formatEvent(e, route, ctx)
+				if u.add(err) {
+					// the stream has started, end it rather than sending unresolved variables
+					return
+				}
+				event = b
+			}
+
+			_, err := w.Write(event)
+			if err != nil {
+				log.WithFields(logrus.Fields{
+					"path": route.Path,
+				}).Debugf("Unable to write event to client - %s", err)
+				return
+			}
+			flusher.Flush()
+		}
+
+		if !route.SSE.Loop {
+			return
+		}
+	}
+}
+
+// formatEvent will replace any variables and format the event in the Server-Sent
+// Events wire format. The errors of values which could not be rendered are returned.
+func formatEvent(e mocks.SSEEvent, route mocks.Route, ctx replacer) ([]byte, error) {
+	var errs []error
+	replace := func(v string, t *variable.Template) string {
+		val, err := render(route, ctx, v, t)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"path": route.Path,
+			}).Errorf("Error parsing event variable %s - %s", v, err)
+			errs = append(errs, err)
+		}
+		return val
+	}
+
+	var b bytes.Buffer
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", replace(e.ID, e.CompiledID()))
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", replace(e.Event, e.CompiledEvent()))
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", e.Retry)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(replace(e.Data, e.CompiledData()), "\n"), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	return b.Bytes(), errors.Join(errs...)
+}
+
+// sleep will pause for the provided duration on the virtual clock, returning false if
+// the client request is cancelled before the duration has passed.
+func sleep(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return r.Context().Err() == nil
+	}
+
+	t := clock.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-r.Context().Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
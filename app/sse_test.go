@@ -0,0 +1,81 @@
+package app
+
+import (
+	"bufio"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madflojo/mockitout/mocks"
+)
+
+func TestSSEHandler(t *testing.T) {
+	m := mocks.Mocks{}
+	m.AddRoute("events", mocks.Route{
+		Path: "/events/:id",
+		SSE: &mocks.SSE{
+			Events: []mocks.SSEEvent{
+				{ID: "1", Event: "greeting", Data: "hello {{ param.id }}", Retry: 1000},
+				{ID: "2", Data: "line one\nline two\n", Delay: 10 * time.Millisecond},
+			},
+		},
+	})
+	m.AddRoute("loop", mocks.Route{
+		Path: "/loop",
+		SSE: &mocks.SSE{
+			Loop:   true,
+			Events: []mocks.SSEEvent{{Data: "tick", Delay: 10 * time.Millisecond}},
+		},
+	})
+	ts := newTestServer(t, m)
+	defer ts.Close()
+
+	t.Run("Events", func(t *testing.T) {
+		r, err := ts.Client().Get(ts.URL + "/events/andre")
+		if err != nil {
+			t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+		}
+		defer r.Body.Close()
+
+		if r.StatusCode != 200 {
+			t.Errorf("Unexpected http status code - %d", r.StatusCode)
+		}
+		if r.Header.Get("Content-Type") != "text/event-stream" {
+			t.Errorf("Unexpected content type - %s", r.Header.Get("Content-Type"))
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Unable to read HTTP response body - %s", err)
+		}
+		expected := "id: 1\nevent: greeting\nretry: 1000\ndata: hello andre\n\nid: 2\ndata: line one\ndata: line two\n\n"
+		if string(body) != expected {
+			t.Errorf("Unexpected event stream %q", body)
+		}
+	})
+
+	t.Run("Loop", func(t *testing.T) {
+		r, err := ts.Client().Get(ts.URL + "/loop")
+		if err != nil {
+			t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+		}
+		defer r.Body.Close()
+
+		// Read more events than defined to verify looping
+		reader := bufio.NewReader(r.Body)
+		for i := 0; i < 3; i++ {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Unable to read event - %s", err)
+			}
+			if strings.TrimSpace(line) != "data: tick" {
+				t.Errorf("Unexpected event line %q", line)
+			}
+			_, err = reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Unable to read event - %s", err)
+			}
+		}
+	})
+}
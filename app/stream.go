@@ -0,0 +1,95 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/madflojo/mockitout/mocks"
+	"github.com/sirupsen/logrus"
+)
+
+// StreamHandler is used to write the response body incrementally. Either the route's
+// chunks are written with their delays, or the body is streamed in fixed size chunks
+// at the defined rate. Each write is flushed to the client. Variables are replaced
+// before the response is started.
+func (s *server) StreamHandler(w http.ResponseWriter, r *http.Request, route mocks.Route, ctx replacer) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.WithFields(logrus.Fields{
+			"path": route.Path,
+		}).Errorf("Unable to stream body, response writer does not support flushing")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Render chunks and body before the response is started, allowing strict routes to fail
+	var u unresolved
+	chunks := make([][]byte, len(route.Chunks))
+	for i, c := range route.Chunks {
+		body, err := render(route, ctx, c.Body, c.CompiledBody())
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"path": route.Path,
+			}).Errorf("Error parsing chunk variable %s - %s", c.Body, err)
+			u.add(err)
+		}
+		chunks[i] = []byte(body)
+	}
+
+	var data []byte
+	if route.Stream != nil {
+		body, err := render(route, ctx, route.Body, route.CompiledBody())
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"path": route.Path,
+			}).Errorf("Error parsing body variable %s - %s", route.Body, err)
+			u.add(err)
+		}
+		data = []byte(body)
+	}
+
+	if len(u) > 0 {
+		u.write(w, r, route)
+		return
+	}
+
+	w.WriteHeader(route.ReturnCode)
+	flusher.Flush()
+
+	write := func(b []byte) bool {
+		_, err := w.Write(b)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"path": route.Path,
+			}).Debugf("Unable to write chunk to client - %s", err)
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	for i, c := range route.Chunks {
+		if !sleep(r, c.Delay) {
+			return
+		}
+		if !write(chunks[i]) {
+			return
+		}
+	}
+
+	if route.Stream == nil {
+		return
+	}
+
+	for i := 0; i < len(data); i += route.Stream.Size {
+		if i > 0 && !sleep(r, route.Stream.Delay()) {
+			return
+		}
+		end := i + route.Stream.Size
+		if end > len(data) {
+			end = len(data)
+		}
+		if !write(data[i:end]) {
+			return
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/madflojo/mockitout/mocks"
+)
+
+func TestStreamHandler(t *testing.T) {
+	m := mocks.Mocks{}
+	m.AddRoute("chunks", mocks.Route{
+		Path:       "/chunks/:id",
+		ReturnCode: 206,
+		Chunks: []mocks.Chunk{
+			{Body: `{"id": "{{ param.id }}", `},
+			{Body: `"items": [1, 2]}`, Delay: 100 * time.Millisecond},
+		},
+	})
+	m.AddRoute("stream", mocks.Route{
+		Path:   "/stream",
+		Body:   "0123456789abcdefghij",
+		Stream: &mocks.Stream{Size: 5, Rate: 100},
+	})
+	ts := newTestServer(t, m)
+	defer ts.Close()
+
+	cases := map[string]struct {
+		path    string
+		code    int
+		body    string
+		minTime time.Duration
+	}{
+		"Chunks": {"/chunks/andre", 206, `{"id": "andre", "items": [1, 2]}`, 100 * time.Millisecond},
+		"Stream": {"/stream", 200, "0123456789abcdefghij", 150 * time.Millisecond},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			start := time.Now()
+			r, err := ts.Client().Get(ts.URL + v.path)
+			if err != nil {
+				t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+			}
+			defer r.Body.Close()
+
+			if r.StatusCode != v.code {
+				t.Errorf("Unexpected http status code - %d", r.StatusCode)
+			}
+			if len(r.TransferEncoding) == 0 || r.TransferEncoding[0] != "chunked" {
+				t.Errorf("Expected chunked transfer encoding - %v", r.TransferEncoding)
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("Unable to read HTTP response body - %s", err)
+			}
+			if string(body) != v.body {
+				t.Errorf("Unexpected body %q", body)
+			}
+			if time.Since(start) < v.minTime {
+				t.Errorf("Response was faster than expected - %s", time.Since(start))
+			}
+		})
+	}
+}
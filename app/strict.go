@@ -0,0 +1,70 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/madflojo/mockitout/mocks"
+	"github.com/madflojo/mockitout/variable"
+	"github.com/sirupsen/logrus"
+)
+
+// diagnostic is the response body returned when a strict route has unresolved
+// variables.
+type diagnostic struct {
+	Error     string               `json:"error"`
+	Route     string               `json:"route"`
+	RequestID string               `json:"request_id"`
+	Variables []diagnosticVariable `json:"variables"`
+}
+
+// diagnosticVariable is a single unresolved variable and the reason it failed.
+type diagnosticVariable struct {
+	Variable string `json:"variable"`
+	Error    string `json:"error"`
+}
+
+// unresolved collects the variables which could not be resolved while rendering the
+// response of a strict route.
+type unresolved []variable.UnresolvedVariable
+
+// add will record the unresolved variables held by err, true is returned if any were
+// found.
+func (u *unresolved) add(err error) bool {
+	switch e := err.(type) {
+	case *variable.UnresolvedError:
+		*u = append(*u, e.Variables...)
+		return true
+	case interface{ Unwrap() []error }:
+		found := false
+		for _, err := range e.Unwrap() {
+			if u.add(err) {
+				found = true
+			}
+		}
+		return found
+	}
+	return false
+}
+
+// write will respond with a 500 listing the unresolved variables, this must be
+// called before the response is started.
+func (u unresolved) write(w http.ResponseWriter, r *http.Request, route mocks.Route) {
+	d := diagnostic{
+		Error:     "unresolved variables",
+		Route:     route.Path,
+		RequestID: variable.RequestID(r.Context()),
+	}
+	for _, v := range u {
+		d.Variables = append(d.Variables, diagnosticVariable{Variable: v.Variable, Error: v.Err.Error()})
+	}
+
+	log.WithFields(logrus.Fields{
+		"path":       route.Path,
+		"request-id": d.RequestID,
+	}).Errorf("Unable to resolve variables for strict route - %s", &variable.UnresolvedError{Variables: u})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	_ = json.NewEncoder(w).Encode(d)
+}
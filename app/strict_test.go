@@ -0,0 +1,140 @@
+package app
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/madflojo/mockitout/mocks"
+)
+
+func TestStrictVariables(t *testing.T) {
+	strict, lenient := true, false
+	m := mocks.Mocks{}
+	m.AddRoute("strict", mocks.Route{
+		Path:            "/strict",
+		StrictVariables: &strict,
+		ResponseHeaders: map[string]string{"x-tenant": "{{ header.x-tenant }}"},
+		Body:            `{"tenant": "{{ header.x-tenant }}", "page": "{{ query.page | default "1" }}"}`,
+	})
+	m.AddRoute("strict-body", mocks.Route{
+		Path:            "/strict-body",
+		StrictVariables: &strict,
+		Body:            `{"tenant": "{{ header.x-tenant }}", "user": "{{ query.user }}"}`,
+	})
+	m.AddRoute("strict-template", mocks.Route{
+		Path:            "/strict-template",
+		StrictVariables: &strict,
+		Template:        mocks.TemplateGo,
+		Body:            `{{ .Query.user }}`,
+	})
+	m.AddRoute("strict-chunks", mocks.Route{
+		Path:            "/strict-chunks",
+		StrictVariables: &strict,
+		Chunks:          []mocks.Chunk{{Body: "a"}, {Body: "{{ header.x-tenant }}"}},
+	})
+	m.AddRoute("strict-sse", mocks.Route{
+		Path:            "/strict-sse",
+		StrictVariables: &strict,
+		SSE:             &mocks.SSE{Events: []mocks.SSEEvent{{Data: "{{ header.x-tenant }}"}}},
+	})
+	m.AddRoute("strict-websocket", mocks.Route{
+		Path:            "/strict-websocket",
+		StrictVariables: &strict,
+		ResponseHeaders: map[string]string{"x-tenant": "{{ header.x-tenant }}"},
+		WebSocket:       &mocks.WebSocket{},
+	})
+	m.AddRoute("lenient", mocks.Route{
+		Path:            "/lenient",
+		StrictVariables: &lenient,
+		Body:            `{{ header.x-tenant }}`,
+	})
+	m.AddRoute("default", mocks.Route{
+		Path: "/default",
+		Body: `{{ header.x-tenant }}`,
+	})
+	ts := newTestServer(t, m)
+	defer ts.Close()
+
+	cases := map[string]struct {
+		path       string
+		tenant     string
+		strict     bool
+		code       int
+		body       string
+		unresolved []string
+	}{
+		"Resolved":              {path: "/strict", tenant: "acme", code: 200, body: `{"tenant": "acme", "page": "1"}`},
+		"Missing Header":        {path: "/strict", code: 500, unresolved: []string{"{{ header.x-tenant }}"}},
+		"Multiple Missing":      {path: "/strict-body", code: 500, unresolved: []string{"{{ header.x-tenant }}", "{{ query.user }}"}},
+		"Go Template":           {path: "/strict-template", code: 500, unresolved: []string{"{{ .Query.user }}"}},
+		"Chunks":                {path: "/strict-chunks", code: 500, unresolved: []string{"{{ header.x-tenant }}"}},
+		"Server-Sent Events":    {path: "/strict-sse", code: 500, unresolved: []string{"{{ header.x-tenant }}"}},
+		"WebSocket Headers":     {path: "/strict-websocket", code: 500, unresolved: []string{"{{ header.x-tenant }}"}},
+		"Route Disabled":        {path: "/lenient", strict: true, code: 200, body: "{{ header.x-tenant }}"},
+		"Server Default":        {path: "/default", strict: true, code: 500, unresolved: []string{"{{ header.x-tenant }}"}},
+		"Server Default Unset":  {path: "/default", code: 200, body: "{{ header.x-tenant }}"},
+		"Server Default Tenant": {path: "/default", tenant: "acme", strict: true, code: 200, body: "acme"},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			cfg.StrictVariables = v.strict
+			defer func() { cfg.StrictVariables = false }()
+
+			req, err := http.NewRequest("GET", ts.URL+v.path, nil)
+			if err != nil {
+				t.Fatalf("Unable to create request - %s", err)
+			}
+			if v.tenant != "" {
+				req.Header.Set("x-tenant", v.tenant)
+			}
+
+			r, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+			}
+			defer r.Body.Close()
+
+			if r.StatusCode != v.code {
+				t.Fatalf("Unexpected http status code - %d", r.StatusCode)
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("Unable to read HTTP response body - %s", err)
+			}
+
+			if v.code != 500 {
+				if string(body) != v.body {
+					t.Errorf("Unexpected body %q", body)
+				}
+				return
+			}
+
+			if r.Header.Get("x-tenant") != "" {
+				t.Errorf("Unexpected user defined header on diagnostic response")
+			}
+			var d diagnostic
+			err = json.Unmarshal(body, &d)
+			if err != nil {
+				t.Fatalf("Unable to parse diagnostic response %q - %s", body, err)
+			}
+			if d.Route != v.path || d.RequestID == "" {
+				t.Errorf("Unexpected diagnostic response %+v", d)
+			}
+			if len(d.Variables) != len(v.unresolved) {
+				t.Fatalf("Unexpected unresolved variables %+v", d.Variables)
+			}
+			for i, u := range v.unresolved {
+				if d.Variables[i].Variable != u || d.Variables[i].Error == "" {
+					t.Errorf("Unexpected unresolved variable %+v, expected %s", d.Variables[i], u)
+				}
+			}
+			if strings.Contains(string(body), "acme") {
+				t.Errorf("Unexpected partial body within diagnostic response")
+			}
+		})
+	}
+}
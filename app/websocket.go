@@ -0,0 +1,305 @@
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+	"github.com/madflojo/mockitout/clock"
+	"github.com/madflojo/mockitout/mocks"
+	"github.com/madflojo/mockitout/variable"
+	"github.com/sirupsen/logrus"
+)
+
+// upgrader is used to upgrade HTTP requests to WebSocket connections. Mocked
+// end-points accept connections from any origin.
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsSession holds the state of a single scripted WebSocket connection.
+type wsSession struct {
+	// conn is the upgraded WebSocket connection.
+	conn *websocket.Conn
+
+	// route is the mocked route being served.
+	route mocks.Route
+
+	// r is the original HTTP upgrade request.
+	r *http.Request
+
+	// ps are the router params of the upgrade request.
+	ps httprouter.Params
+
+	// mu protects writes to the connection, only one writer is allowed at a time.
+	mu sync.Mutex
+
+	// done is closed once the session has ended.
+	done chan struct{}
+
+	// once ensures the session is only ended once.
+	once sync.Once
+
+	// wg tracks the goroutines of the session, run waits for them before returning.
+	wg sync.WaitGroup
+}
+
+// wsSessions tracks the active WebSocket sessions of a server, hijacked connections
+// are not closed by the HTTP server on shutdown.
+type wsSessions struct {
+	// mu protects active.
+	mu sync.Mutex
+
+	// active are the sessions being served.
+	active map[*wsSession]struct{}
+
+	// wg tracks the handlers of active sessions.
+	wg sync.WaitGroup
+}
+
+// add will track the session until it is removed.
+func (s *wsSessions) add(ws *wsSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active == nil {
+		s.active = make(map[*wsSession]struct{})
+	}
+	s.active[ws] = struct{}{}
+	s.wg.Add(1)
+}
+
+// remove will stop tracking the session.
+func (s *wsSessions) remove(ws *wsSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.active, ws)
+	s.wg.Done()
+}
+
+// closeAll will end every active session and wait for their handlers to return.
+func (s *wsSessions) closeAll() {
+	s.mu.Lock()
+	for ws := range s.active {
+		ws.end()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// WebSocketHandler is used to upgrade requests to mocked WebSocket end-points and
+// play the route's script.
+func (s *server) WebSocketHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params, route mocks.Route) {
+	ctx := newReplacer(route, r, w, ps)
+	headers := http.Header{}
+	var u unresolved
+	if u.add(setHeaders(headers, route, ctx)) {
+		u.write(w, r, route)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, headers)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"path": route.Path,
+		}).Errorf("Unable to upgrade WebSocket connection - %s", err)
+		return
+	}
+	defer conn.Close()
+
+	sess := &wsSession{
+		conn:  conn,
+		route: route,
+		r:     r,
+		ps:    ps,
+		done:  make(chan struct{}),
+	}
+	s.sessions.add(sess)
+	defer s.sessions.remove(sess)
+	sess.run()
+}
+
+// run will play the WebSocket script until the connection is closed.
+func (ws *wsSession) run() {
+	script := ws.route.WebSocket
+
+	ws.goroutine(ws.read)
+
+	for _, p := range script.Periodic {
+		p := p
+		ws.goroutine(func() { ws.periodic(p) })
+	}
+
+	if script.Close != nil {
+		ws.goroutine(func() {
+			if ws.wait(script.Close.After) {
+				ws.close(script.Close)
+			}
+		})
+	}
+
+	for _, m := range script.OnConnect {
+		if !ws.send(m.Message, m.CompiledMessage(), m.Delay, ws.r) {
+			break
+		}
+	}
+
+	<-ws.done
+
+	// close the connection to unblock the reader before waiting for it
+	ws.conn.Close()
+	ws.wg.Wait()
+}
+
+// goroutine will run f in the background, tracked by the session's wait group.
+func (ws *wsSession) goroutine(f func()) {
+	ws.wg.Add(1)
+	go func() {
+		defer ws.wg.Done()
+		f()
+	}()
+}
+
+// read will consume incoming messages, sending any matching replies.
+func (ws *wsSession) read() {
+	defer ws.end()
+	for {
+		_, msg, err := ws.conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.WithFields(logrus.Fields{
+					"path": ws.route.Path,
+				}).Debugf("WebSocket connection closed - %s", err)
+			}
+			return
+		}
+
+		log.WithFields(logrus.Fields{
+			"path": ws.route.Path,
+		}).Debugf("WebSocket message received - %s", msg)
+
+		for _, reply := range ws.route.WebSocket.Replies {
+			if !reply.Matches(msg) {
+				continue
+			}
+
+			// Expose the incoming message as the request body for variables
+			r := ws.r.Clone(ws.r.Context())
+			r.Body = ioutil.NopCloser(bytes.NewReader(msg))
+			r.ContentLength = int64(len(msg))
+
+			for _, m := range reply.Messages {
+				if !ws.send(m.Message, m.CompiledMessage(), m.Delay, r) {
+					return
+				}
+			}
+			if reply.Close != nil && ws.wait(reply.Close.After) {
+				ws.close(reply.Close)
+			}
+			break
+		}
+	}
+}
+
+// periodic will send the message at the defined interval until the session ends.
+func (ws *wsSession) periodic(p mocks.WebSocketPeriodic) {
+	for i := 0; p.Count == 0 || i < p.Count; i++ {
+		if !ws.wait(p.Interval) {
+			return
+		}
+		if !ws.send(p.Message, p.CompiledMessage(), 0, ws.r) {
+			return
+		}
+	}
+}
+
+// send will wait for the delay, replace any variables of the message using its compiled
+// template and write it to the client. False is returned if the session has ended.
+func (ws *wsSession) send(message string, t *variable.Template, delay time.Duration, r *http.Request) bool {
+	if !ws.wait(delay) {
+		return false
+	}
+
+	msg, err := render(ws.route, newReplacer(ws.route, r, nil, ws.ps), message, t)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"path": ws.route.Path,
+		}).Errorf("Error parsing websocket message variable %s - %s", message, err)
+	}
+	var u unresolved
+	if u.add(err) {
+		// the connection is established, close it rather than sending unresolved variables
+		ws.close(&mocks.WebSocketClose{Code: websocket.CloseInternalServerErr, Reason: "unresolved variables"})
+		return false
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	err = ws.conn.WriteMessage(websocket.TextMessage, []byte(msg))
+	if err != nil {
+		ws.end()
+		return false
+	}
+	return true
+}
+
+// close will send a close frame to the client and end the session.
+func (ws *wsSession) close(c *mocks.WebSocketClose) {
+	code := c.Code
+	if code == 0 {
+		code = websocket.CloseNormalClosure
+	}
+
+	ws.mu.Lock()
+	err := ws.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, c.Reason), time.Now().Add(time.Second))
+	ws.mu.Unlock()
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"path": ws.route.Path,
+		}).Debugf("Unable to send WebSocket close message - %s", err)
+	}
+
+	// Give the client a chance to acknowledge the close, in real time as the
+	// virtual clock may be frozen
+	ws.goroutine(func() {
+		t := time.NewTimer(time.Second)
+		defer t.Stop()
+		select {
+		case <-ws.done:
+		case <-t.C:
+			ws.end()
+		}
+	})
+}
+
+// wait will pause for the provided duration on the virtual clock, returning false if
+// the session ends before the duration has passed.
+func (ws *wsSession) wait(d time.Duration) bool {
+	if d <= 0 {
+		select {
+		case <-ws.done:
+			return false
+		default:
+			return true
+		}
+	}
+
+	t := clock.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ws.done:
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// end will mark the session as finished.
+func (ws *wsSession) end() {
+	ws.once.Do(func() {
+		close(ws.done)
+	})
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+	"github.com/madflojo/mockitout/mocks"
+	"github.com/sirupsen/logrus"
+)
+
+// newTestServer will create an HTTP test server serving the provided mocks. The server
+// and its WebSocket sessions are closed when the test ends, so that the package globals
+// are not replaced while earlier servers are still handling requests.
+func newTestServer(t testing.TB, m mocks.Mocks) *httptest.Server {
+	log = logrus.New()
+	log.Level = logrus.FatalLevel
+	mocked = m
+	s := &server{
+		httpRouter: httprouter.New(),
+	}
+	srv = s
+	srv.httpRouter.SaveMatchedRoutePath = true
+	err := srv.registerMocks()
+	if err != nil {
+		t.Fatalf("Unable to register mocks - %s", err)
+	}
+	ts := httptest.NewServer(srv.httpRouter)
+	t.Cleanup(func() {
+		ts.Close()
+		s.sessions.closeAll()
+	})
+	return ts
+}
+
+func TestWebSocketHandler(t *testing.T) {
+	m := mocks.Mocks{}
+	m.AddRoute("ws", mocks.Route{
+		Path: "/ws/:id",
+		WebSocket: &mocks.WebSocket{
+			OnConnect: []mocks.WebSocketMessage{
+				{Message: "hello {{ param.id }}"},
+			},
+			Replies: []mocks.WebSocketReply{
+				{Match: "^ping$", Messages: []mocks.WebSocketMessage{{Message: "pong", Delay: 10 * time.Millisecond}}},
+				{Match: "^bye$", Close: &mocks.WebSocketClose{Code: 4000, Reason: "later"}},
+				{Messages: []mocks.WebSocketMessage{{Message: "echo {{ body.name }}"}}},
+			},
+			Periodic: []mocks.WebSocketPeriodic{
+				{Interval: 50 * time.Millisecond, Message: "tick", Count: 1},
+			},
+		},
+	})
+	if err := m.Routes["ws"].Validate(); err != nil {
+		t.Fatalf("Unexpected error validating route - %s", err)
+	}
+
+	ts := newTestServer(t, m)
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/andre", nil)
+	if err != nil {
+		t.Fatalf("Unable to connect to WebSocket - %s", err)
+	}
+	defer conn.Close()
+
+	expect := func(t *testing.T, want string) {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Unexpected error reading message - %s", err)
+		}
+		if string(msg) != want {
+			t.Errorf("Unexpected message %q, expected %q", msg, want)
+		}
+	}
+
+	t.Run("On Connect", func(t *testing.T) {
+		expect(t, "hello andre")
+	})
+
+	t.Run("Periodic", func(t *testing.T) {
+		expect(t, "tick")
+	})
+
+	t.Run("Reply", func(t *testing.T) {
+		err := conn.WriteMessage(websocket.TextMessage, []byte("ping"))
+		if err != nil {
+			t.Fatalf("Unexpected error writing message - %s", err)
+		}
+		expect(t, "pong")
+	})
+
+	t.Run("Reply with variables", func(t *testing.T) {
+		err := conn.WriteMessage(websocket.TextMessage, []byte(`{"name": "Jim"}`))
+		if err != nil {
+			t.Fatalf("Unexpected error writing message - %s", err)
+		}
+		expect(t, "echo Jim")
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		err := conn.WriteMessage(websocket.TextMessage, []byte("bye"))
+		if err != nil {
+			t.Fatalf("Unexpected error writing message - %s", err)
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, _, err = conn.ReadMessage()
+		if !websocket.IsCloseError(err, 4000) {
+			t.Errorf("Expected close error with code 4000, got %s", err)
+		}
+	})
+}
+
+func TestWebSocketHandlerNoUpgrade(t *testing.T) {
+	m := mocks.Mocks{}
+	m.AddRoute("ws", mocks.Route{
+		Path:      "/ws",
+		WebSocket: &mocks.WebSocket{},
+	})
+	ts := newTestServer(t, m)
+	defer ts.Close()
+
+	r, err := ts.Client().Get(ts.URL + "/ws")
+	if err != nil {
+		t.Fatalf("Unexpected error when requesting mock URL - %s", err)
+	}
+	if r.StatusCode != 400 {
+		t.Errorf("Unexpected http status code - %d", r.StatusCode)
+	}
+}
@@ -0,0 +1,269 @@
+/*
+Package certs generates the TLS certificates used by MockItOut for testing. A CA is
+generated on start and issues leaf certificates for the configured hosts, clients
+trust the CA rather than skipping verification.
+
+Certificates are selected by the SNI hostname of each connection, falling back to a
+certificate including every host.
+
+	ca, err := certs.NewCA()
+	if err != nil {
+		// do something
+	}
+	cert, err := ca.Issue("localhost", "127.0.0.1")
+*/
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoHosts is returned when a certificate is issued without any hosts
+var ErrNoHosts = errors.New("at least one host is required")
+
+// Validity is the lifetime of generated certificates
+var Validity = 365 * 24 * time.Hour
+
+// CA is a certificate authority issuing leaf certificates. The zero value is not usable,
+// use NewCA.
+type CA struct {
+	// cert is the CA certificate.
+	cert *x509.Certificate
+
+	// key is the private key of the CA.
+	key *ecdsa.PrivateKey
+}
+
+// NewCA will generate a new ECDSA P-256 certificate authority.
+func NewCA() (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate CA key - %w", err)
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("MockItOut CA %08x", serial.Uint64()&0xffffffff), Organization: []string{"MockItOut"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(Validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("could not create CA certificate - %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse CA certificate - %w", err)
+	}
+	return &CA{cert: cert, key: key}, nil
+}
+
+// Certificate returns the CA certificate.
+func (c *CA) Certificate() *x509.Certificate {
+	return c.cert
+}
+
+// PEM returns the PEM encoded CA certificate.
+func (c *CA) PEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+}
+
+// Options customize issued certificates, the zero value issues ECDSA P-256 certificates
+// valid from now for the Validity.
+type Options struct {
+	// NotBefore is the start of the validity period, defaults to an hour ago.
+	NotBefore time.Time
+
+	// NotAfter is the end of the validity period, defaults to now plus the Validity.
+	NotAfter time.Time
+
+	// RSA issues an RSA 2048 key rather than ECDSA, for cipher suites requiring RSA.
+	RSA bool
+}
+
+// Issue will create a leaf certificate for the hosts, which may be DNS names, wildcard
+// names or IP addresses. The first host is used as the common name.
+func (c *CA) Issue(hosts ...string) (tls.Certificate, error) {
+	return c.IssueWithOptions(Options{}, hosts...)
+}
+
+// IssueWithOptions will create a leaf certificate for the hosts using the Options.
+func (c *CA) IssueWithOptions(o Options, hosts ...string) (tls.Certificate, error) {
+	return issue(c.cert, c.key, o, hosts)
+}
+
+// SelfSigned will create a self-signed leaf certificate for the hosts using the Options,
+// which is not trusted by any CA.
+func SelfSigned(o Options, hosts ...string) (tls.Certificate, error) {
+	return issue(nil, nil, o, hosts)
+}
+
+// issue will create a leaf certificate signed by the parent, or self-signed when the
+// parent is nil.
+func issue(parent *x509.Certificate, parentKey crypto.Signer, o Options, hosts []string) (tls.Certificate, error) {
+	if len(hosts) == 0 {
+		return tls.Certificate{}, ErrNoHosts
+	}
+	var key crypto.Signer
+	var err error
+	if o.RSA {
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	} else {
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("could not generate key - %w", err)
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: hosts[0], Organization: []string{"MockItOut"}},
+		NotBefore:    o.NotBefore,
+		NotAfter:     o.NotAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if tmpl.NotBefore.IsZero() {
+		tmpl.NotBefore = now.Add(-time.Hour)
+	}
+	if tmpl.NotAfter.IsZero() {
+		tmpl.NotAfter = now.Add(Validity)
+	}
+	if o.RSA {
+		// RSA key exchange cipher suites encrypt with the key
+		tmpl.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+			continue
+		}
+		tmpl.DNSNames = append(tmpl.DNSNames, h)
+	}
+
+	chain := [][]byte{}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	} else {
+		chain = append(chain, parent.Raw)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("could not create certificate - %w", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("could not parse certificate - %w", err)
+	}
+	return tls.Certificate{
+		Certificate: append([][]byte{der}, chain...),
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+// WriteFiles will write the PEM encoded certificate chain and private key to the files.
+func WriteFiles(cert tls.Certificate, certFile, keyFile string) error {
+	var chain []byte
+	for _, der := range cert.Certificate {
+		chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	err := os.WriteFile(certFile, chain, 0644)
+	if err != nil {
+		return fmt.Errorf("could not write certificate - %w", err)
+	}
+
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("could not encode private key - %w", err)
+	}
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0600)
+	if err != nil {
+		return fmt.Errorf("could not write private key - %w", err)
+	}
+	return nil
+}
+
+// SNI selects certificates by the SNI hostname of a TLS connection. The zero value is
+// not usable, use NewSNI.
+type SNI struct {
+	// mu protects names.
+	mu sync.RWMutex
+
+	// names is a map of lower case hostnames, including wildcard names, to their
+	// certificates.
+	names map[string]*tls.Certificate
+
+	// fallback is returned when no certificate matches the hostname.
+	fallback *tls.Certificate
+}
+
+// NewSNI will create an SNI returning the fallback certificate when no hostname matches.
+func NewSNI(fallback tls.Certificate) *SNI {
+	return &SNI{names: make(map[string]*tls.Certificate), fallback: &fallback}
+}
+
+// Add will select the certificate for the hostname, e.g. api.example.com or
+// *.example.com.
+func (s *SNI) Add(host string, cert tls.Certificate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.names[strings.ToLower(host)] = &cert
+}
+
+// GetCertificate returns the certificate of the hello's SNI hostname, matching exact
+// names before wildcards. It can be used as tls.Config.GetCertificate.
+func (s *SNI) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if cert, ok := s.names[name]; ok {
+		return cert, nil
+	}
+	if _, rest, ok := strings.Cut(name, "."); ok {
+		if cert, ok := s.names["*."+rest]; ok {
+			return cert, nil
+		}
+	}
+	return s.fallback, nil
+}
+
+// serialNumber returns a random certificate serial number.
+func serialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("could not generate serial number - %w", err)
+	}
+	return serial, nil
+}
@@ -0,0 +1,163 @@
+package certs
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIssue(t *testing.T) {
+	ca, err := NewCA()
+	if err != nil {
+		t.Fatalf("Unable to create CA - %s", err)
+	}
+	if !ca.Certificate().IsCA {
+		t.Fatalf("CA certificate is not a CA")
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ca.PEM()) {
+		t.Fatalf("Unable to parse CA PEM")
+	}
+
+	cert, err := ca.Issue("localhost", "*.example.com", "127.0.0.1", "::1")
+	if err != nil {
+		t.Fatalf("Unable to issue certificate - %s", err)
+	}
+	if cert.Leaf.Subject.CommonName != "localhost" {
+		t.Errorf("Unexpected common name %s", cert.Leaf.Subject.CommonName)
+	}
+
+	cases := map[string]bool{
+		"localhost":       true,
+		"api.example.com": true,
+		"127.0.0.1":       true,
+		"::1":             true,
+		"example.com":     false,
+		"other.test":      false,
+	}
+	for host, ok := range cases {
+		t.Run(host, func(t *testing.T) {
+			_, err := cert.Leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots})
+			if ok && err != nil {
+				t.Errorf("Unexpected error verifying certificate - %s", err)
+			}
+			if !ok && err == nil {
+				t.Errorf("Expected error verifying certificate, got nil")
+			}
+		})
+	}
+
+	t.Run("No Hosts", func(t *testing.T) {
+		_, err := ca.Issue()
+		if !errors.Is(err, ErrNoHosts) {
+			t.Errorf("Unexpected error %v", err)
+		}
+	})
+
+	t.Run("Write Files", func(t *testing.T) {
+		dir := t.TempDir()
+		certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+		err := WriteFiles(cert, certFile, keyFile)
+		if err != nil {
+			t.Fatalf("Unable to write files - %s", err)
+		}
+		c, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			t.Fatalf("Unable to load written files - %s", err)
+		}
+		if len(c.Certificate) != 2 {
+			t.Errorf("Expected certificate chain to include the CA, got %d certificates", len(c.Certificate))
+		}
+		info, err := os.Stat(keyFile)
+		if err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("Unexpected key file permissions %v", info.Mode())
+		}
+	})
+}
+
+func TestSNI(t *testing.T) {
+	ca, err := NewCA()
+	if err != nil {
+		t.Fatalf("Unable to create CA - %s", err)
+	}
+	issue := func(host string) tls.Certificate {
+		c, err := ca.Issue(host)
+		if err != nil {
+			t.Fatalf("Unable to issue certificate - %s", err)
+		}
+		return c
+	}
+
+	sni := NewSNI(issue("fallback"))
+	sni.Add("api.example.com", issue("api.example.com"))
+	sni.Add("*.example.com", issue("*.example.com"))
+	sni.Add("Localhost", issue("localhost"))
+
+	cases := map[string]string{
+		"api.example.com":  "api.example.com",
+		"API.example.com.": "api.example.com",
+		"web.example.com":  "*.example.com",
+		"localhost":        "localhost",
+		"a.b.example.com":  "fallback",
+		"example.com":      "fallback",
+		"":                 "fallback",
+	}
+	for name, cn := range cases {
+		t.Run(name, func(t *testing.T) {
+			c, err := sni.GetCertificate(&tls.ClientHelloInfo{ServerName: name})
+			if err != nil {
+				t.Fatalf("Unexpected error - %s", err)
+			}
+			if c.Leaf.Subject.CommonName != cn {
+				t.Errorf("Unexpected certificate %s, expected %s", c.Leaf.Subject.CommonName, cn)
+			}
+		})
+	}
+}
+
+func TestIssueWithOptions(t *testing.T) {
+	ca, err := NewCA()
+	if err != nil {
+		t.Fatalf("Unable to create CA - %s", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Certificate())
+	now := time.Now()
+
+	issue := func(c tls.Certificate, err error) tls.Certificate {
+		if err != nil {
+			t.Fatalf("Unable to issue certificate - %s", err)
+		}
+		return c
+	}
+	cases := map[string]struct {
+		cert tls.Certificate
+		rsa  bool
+		err  string
+	}{
+		"Expired":       {cert: issue(ca.IssueWithOptions(Options{NotBefore: now.Add(-48 * time.Hour), NotAfter: now.Add(-24 * time.Hour)}, "localhost")), err: "expired"},
+		"Not Yet Valid": {cert: issue(ca.IssueWithOptions(Options{NotBefore: now.Add(24 * time.Hour)}, "localhost")), err: "not yet valid"},
+		"RSA":           {cert: issue(ca.IssueWithOptions(Options{RSA: true}, "localhost")), rsa: true},
+		"Self Signed":   {cert: issue(SelfSigned(Options{}, "localhost")), err: "unknown authority"},
+	}
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			_, err := v.cert.Leaf.Verify(x509.VerifyOptions{DNSName: "localhost", Roots: roots})
+			if v.err == "" && err != nil {
+				t.Errorf("Unexpected error verifying certificate - %s", err)
+			}
+			if v.err != "" && (err == nil || !strings.Contains(err.Error(), v.err)) {
+				t.Errorf("Expected error %q verifying certificate, got %v", v.err, err)
+			}
+			if _, ok := v.cert.PrivateKey.(*rsa.PrivateKey); ok != v.rsa {
+				t.Errorf("Unexpected private key type %T", v.cert.PrivateKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Package clock provides the virtual clock used by the time based features of MockItOut,
+such as time variables and response delays.
+
+The clock follows the real time by default, it can be set to any time, frozen, resumed
+and advanced while the service is running. Setting the time jumps the clock without
+affecting pending delays, advancing the clock counts as time passing so delays waiting
+on the clock fire early.
+
+	clock.Default.Set(time.Date(2030, time.January, 1, 23, 59, 59, 0, time.UTC))
+	clock.Default.Freeze()
+	clock.Default.Advance(time.Second)
+*/
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a controllable clock. The zero value is not usable, use New.
+type Clock struct {
+	// mu protects the clock state.
+	mu sync.Mutex
+
+	// wall is the time of the clock at start.
+	wall time.Time
+
+	// elapsed is the time passed on the clock at start, delays are measured against
+	// the elapsed time so that setting the wall time does not affect them.
+	elapsed time.Duration
+
+	// start is the real time the clock was last changed.
+	start time.Time
+
+	// frozen stops the clock, time only passes when advanced.
+	frozen bool
+
+	// changed is closed and replaced every time the clock is changed, waking any
+	// pending timers.
+	changed chan struct{}
+}
+
+// State describes the current state of a Clock.
+type State struct {
+	// Now is the current time of the clock.
+	Now time.Time `json:"now"`
+
+	// Frozen is true if the clock is stopped.
+	Frozen bool `json:"frozen"`
+
+	// Offset is the difference between the clock and the real time.
+	Offset string `json:"offset"`
+}
+
+// New will create a Clock following the real time.
+func New() *Clock {
+	now := time.Now()
+	return &Clock{
+		wall:    now,
+		start:   now,
+		changed: make(chan struct{}),
+	}
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now()
+}
+
+// now returns the current time, the lock must be held.
+func (c *Clock) now() time.Time {
+	if c.frozen {
+		return c.wall
+	}
+	return c.wall.Add(time.Since(c.start))
+}
+
+// since returns the elapsed time of the clock, the lock must be held.
+func (c *Clock) since() time.Duration {
+	if c.frozen {
+		return c.elapsed
+	}
+	return c.elapsed + time.Since(c.start)
+}
+
+// update will apply fn to the clock, restarting it from the current real time and
+// waking any pending timers.
+func (c *Clock) update(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.wall, c.elapsed = c.now(), c.since()
+	c.start = time.Now()
+	fn()
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Set will jump the clock to t, the clock continues from t unless frozen.
+func (c *Clock) Set(t time.Time) {
+	c.update(func() {
+		c.wall = t
+	})
+}
+
+// Freeze will stop the clock at the current time.
+func (c *Clock) Freeze() {
+	c.update(func() {
+		c.frozen = true
+	})
+}
+
+// Resume will restart a frozen clock from its current time.
+func (c *Clock) Resume() {
+	c.update(func() {
+		c.frozen = false
+	})
+}
+
+// Advance will move the clock forward by d, pending timers count d as passed.
+func (c *Clock) Advance(d time.Duration) {
+	c.update(func() {
+		c.wall = c.wall.Add(d)
+		c.elapsed += d
+	})
+}
+
+// Reset will return the clock to the real time.
+func (c *Clock) Reset() {
+	c.update(func() {
+		c.wall = c.start
+		c.frozen = false
+	})
+}
+
+// State returns the current state of the clock.
+func (c *Clock) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	return State{
+		Now:    now,
+		Frozen: c.frozen,
+		Offset: now.Sub(time.Now()).Round(time.Second).String(),
+	}
+}
+
+// Timer fires once the clock has passed its duration, like time.Timer.
+type Timer struct {
+	// C receives the time of the clock when the timer fires.
+	C <-chan time.Time
+
+	// stop is closed when the timer is stopped.
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewTimer will create a Timer firing once d has passed on the clock.
+func (c *Clock) NewTimer(d time.Duration) *Timer {
+	ch := make(chan time.Time, 1)
+	t := &Timer{C: ch, stop: make(chan struct{})}
+
+	c.mu.Lock()
+	deadline := c.since() + d
+	c.mu.Unlock()
+
+	go c.wait(deadline, ch, t.stop)
+	return t
+}
+
+// Stop will prevent the timer from firing.
+func (t *Timer) Stop() {
+	t.once.Do(func() {
+		close(t.stop)
+	})
+}
+
+// wait will send the clock's time to ch once the elapsed time reaches deadline.
+func (c *Clock) wait(deadline time.Duration, ch chan<- time.Time, stop <-chan struct{}) {
+	for {
+		c.mu.Lock()
+		now, elapsed, frozen, changed := c.now(), c.since(), c.frozen, c.changed
+		c.mu.Unlock()
+
+		if elapsed >= deadline {
+			ch <- now
+			return
+		}
+
+		// a frozen clock only moves when changed
+		var fire <-chan time.Time
+		var real *time.Timer
+		if !frozen {
+			real = time.NewTimer(deadline - elapsed)
+			fire = real.C
+		}
+
+		select {
+		case <-stop:
+			if real != nil {
+				real.Stop()
+			}
+			return
+		case <-changed:
+		case <-fire:
+		}
+		if real != nil {
+			real.Stop()
+		}
+	}
+}
+
+// Default is the clock used by MockItOut.
+var Default = New()
+
+// Now returns the current time of the Default clock.
+func Now() time.Time {
+	return Default.Now()
+}
+
+// NewTimer will create a Timer on the Default clock.
+func NewTimer(d time.Duration) *Timer {
+	return Default.NewTimer(d)
+}
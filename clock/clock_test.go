@@ -0,0 +1,125 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	c := New()
+	target := time.Date(2030, time.January, 1, 23, 59, 59, 0, time.UTC)
+
+	t.Run("Follows Real Time", func(t *testing.T) {
+		if d := time.Since(c.Now()); d < 0 || d > time.Second {
+			t.Errorf("Unexpected clock time %s", c.Now())
+		}
+	})
+
+	t.Run("Set", func(t *testing.T) {
+		c.Set(target)
+		if d := c.Now().Sub(target); d < 0 || d > time.Second {
+			t.Errorf("Unexpected clock time %s", c.Now())
+		}
+	})
+
+	t.Run("Freeze", func(t *testing.T) {
+		c.Freeze()
+		c.Set(target)
+		time.Sleep(10 * time.Millisecond)
+		if !c.Now().Equal(target) {
+			t.Errorf("Unexpected clock time %s, expected %s", c.Now(), target)
+		}
+		if s := c.State(); !s.Frozen || !s.Now.Equal(target) {
+			t.Errorf("Unexpected clock state %+v", s)
+		}
+	})
+
+	t.Run("Advance", func(t *testing.T) {
+		c.Advance(time.Second)
+		if !c.Now().Equal(target.Add(time.Second)) {
+			t.Errorf("Unexpected clock time %s", c.Now())
+		}
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		c.Resume()
+		time.Sleep(10 * time.Millisecond)
+		if !c.Now().After(target.Add(time.Second)) {
+			t.Errorf("Unexpected clock time %s, expected the clock to be running", c.Now())
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		c.Reset()
+		if d := time.Since(c.Now()); d < 0 || d > time.Second {
+			t.Errorf("Unexpected clock time %s", c.Now())
+		}
+		if s := c.State(); s.Frozen || s.Offset != "0s" {
+			t.Errorf("Unexpected clock state %+v", s)
+		}
+	})
+}
+
+func TestTimer(t *testing.T) {
+	t.Run("Real Time", func(t *testing.T) {
+		c := New()
+		timer := c.NewTimer(10 * time.Millisecond)
+		select {
+		case <-timer.C:
+		case <-time.After(time.Second):
+			t.Errorf("Timer did not fire")
+		}
+	})
+
+	t.Run("Advance", func(t *testing.T) {
+		c := New()
+		timer := c.NewTimer(time.Hour)
+		c.Advance(time.Hour)
+		select {
+		case <-timer.C:
+		case <-time.After(time.Second):
+			t.Errorf("Timer did not fire once the clock advanced")
+		}
+	})
+
+	t.Run("Frozen", func(t *testing.T) {
+		c := New()
+		c.Freeze()
+		timer := c.NewTimer(10 * time.Millisecond)
+		select {
+		case <-timer.C:
+			t.Fatalf("Timer fired while the clock was frozen")
+		case <-time.After(50 * time.Millisecond):
+		}
+		c.Advance(10 * time.Millisecond)
+		select {
+		case <-timer.C:
+		case <-time.After(time.Second):
+			t.Errorf("Timer did not fire once the clock advanced")
+		}
+	})
+
+	t.Run("Set", func(t *testing.T) {
+		c := New()
+		c.Freeze()
+		timer := c.NewTimer(time.Hour)
+		c.Set(time.Now().Add(48 * time.Hour))
+		select {
+		case <-timer.C:
+			t.Errorf("Timer fired when the clock was set")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		c := New()
+		timer := c.NewTimer(10 * time.Millisecond)
+		timer.Stop()
+		timer.Stop()
+		select {
+		case <-timer.C:
+			t.Errorf("Timer fired after being stopped")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/jessevdk/go-flags"
 	"github.com/madflojo/mockitout/app"
 	"github.com/madflojo/mockitout/config"
+	"github.com/madflojo/mockitout/mocks"
+	"github.com/madflojo/mockitout/pact"
 	"github.com/sirupsen/logrus"
 	"os"
 )
@@ -11,7 +13,10 @@ import (
 // options is used to process command line arguments. These are minimal, in lieu of using
 // environment variables to control the server.
 type options struct {
-	Debug bool `long:"debug" description:"Enable debug logging"`
+	Debug        bool   `long:"debug" description:"Enable debug logging"`
+	ExportPact   string `long:"export-pact" description:"Generate a Pact contract from the mocks file at the given path and exit"`
+	PactConsumer string `long:"pact-consumer" default:"consumer" description:"Consumer name used when generating a Pact contract"`
+	PactProvider string `long:"pact-provider" default:"mockitout" description:"Provider name used when generating a Pact contract"`
 }
 
 func main() {
@@ -36,6 +41,20 @@ func main() {
 		env.Debug = opts.Debug
 	}
 
+	// Generate Pact contract from mocks file
+	if opts.ExportPact != "" {
+		m, err := mocks.FromFile(env.MocksFile)
+		if err != nil {
+			log.Fatalf("Unable to load mocks file - %s", err)
+		}
+		err = pact.FromMocks(m, opts.PactConsumer, opts.PactProvider).WriteFile(opts.ExportPact)
+		if err != nil {
+			log.Fatalf("Unable to generate Pact contract - %s", err)
+		}
+		log.Infof("Pact contract written to %s", opts.ExportPact)
+		return
+	}
+
 	// Run application
 	err = app.Run(env)
 	if err != nil || err != app.ErrShutdown {
@@ -7,6 +7,8 @@ package config
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/caarlos0/env/v6"
 )
 
@@ -24,6 +26,18 @@ type Config struct {
 	// ListenAddr specifies the HTTP Listener address used for this service.
 	ListenAddr string `env:"LISTEN_ADDR" envDefault:"0.0.0.0:8443"`
 
+	// DisableHTTP2 will turn off HTTP/2 negotiation (ALPN) for TLS listeners, limiting
+	// clients to HTTP/1.1.
+	DisableHTTP2 bool `env:"DISABLE_HTTP2" envDefault:"false"`
+
+	// EnableH2C specifies if HTTP/2 over cleartext (h2c) is enabled. This is used only
+	// if TLS is Disabled.
+	EnableH2C bool `env:"ENABLE_H2C" envDefault:"false"`
+
+	// HTTP3ListenAddr specifies the UDP Listener address used for HTTP/3. When empty
+	// HTTP/3 is disabled. This requires TLS to be Enabled.
+	HTTP3ListenAddr string `env:"HTTP3_LISTEN_ADDR"`
+
 	// CertFile specifies the location of the TLS certificate file. This is used only
 	// if TLS is Enabled.
 	CertFile string `env:"CERT_FILE"`
@@ -32,22 +46,111 @@ type Config struct {
 	// is Enabled.
 	KeyFile string `env:"KEY_FILE"`
 
+	// ClientCAFile specifies the location of PEM encoded CA certificates used to verify
+	// client certificates. This is used only if TLS is Enabled.
+	ClientCAFile string `env:"CLIENT_CA_FILE"`
+
+	// ClientAuth specifies the TLS client certificate policy, one of none, request,
+	// require or verify. When empty the policy is verify if a ClientCAFile is set,
+	// otherwise none.
+	ClientAuth string `env:"CLIENT_AUTH"`
+
 	// GenCerts specifies if the service should generate a test Key and Cert to use
 	// with TLS.
 	GenCerts bool `env:"GEN_CERTS" envDefault:"false"`
 
+	// CertHosts specifies the hostnames and IP addresses of generated certificates. A
+	// certificate is issued for each host and selected by SNI.
+	CertHosts []string `env:"CERT_HOSTS" envSeparator:"," envDefault:"localhost,127.0.0.1,::1"`
+
+	// CAFile specifies a location the PEM encoded CA of generated certificates is
+	// written to, allowing clients to trust it. This is used only if GenCerts is
+	// enabled.
+	CAFile string `env:"CA_FILE"`
+
+	// TLSProfiles specifies a list of misbehaving TLS listeners as name=address, e.g.
+	// expired=0.0.0.0:9443. Profiles are expired, not_yet_valid, wrong_host,
+	// self_signed, untrusted, tls10, tls11 and cipher:<suite>, suites are separated by +.
+	// This requires GenCerts to be enabled.
+	TLSProfiles []string `env:"TLS_PROFILES" envSeparator:","`
+
+	// CAPath specifies the HTTP path serving the PEM encoded CA of generated
+	// certificates, e.g. /_mockitout/ca.pem. When empty the end-point is disabled.
+	CAPath string `env:"CA_PATH"`
+
 	// MocksFile specifies the full path to the mocks configuration file. This value
-	// must be set or the service will not start.
+	// must be set or the service will not start, unless PactFiles are provided.
 	MocksFile string `env:"MOCKS_FILE"`
+
+	// RandomSeed specifies the seed used for random variables, making responses
+	// reproducible. When zero random values differ on every request.
+	RandomSeed uint64 `env:"RANDOM_SEED"`
+
+	// StrictVariables specifies if requests should fail with a diagnostic 500 when
+	// response variables cannot be resolved. Routes can override this setting.
+	StrictVariables bool `env:"STRICT_VARIABLES" envDefault:"false"`
+
+	// MaxBodySize specifies the maximum number of bytes of the request body read for
+	// body variables. Larger bodies leave body variables unresolved.
+	MaxBodySize int64 `env:"MAX_BODY_SIZE" envDefault:"10485760"`
+
+	// ClockPath specifies the HTTP path of the virtual clock control endpoint, e.g.
+	// /_mockitout/clock. When empty the endpoint is disabled.
+	ClockPath string `env:"CLOCK_PATH"`
+
+	// ClockTime specifies an RFC 3339 time the virtual clock starts from. When empty
+	// the clock follows the real time.
+	ClockTime string `env:"CLOCK_TIME"`
+
+	// ClockFrozen specifies if the virtual clock starts frozen.
+	ClockFrozen bool `env:"CLOCK_FROZEN" envDefault:"false"`
+
+	// JWTKeyFile specifies the location of a PEM encoded private key used to sign
+	// tokens created by jwt variables. When empty a key is generated on start.
+	JWTKeyFile string `env:"JWT_KEY_FILE"`
+
+	// JWTIssuer specifies the default iss claim of tokens created by jwt variables.
+	JWTIssuer string `env:"JWT_ISSUER" envDefault:"mockitout"`
+
+	// JWTExpiry specifies the default lifetime of tokens created by jwt variables.
+	JWTExpiry time.Duration `env:"JWT_EXPIRY" envDefault:"1h"`
+
+	// JWKSPath specifies the HTTP path publishing the public key of the token signing
+	// key as a JSON Web Key Set, e.g. /.well-known/jwks.json. When empty the end-point
+	// is disabled.
+	JWKSPath string `env:"JWKS_PATH"`
+
+	// PactFiles specifies a list of Pact contract files to load. Each HTTP interaction
+	// within the contracts is served as a mocked route.
+	PactFiles []string `env:"PACT_FILES" envSeparator:","`
+
+	// GRPCListenAddr specifies the gRPC Listener address used for mocked gRPC methods.
+	// When empty the gRPC listener is disabled.
+	GRPCListenAddr string `env:"GRPC_LISTEN_ADDR"`
+
+	// ProtoFiles specifies a list of .proto files describing the mocked gRPC services.
+	ProtoFiles []string `env:"PROTO_FILES" envSeparator:","`
+
+	// ProtoImportPaths specifies a list of directories used to resolve ProtoFiles and
+	// their imports.
+	ProtoImportPaths []string `env:"PROTO_IMPORT_PATHS" envSeparator:","`
+
+	// ProtoDescriptorSet specifies the location of a compiled FileDescriptorSet
+	// describing the mocked gRPC services. This can be used instead of ProtoFiles.
+	ProtoDescriptorSet string `env:"PROTO_DESCRIPTOR_SET"`
 }
 
 // New will create a new Config instance with strong defaults.
 func New() Config {
 	c := Config{
-		ListenAddr: "0.0.0.0:8443",
-		EnableTLS:  true,
-		Debug:      false,
-		GenCerts:   true,
+		ListenAddr:  "0.0.0.0:8443",
+		EnableTLS:   true,
+		Debug:       false,
+		GenCerts:    true,
+		CertHosts:   []string{"localhost", "127.0.0.1", "::1"},
+		MaxBodySize: 10 << 20,
+		JWTIssuer:   "mockitout",
+		JWTExpiry:   time.Hour,
 	}
 	return c
 }
@@ -23,6 +23,22 @@ func TestConfigDefaults(t *testing.T) {
 	if cfg.GenCerts != true {
 		t.Errorf("Unexpected value for GenCerts - %t", cfg.GenCerts)
 	}
+
+	if cfg.MaxBodySize != 10<<20 {
+		t.Errorf("Unexpected value for MaxBodySize - %d", cfg.MaxBodySize)
+	}
+
+	if cfg.ClockPath != "" {
+		t.Errorf("Unexpected value for ClockPath - %s", cfg.ClockPath)
+	}
+
+	if cfg.JWKSPath != "" {
+		t.Errorf("Unexpected value for JWKSPath - %s", cfg.JWKSPath)
+	}
+
+	if cfg.CAPath != "" || len(cfg.CertHosts) != 3 {
+		t.Errorf("Unexpected value for CAPath or CertHosts - %s %v", cfg.CAPath, cfg.CertHosts)
+	}
 }
 
 func TestConfigFromEnv(t *testing.T) {
@@ -0,0 +1,130 @@
+/*
+Package descriptors is used to load Protocol Buffer descriptors from .proto source
+files or compiled descriptor sets. These descriptors are used to serve mocked gRPC
+methods without generated code.
+*/
+package descriptors
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Registry holds loaded Protocol Buffer file descriptors.
+type Registry struct {
+	// files is the collection of all loaded file descriptors, including imports.
+	files *protoregistry.Files
+}
+
+// FromProtoFiles will compile the provided .proto files and return a Registry of the
+// resulting descriptors. Import paths are used to resolve the files and their imports,
+// well-known types are always available.
+func FromProtoFiles(files []string, importPaths []string) (*Registry, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: importPaths,
+		}),
+	}
+
+	compiled, err := compiler.Compile(context.Background(), files...)
+	if err != nil {
+		return nil, fmt.Errorf("could not compile proto files - %s", err)
+	}
+
+	r := &Registry{files: new(protoregistry.Files)}
+	for _, fd := range compiled {
+		err := r.register(fd)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// FromDescriptorSet will read a compiled FileDescriptorSet, such as those created by
+// protoc --descriptor_set_out with --include_imports, and return a Registry.
+func FromDescriptorSet(filepath string) (*Registry, error) {
+	c, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read descriptor set at %s - %s", filepath, err)
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	err = proto.Unmarshal(c, set)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse descriptor set - %s", err)
+	}
+
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("could not load descriptor set - %s", err)
+	}
+	return &Registry{files: files}, nil
+}
+
+// register will add the file descriptor and its imports to the registry.
+func (r *Registry) register(fd protoreflect.FileDescriptor) error {
+	if _, err := r.files.FindFileByPath(fd.Path()); err == nil {
+		return nil
+	}
+
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		err := r.register(imports.Get(i).FileDescriptor)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := r.files.RegisterFile(fd)
+	if err != nil {
+		return fmt.Errorf("could not register proto file %s - %s", fd.Path(), err)
+	}
+	return nil
+}
+
+// Method will find the method descriptor for the full method name. Names may be in
+// the gRPC wire format /package.Service/Method or package.Service/Method.
+func (r *Registry) Method(name string) (protoreflect.MethodDescriptor, error) {
+	svc, method, ok := strings.Cut(strings.TrimPrefix(name, "/"), "/")
+	if !ok || svc == "" || method == "" {
+		return nil, fmt.Errorf("invalid method name %s, expected package.Service/Method", name)
+	}
+
+	d, err := r.files.FindDescriptorByName(protoreflect.FullName(svc))
+	if err != nil {
+		return nil, fmt.Errorf("could not find service %s - %s", svc, err)
+	}
+
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", svc)
+	}
+
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil, fmt.Errorf("could not find method %s within service %s", method, svc)
+	}
+	return md, nil
+}
+
+// Services will return the full names of all services within the registry.
+func (r *Registry) Services() []string {
+	var services []string
+	r.files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		for i := 0; i < fd.Services().Len(); i++ {
+			services = append(services, string(fd.Services().Get(i).FullName()))
+		}
+		return true
+	})
+	return services
+}
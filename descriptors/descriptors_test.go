@@ -0,0 +1,154 @@
+package descriptors
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const testProto = `
+syntax = "proto3";
+package users.v1;
+
+import "google/protobuf/timestamp.proto";
+import "common.proto";
+
+message GetUserRequest {
+  string id = 1;
+}
+
+message User {
+  string id = 1;
+  string name = 2;
+  google.protobuf.Timestamp created = 3;
+  common.v1.Status status = 4;
+}
+
+service UserService {
+  rpc GetUser(GetUserRequest) returns (User);
+  rpc WatchUsers(GetUserRequest) returns (stream User);
+}
+`
+
+const commonProto = `
+syntax = "proto3";
+package common.v1;
+
+enum Status {
+  UNKNOWN = 0;
+  ACTIVE = 1;
+}
+`
+
+// writeProtos will write the test proto files to a temporary directory.
+func writeProtos(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "protos")
+	if err != nil {
+		t.Fatalf("Error creating temp dir - %s", err)
+	}
+	for k, v := range map[string]string{"users.proto": testProto, "common.proto": commonProto} {
+		err := ioutil.WriteFile(filepath.Join(dir, k), []byte(v), 0644)
+		if err != nil {
+			t.Fatalf("Error writing proto file - %s", err)
+		}
+	}
+	return dir
+}
+
+func TestFromProtoFiles(t *testing.T) {
+	dir := writeProtos(t)
+	defer os.RemoveAll(dir)
+
+	r, err := FromProtoFiles([]string{"users.proto"}, []string{dir})
+	if err != nil {
+		t.Fatalf("Unexpected error loading proto files - %s", err)
+	}
+
+	methods := map[string]bool{
+		"users.v1.UserService/GetUser":     true,
+		"/users.v1.UserService/WatchUsers": true,
+		"users.v1.UserService/Missing":     false,
+		"users.v1.Missing/GetUser":         false,
+		"users.v1.User/GetUser":            false,
+		"GetUser":                          false,
+	}
+	for k, v := range methods {
+		t.Run("Method "+k, func(t *testing.T) {
+			md, err := r.Method(k)
+			if v && (err != nil || md == nil) {
+				t.Errorf("Unexpected error finding method - %s", err)
+			}
+			if !v && err == nil {
+				t.Errorf("Expected error finding method")
+			}
+		})
+	}
+
+	if s := r.Services(); len(s) != 1 || s[0] != "users.v1.UserService" {
+		t.Errorf("Unexpected services - %v", s)
+	}
+
+	t.Run("Missing import path", func(t *testing.T) {
+		_, err := FromProtoFiles([]string{"users.proto"}, nil)
+		if err == nil {
+			t.Errorf("Expected error compiling proto without import paths")
+		}
+	})
+}
+
+func TestFromDescriptorSet(t *testing.T) {
+	dir := writeProtos(t)
+	defer os.RemoveAll(dir)
+
+	r, err := FromProtoFiles([]string{"users.proto"}, []string{dir})
+	if err != nil {
+		t.Fatalf("Unexpected error loading proto files - %s", err)
+	}
+
+	// Create a descriptor set from the compiled files
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, p := range []string{"google/protobuf/timestamp.proto", "common.proto", "users.proto"} {
+		fd, err := r.files.FindFileByPath(p)
+		if err != nil {
+			t.Fatalf("Could not find file %s - %s", p, err)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	data, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatalf("Could not encode descriptor set - %s", err)
+	}
+	f := filepath.Join(dir, "users.pb")
+	err = ioutil.WriteFile(f, data, 0644)
+	if err != nil {
+		t.Fatalf("Could not write descriptor set - %s", err)
+	}
+
+	ds, err := FromDescriptorSet(f)
+	if err != nil {
+		t.Fatalf("Unexpected error loading descriptor set - %s", err)
+	}
+	_, err = ds.Method("users.v1.UserService/GetUser")
+	if err != nil {
+		t.Errorf("Unexpected error finding method - %s", err)
+	}
+
+	t.Run("Invalid descriptor set", func(t *testing.T) {
+		_, err := FromDescriptorSet(filepath.Join(dir, "users.proto"))
+		if err == nil {
+			t.Errorf("Expected error loading invalid descriptor set")
+		}
+	})
+
+	t.Run("Missing descriptor set", func(t *testing.T) {
+		_, err := FromDescriptorSet(filepath.Join(dir, "missing.pb"))
+		if err == nil {
+			t.Errorf("Expected error loading missing descriptor set")
+		}
+	})
+}
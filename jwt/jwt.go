@@ -0,0 +1,300 @@
+/*
+Package jwt signs JSON Web Tokens for MockItOut and publishes the public key as a JSON
+Web Key Set, allowing services validating tokens to be tested against mocked identity
+providers.
+
+Keys are either generated on start or loaded from a PEM file. RSA keys sign with RS256,
+ECDSA P-256 and P-384 keys with ES256 and ES384.
+
+	s, err := jwt.Generate()
+	if err != nil {
+		// do something
+	}
+	token, err := s.Sign(map[string]interface{}{"sub": "user"})
+*/
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+)
+
+var (
+	// ErrUnsupportedKey is returned when a key is not an RSA or ECDSA P-256/P-384 private key
+	ErrUnsupportedKey = errors.New("unsupported key type, expected an RSA or ECDSA P-256/P-384 private key")
+
+	// ErrInvalidPEM is returned when a key file does not contain a PEM encoded private key
+	ErrInvalidPEM = errors.New("no PEM encoded private key found")
+
+	// ErrInvalidToken is returned when a token is malformed or its signature is invalid
+	ErrInvalidToken = errors.New("invalid token")
+
+	// ErrExpiredToken is returned when a token has expired or is not yet valid
+	ErrExpiredToken = errors.New("token is expired or not yet valid")
+)
+
+// Signer signs tokens with a private key.
+type Signer struct {
+	// key is the private key tokens are signed with.
+	key crypto.Signer
+
+	// alg is the JWS algorithm of the key, e.g. RS256.
+	alg string
+
+	// hash is the digest used by the algorithm.
+	hash crypto.Hash
+
+	// kid is the key ID, the RFC 7638 thumbprint of the public key.
+	kid string
+}
+
+// Key is a public JSON Web Key.
+type Key struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// KeySet is a JSON Web Key Set.
+type KeySet struct {
+	Keys []Key `json:"keys"`
+}
+
+// Default is the Signer used by MockItOut, it is nil until configured.
+var Default *Signer
+
+// New will create a Signer using the private key.
+func New(key crypto.PrivateKey) (*Signer, error) {
+	k, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+	alg, hash, err := algorithm(k.Public())
+	if err != nil {
+		return nil, err
+	}
+	s := &Signer{key: k, alg: alg, hash: hash}
+
+	thumbprint, err := s.thumbprint()
+	if err != nil {
+		return nil, err
+	}
+	s.kid = thumbprint
+	return s, nil
+}
+
+// algorithm returns the JWS algorithm and digest used with the public key.
+func algorithm(key crypto.PublicKey) (string, crypto.Hash, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return "RS256", crypto.SHA256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return "ES256", crypto.SHA256, nil
+		case elliptic.P384():
+			return "ES384", crypto.SHA384, nil
+		}
+	}
+	return "", 0, ErrUnsupportedKey
+}
+
+// Generate will create a Signer with a new RSA 2048 key.
+func Generate() (*Signer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate key - %s", err)
+	}
+	return New(key)
+}
+
+// FromFile will create a Signer using the PEM encoded private key within the file.
+// PKCS #1, PKCS #8 and SEC 1 keys are supported.
+func FromFile(path string) (*Signer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read key file - %s", err)
+	}
+	return FromPEM(b)
+}
+
+// FromPEM will create a Signer using the first PEM encoded private key within data.
+func FromPEM(data []byte) (*Signer, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, ErrInvalidPEM
+		}
+
+		var key crypto.PrivateKey
+		var err error
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not parse private key - %s", err)
+		}
+		return New(key)
+	}
+}
+
+// Algorithm returns the JWS algorithm used to sign tokens.
+func (s *Signer) Algorithm() string {
+	return s.alg
+}
+
+// KeyID returns the ID of the key, included within the header of signed tokens.
+func (s *Signer) KeyID() string {
+	return s.kid
+}
+
+// Public returns the public key of the Signer.
+func (s *Signer) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+// Sign will create a signed token holding the claims.
+func (s *Signer) Sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": s.alg, "typ": "JWT", "kid": s.kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("could not encode claims - %s", err)
+	}
+
+	signed := encode(header) + "." + encode(payload)
+	h := s.hash.New()
+	h.Write([]byte(signed))
+	sig, err := s.key.Sign(rand.Reader, h.Sum(nil), s.hash)
+	if err != nil {
+		return "", fmt.Errorf("could not sign token - %s", err)
+	}
+
+	// ECDSA signatures are the fixed size r and s values rather than ASN.1
+	if k, ok := s.key.(*ecdsa.PrivateKey); ok {
+		sig, err = rawSignature(sig, (k.Curve.Params().BitSize+7)/8)
+		if err != nil {
+			return "", err
+		}
+	}
+	return signed + "." + encode(sig), nil
+}
+
+// Verify will check the signature of a token signed by the Signer and return its claims.
+// The exp and nbf claims, when present, are checked against now.
+func (s *Signer) Verify(token string, now time.Time) (map[string]interface{}, error) {
+	return s.Verifier().Verify(token, now)
+}
+
+// Verifier returns a Verifier for tokens signed by the Signer.
+func (s *Signer) Verifier() *Verifier {
+	return &Verifier{key: s.key.Public(), alg: s.alg, hash: s.hash, kid: s.kid}
+}
+
+// JWK returns the public JSON Web Key of the Signer.
+func (s *Signer) JWK() Key {
+	k := Key{Use: "sig", Alg: s.alg, Kid: s.kid}
+	switch pub := s.key.Public().(type) {
+	case *rsa.PublicKey:
+		k.Kty = "RSA"
+		k.N = encode(pub.N.Bytes())
+		k.E = encode(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		k.Kty = "EC"
+		k.Crv = pub.Curve.Params().Name
+		k.X = encode(pub.X.FillBytes(make([]byte, size)))
+		k.Y = encode(pub.Y.FillBytes(make([]byte, size)))
+	}
+	return k
+}
+
+// JWKS returns the JSON Web Key Set publishing the public key of the Signer.
+func (s *Signer) JWKS() KeySet {
+	return KeySet{Keys: []Key{s.JWK()}}
+}
+
+// thumbprint returns the RFC 7638 thumbprint of the public key.
+func (s *Signer) thumbprint() (string, error) {
+	k := s.JWK()
+	var members interface{}
+	if k.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{k.E, k.Kty, k.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{k.Crv, k.Kty, k.X, k.Y}
+	}
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return encode(sum[:]), nil
+}
+
+// rawSignature converts an ASN.1 ECDSA signature to the r and s values padded to size.
+func rawSignature(sig []byte, size int) ([]byte, error) {
+	var v struct {
+		R, S *big.Int
+	}
+	_, err := asn1.Unmarshal(sig, &v)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode signature - %s", err)
+	}
+	raw := make([]byte, 2*size)
+	v.R.FillBytes(raw[:size])
+	v.S.FillBytes(raw[size:])
+	return raw, nil
+}
+
+// decodeJSON decodes the unpadded base64url JSON data into v.
+func decodeJSON(data string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// encode returns data as unpadded base64url.
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -0,0 +1,224 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// verify checks the token signature using the published JWK and returns the claims.
+func verify(t *testing.T, token string, k Key) map[string]interface{} {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("Unexpected token format %s", token)
+	}
+
+	var header map[string]string
+	b, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	if err := json.Unmarshal(b, &header); err != nil {
+		t.Fatalf("Unable to parse token header - %s", err)
+	}
+	if header["alg"] != k.Alg || header["kid"] != k.Kid || header["typ"] != "JWT" {
+		t.Errorf("Unexpected token header %+v", header)
+	}
+
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	decode := func(s string) *big.Int {
+		b, _ := base64.RawURLEncoding.DecodeString(s)
+		return new(big.Int).SetBytes(b)
+	}
+	switch k.Kty {
+	case "RSA":
+		pub := &rsa.PublicKey{N: decode(k.N), E: int(decode(k.E).Int64())}
+		h := crypto.SHA256.New()
+		h.Write([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, h.Sum(nil), sig); err != nil {
+			t.Errorf("Invalid token signature - %s", err)
+		}
+	case "EC":
+		curve, hash := elliptic.P256(), crypto.SHA256
+		if k.Crv == "P-384" {
+			curve, hash = elliptic.P384(), crypto.SHA384
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: decode(k.X), Y: decode(k.Y)}
+		h := hash.New()
+		h.Write([]byte(parts[0] + "." + parts[1]))
+		size := len(sig) / 2
+		if !ecdsa.Verify(pub, h.Sum(nil), new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])) {
+			t.Errorf("Invalid token signature")
+		}
+	}
+
+	var claims map[string]interface{}
+	b, _ = base64.RawURLEncoding.DecodeString(parts[1])
+	if err := json.Unmarshal(b, &claims); err != nil {
+		t.Fatalf("Unable to parse token claims - %s", err)
+	}
+	return claims
+}
+
+func TestSigner(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+
+	cases := map[string]struct {
+		key crypto.PrivateKey
+		alg string
+		kty string
+	}{
+		"RSA":   {key: rsaKey, alg: "RS256", kty: "RSA"},
+		"P-256": {key: p256, alg: "ES256", kty: "EC"},
+		"P-384": {key: p384, alg: "ES384", kty: "EC"},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			s, err := New(v.key)
+			if err != nil {
+				t.Fatalf("Unexpected error creating signer - %s", err)
+			}
+			if s.Algorithm() != v.alg {
+				t.Errorf("Unexpected algorithm %s", s.Algorithm())
+			}
+
+			set := s.JWKS()
+			if len(set.Keys) != 1 || set.Keys[0].Kty != v.kty || set.Keys[0].Kid != s.KeyID() || set.Keys[0].Use != "sig" {
+				t.Fatalf("Unexpected key set %+v", set)
+			}
+
+			token, err := s.Sign(map[string]interface{}{"sub": "user", "admin": true})
+			if err != nil {
+				t.Fatalf("Unexpected error signing token - %s", err)
+			}
+			claims := verify(t, token, set.Keys[0])
+			if claims["sub"] != "user" || claims["admin"] != true {
+				t.Errorf("Unexpected claims %+v", claims)
+			}
+		})
+	}
+
+	t.Run("Verify", func(t *testing.T) {
+		s, _ := New(p256)
+		other, _ := New(rsaKey)
+		now := time.Now()
+		token, _ := s.Sign(map[string]interface{}{"sub": "user", "exp": now.Add(time.Hour).Unix(), "nbf": now.Add(-time.Minute).Unix()})
+
+		claims, err := s.Verify(token, now)
+		if err != nil || claims["sub"] != "user" {
+			t.Errorf("Unexpected verify result %+v - %v", claims, err)
+		}
+
+		cases := map[string]struct {
+			token string
+			now   time.Time
+			err   error
+		}{
+			"Expired":       {token: token, now: now.Add(2 * time.Hour), err: ErrExpiredToken},
+			"Not Yet Valid": {token: token, now: now.Add(-time.Hour), err: ErrExpiredToken},
+			"Tampered":      {token: token[:len(token)-4] + "AAAA", now: now, err: ErrInvalidToken},
+			"Malformed":     {token: "abc.def", now: now, err: ErrInvalidToken},
+		}
+		for k, v := range cases {
+			t.Run(k, func(t *testing.T) {
+				_, err := s.Verify(v.token, v.now)
+				if err != v.err {
+					t.Errorf("Unexpected error %v, expected %v", err, v.err)
+				}
+			})
+		}
+
+		t.Run("Other Key", func(t *testing.T) {
+			token, _ := other.Sign(map[string]interface{}{"sub": "user"})
+			_, err := s.Verify(token, now)
+			if err != ErrInvalidToken {
+				t.Errorf("Unexpected error %v", err)
+			}
+		})
+	})
+
+	t.Run("Unsupported Key", func(t *testing.T) {
+		p224, _ := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+		_, err := New(p224)
+		if err != ErrUnsupportedKey {
+			t.Errorf("Unexpected error for unsupported key - %v", err)
+		}
+	})
+
+	t.Run("Thumbprint", func(t *testing.T) {
+		// RFC 7638 section 3.1 example key
+		n, _ := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+		s, err := New(&rsa.PrivateKey{PublicKey: rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}})
+		if err != nil {
+			t.Fatalf("Unexpected error creating signer - %s", err)
+		}
+		if s.KeyID() != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+			t.Errorf("Unexpected key ID %s", s.KeyID())
+		}
+	})
+}
+
+func TestFromFile(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+	sec1, _ := x509.MarshalECPrivateKey(ecKey)
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("not a key")})
+
+	cases := map[string]struct {
+		data []byte
+		alg  string
+		err  bool
+	}{
+		"PKCS1":      {data: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), alg: "RS256"},
+		"PKCS8":      {data: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), alg: "ES256"},
+		"SEC1":       {data: append(cert, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1})...), alg: "ES256"},
+		"No Key":     {data: cert, err: true},
+		"Invalid":    {data: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("bad")}), err: true},
+		"Empty File": {err: true},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			f, err := os.CreateTemp("", "key")
+			if err != nil {
+				t.Fatalf("Unable to create key file - %s", err)
+			}
+			defer os.Remove(f.Name())
+			_, _ = f.Write(v.data)
+			f.Close()
+
+			s, err := FromFile(f.Name())
+			if v.err {
+				if err == nil {
+					t.Errorf("Expected error loading key")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error loading key - %s", err)
+			}
+			if s.Algorithm() != v.alg {
+				t.Errorf("Unexpected algorithm %s", s.Algorithm())
+			}
+		})
+	}
+
+	t.Run("Missing File", func(t *testing.T) {
+		_, err := FromFile("/doesnotexist")
+		if err == nil {
+			t.Errorf("Expected error loading missing key file")
+		}
+	})
+}
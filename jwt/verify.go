@@ -0,0 +1,135 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// Verifier checks the signature of tokens with a public key.
+type Verifier struct {
+	// key is the public key tokens are verified with.
+	key crypto.PublicKey
+
+	// alg is the JWS algorithm tokens must be signed with.
+	alg string
+
+	// hash is the digest used by the algorithm.
+	hash crypto.Hash
+
+	// kid is the key ID, when set tokens with a different kid are rejected.
+	kid string
+}
+
+// NewVerifier will create a Verifier using the public key.
+func NewVerifier(key crypto.PublicKey) (*Verifier, error) {
+	alg, hash, err := algorithm(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{key: key, alg: alg, hash: hash}, nil
+}
+
+// VerifierFromFile will create a Verifier using the first PEM encoded public key,
+// certificate or private key within the file.
+func VerifierFromFile(path string) (*Verifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read key file - %s", err)
+	}
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, ErrInvalidPEM
+		}
+
+		var key crypto.PublicKey
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			cert, err = x509.ParseCertificate(block.Bytes)
+			if err == nil {
+				key = cert.PublicKey
+			}
+		case "RSA PRIVATE KEY", "EC PRIVATE KEY", "PRIVATE KEY":
+			var s *Signer
+			s, err = FromPEM(pem.EncodeToMemory(block))
+			if err == nil {
+				return s.Verifier(), nil
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not parse public key - %s", err)
+		}
+		return NewVerifier(key)
+	}
+}
+
+// Verify will check the signature of the token and return its claims. The exp and nbf
+// claims, when present, are checked against now.
+func (v *Verifier) Verify(token string, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	err := decodeJSON(parts[0], &header)
+	if err != nil || header.Alg != v.alg || (v.kid != "" && header.Kid != "" && header.Kid != v.kid) {
+		return nil, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	h := v.hash.New()
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	switch pub := v.key.(type) {
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(pub, v.hash, h.Sum(nil), sig) != nil {
+			return nil, ErrInvalidToken
+		}
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return nil, ErrInvalidToken
+		}
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, h.Sum(nil), r, s) {
+			return nil, ErrInvalidToken
+		}
+	}
+
+	var claims map[string]interface{}
+	err = decodeJSON(parts[1], &claims)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if exp, ok := claims["exp"].(float64); ok && now.Unix() >= int64(exp) {
+		return claims, ErrExpiredToken
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Unix() < int64(nbf) {
+		return claims, ErrExpiredToken
+	}
+	return claims, nil
+}
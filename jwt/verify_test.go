@@ -0,0 +1,75 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestVerifierFromFile(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	spki, _ := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "signer"}, NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	cert, _ := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &rsaKey.PublicKey, rsaKey)
+	rsaSigner, _ := New(rsaKey)
+	ecSigner, _ := New(ecKey)
+
+	cases := map[string]struct {
+		data   []byte
+		signer *Signer
+		err    bool
+	}{
+		"Public Key":       {data: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: spki}), signer: ecSigner},
+		"RSA Public Key":   {data: pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)}), signer: rsaSigner},
+		"Certificate":      {data: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), signer: rsaSigner},
+		"Private Key":      {data: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), signer: rsaSigner},
+		"Invalid Key":      {data: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("bad")}), err: true},
+		"No Key":           {data: []byte("not pem"), err: true},
+		"Unsupported Type": {data: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: []byte("csr")}), err: true},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			f, err := os.CreateTemp("", "key")
+			if err != nil {
+				t.Fatalf("Unable to create key file - %s", err)
+			}
+			defer os.Remove(f.Name())
+			_, _ = f.Write(v.data)
+			f.Close()
+
+			verifier, err := VerifierFromFile(f.Name())
+			if v.err {
+				if err == nil {
+					t.Errorf("Expected error loading key")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error loading key - %s", err)
+			}
+
+			token, _ := v.signer.Sign(map[string]interface{}{"sub": "user"})
+			claims, err := verifier.Verify(token, time.Now())
+			if err != nil || claims["sub"] != "user" {
+				t.Errorf("Unexpected verify result %+v - %v", claims, err)
+			}
+
+			other, _ := Generate()
+			token, _ = other.Sign(map[string]interface{}{"sub": "user"})
+			_, err = verifier.Verify(token, time.Now())
+			if err != ErrInvalidToken {
+				t.Errorf("Unexpected error verifying token from another key - %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package mocks
+
+import (
+	"fmt"
+
+	"github.com/madflojo/mockitout/jwt"
+)
+
+// DefaultRealm is the realm returned within WWW-Authenticate headers when a route does
+// not set one.
+const DefaultRealm = "MockItOut"
+
+// DefaultAPIKeyHeader is the header holding API keys when neither a header or query
+// parameter is set.
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// Auth defines the authentication requirements of a route. Every configured method
+// must be satisfied, failing requests receive a 401 or 403.
+//
+// The below is a sample route requiring a JWT and client certificate in YAML format.
+//
+//	routes:
+//	  orders:
+//	    path: "/orders"
+//	    auth:
+//	      bearer:
+//	        jwt:
+//	          issuer: "https://idp.example.com"
+//	          audience: "orders"
+//	          scopes: ["orders:read"]
+//	      client_cert:
+//	        subjects: ["orders-client"]
+//	    body: '{"orders": []}'
+type Auth struct {
+	// Realm is the realm returned within WWW-Authenticate headers.
+	Realm string `yaml:"realm"`
+
+	// Basic requires HTTP Basic credentials.
+	Basic *BasicAuth `yaml:"basic"`
+
+	// Bearer requires a bearer token.
+	Bearer *BearerAuth `yaml:"bearer"`
+
+	// APIKey requires an API key within a header or query parameter.
+	APIKey *APIKeyAuth `yaml:"api_key"`
+
+	// ClientCert requires a TLS client certificate.
+	ClientCert *ClientCertAuth `yaml:"client_cert"`
+}
+
+// BasicAuth is the config of HTTP Basic authentication.
+type BasicAuth struct {
+	// Users is a map of usernames to passwords.
+	Users map[string]string `yaml:"users"`
+}
+
+// BearerAuth is the config of bearer token authentication. A request is accepted if
+// its token is one of the Tokens or a valid JWT.
+type BearerAuth struct {
+	// Tokens is a list of accepted opaque tokens.
+	Tokens []string `yaml:"tokens"`
+
+	// JWT accepts JSON Web Tokens validated against a key.
+	JWT *JWTAuth `yaml:"jwt"`
+}
+
+// JWTAuth is the config of JWT validation. Tokens are checked against the virtual clock.
+type JWTAuth struct {
+	// KeyFile is the location of a PEM encoded public key or certificate tokens are
+	// validated with. When empty the key used by jwt variables is used.
+	KeyFile string `yaml:"key_file"`
+
+	// Issuer is the required iss claim.
+	Issuer string `yaml:"issuer"`
+
+	// Audience is the required aud claim, a token with many audiences must include it.
+	Audience string `yaml:"audience"`
+
+	// Scopes are required within the space separated scope claim, requests without
+	// them are rejected with a 403.
+	Scopes []string `yaml:"scopes"`
+
+	// Claims are required claim values, requests without them are rejected with a 403.
+	Claims map[string]string `yaml:"claims"`
+
+	// verifier validates tokens when a KeyFile is set.
+	verifier *jwt.Verifier
+}
+
+// APIKeyAuth is the config of API key authentication.
+type APIKeyAuth struct {
+	// Header is the request header holding the key.
+	Header string `yaml:"header"`
+
+	// Query is the query parameter holding the key.
+	Query string `yaml:"query"`
+
+	// Keys is a list of accepted keys.
+	Keys []string `yaml:"keys"`
+}
+
+// ClientCertAuth is the config of TLS client certificate authentication.
+type ClientCertAuth struct {
+	// Subjects is a list of accepted certificate subjects, matching either the common
+	// name or full distinguished name. When empty any client certificate is accepted.
+	Subjects []string `yaml:"subjects"`
+}
+
+// RealmName returns the realm of the route.
+func (a *Auth) RealmName() string {
+	if a.Realm == "" {
+		return DefaultRealm
+	}
+	return a.Realm
+}
+
+// Verifier returns the Verifier used to validate tokens, the key used by jwt variables
+// is used when no KeyFile is set.
+func (j *JWTAuth) Verifier() *jwt.Verifier {
+	if j.verifier != nil {
+		return j.verifier
+	}
+	if jwt.Default == nil {
+		return nil
+	}
+	return jwt.Default.Verifier()
+}
+
+// validate will check the authentication requirements, loading any keys.
+func (a *Auth) validate() error {
+	if a.Basic == nil && a.Bearer == nil && a.APIKey == nil && a.ClientCert == nil {
+		return fmt.Errorf("auth requires at least one of basic, bearer, api_key or client_cert")
+	}
+	if a.Basic != nil && a.Bearer != nil {
+		return fmt.Errorf("basic and bearer auth cannot be used together")
+	}
+	if a.Basic != nil && len(a.Basic.Users) == 0 {
+		return fmt.Errorf("basic auth requires users")
+	}
+	if a.Bearer != nil {
+		if len(a.Bearer.Tokens) == 0 && a.Bearer.JWT == nil {
+			return fmt.Errorf("bearer auth requires tokens or jwt")
+		}
+		if j := a.Bearer.JWT; j != nil && j.KeyFile != "" {
+			v, err := jwt.VerifierFromFile(j.KeyFile)
+			if err != nil {
+				return fmt.Errorf("could not load jwt key - %s", err)
+			}
+			j.verifier = v
+		}
+	}
+	if a.APIKey != nil && len(a.APIKey.Keys) == 0 {
+		return fmt.Errorf("api_key auth requires keys")
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package mocks
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/madflojo/mockitout/variable"
+)
+
+// GRPCMethod is the config for each mocked gRPC method. Requests are decoded to JSON,
+// allowing the same match criteria and variables used for HTTP routes. The request
+// metadata is available as headers and the JSON request as the body.
+//
+// The below is a sample gRPC mock in YAML format.
+//
+//	grpc:
+//	  get_user:
+//	    method: "users.v1.UserService/GetUser"
+//	    match:
+//	      body:
+//	        "$.id":
+//	          value: "1"
+//	    headers:
+//	      "x-served-by": "MockItOut"
+//	    response: |
+//	      {"id": "{{ body.id }}", "name": "{{ $randomFirstName }}"}
+//	  missing_user:
+//	    method: "users.v1.UserService/GetUser"
+//	    status:
+//	      code: "NOT_FOUND"
+//	      message: "user not found"
+//	  watch_users:
+//	    method: "users.v1.UserService/WatchUsers"
+//	    stream:
+//	      - response: '{"id": "1"}'
+//	      - response: '{"id": "2"}'
+//	        delay: 1s
+type GRPCMethod struct {
+	// Method is the full name of the mocked method, package.Service/Method.
+	Method string `yaml:"method"`
+
+	// Match defines additional request criteria that must be satisfied for the mock
+	// to respond. Headers are matched against the request metadata.
+	Match Match `yaml:"match"`
+
+	// Headers is a map of response metadata.
+	Headers map[string]string `yaml:"headers"`
+
+	// Response is the JSON encoded response message for unary methods.
+	Response string `yaml:"response"`
+
+	// Stream is the list of JSON encoded response messages for server-streaming
+	// methods.
+	Stream []GRPCMessage `yaml:"stream"`
+
+	// Status is the gRPC status returned, when set no response messages are sent.
+	Status *GRPCStatus `yaml:"status"`
+
+	// StrictVariables fails requests with an INTERNAL status when variables cannot be
+	// resolved. When not set the server default is used.
+	StrictVariables *bool `yaml:"strict_variables"`
+
+	// response and headers are the compiled Response and Headers templates, these are
+	// compiled once when the method is validated.
+	response *variable.Template
+	headers  map[string]*variable.Template
+}
+
+// GRPCMessage is a single message sent by a server-streaming method.
+type GRPCMessage struct {
+	// Response is the JSON encoded response message. Variables are supported.
+	Response string `yaml:"response"`
+
+	// Delay is the time to wait before sending the message.
+	Delay time.Duration `yaml:"delay"`
+
+	// response is the compiled Response template.
+	response *variable.Template
+}
+
+// GRPCStatus is a gRPC error status.
+type GRPCStatus struct {
+	// Code is the gRPC status code, either the numeric value or name, e.g. NOT_FOUND.
+	Code string `yaml:"code"`
+
+	// Message is the status message. Variables are supported.
+	Message string `yaml:"message"`
+
+	// message is the compiled Message template.
+	message *variable.Template
+}
+
+// IsStrict will return true if unresolved variables should fail requests to the
+// method, def is used when the mock does not set StrictVariables.
+func (g GRPCMethod) IsStrict(def bool) bool {
+	if g.StrictVariables == nil {
+		return def
+	}
+	return *g.StrictVariables
+}
+
+// CompiledResponse will return the compiled Response template, nil is returned if the
+// method was not validated.
+func (g GRPCMethod) CompiledResponse() *variable.Template {
+	return g.response
+}
+
+// CompiledHeader will return the compiled template of the named response header, nil
+// is returned if the method was not validated.
+func (g GRPCMethod) CompiledHeader(k string) *variable.Template {
+	return g.headers[k]
+}
+
+// CompiledResponse will return the compiled Response template, nil is returned if the
+// method was not validated.
+func (m GRPCMessage) CompiledResponse() *variable.Template {
+	return m.response
+}
+
+// CompiledMessage will return the compiled Message template, nil is returned if the
+// method was not validated.
+func (s GRPCStatus) CompiledMessage() *variable.Template {
+	return s.message
+}
+
+// validate will check the gRPC method configuration, compiling its match criteria and
+// response values.
+func (g *GRPCMethod) validate() error {
+	svc, method, ok := strings.Cut(strings.TrimPrefix(g.Method, "/"), "/")
+	if !ok || svc == "" || method == "" {
+		return fmt.Errorf("invalid method name %q, expected package.Service/Method", g.Method)
+	}
+	if g.Status != nil && g.Status.Code == "" {
+		return fmt.Errorf("grpc status code must be defined")
+	}
+
+	compile := func(v string) (*variable.Template, error) {
+		err := variable.ValidateVariables(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q - %s", v, err)
+		}
+		return variable.Compile(v), nil
+	}
+
+	var err error
+	g.response, err = compile(g.Response)
+	if err != nil {
+		return err
+	}
+	for i := range g.Stream {
+		g.Stream[i].response, err = compile(g.Stream[i].Response)
+		if err != nil {
+			return err
+		}
+	}
+	if g.Status != nil {
+		g.Status.message, err = compile(g.Status.Message)
+		if err != nil {
+			return err
+		}
+	}
+	g.headers = make(map[string]*variable.Template, len(g.Headers))
+	for k, v := range g.Headers {
+		g.headers[k], err = compile(v)
+		if err != nil {
+			return err
+		}
+	}
+	return g.Match.Compile()
+}
@@ -0,0 +1,623 @@
+package mocks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/madflojo/mockitout/variable"
+)
+
+// ProviderStateHeader is the HTTP request header used to select routes by their Pact
+// provider state.
+const ProviderStateHeader = "X-Provider-State"
+
+// Match defines the request criteria a route requires before it will respond.
+//
+// The below is a sample route with match criteria in YAML format.
+//
+//	routes:
+//	  admin:
+//	    path: "/users"
+//	    method: "POST"
+//	    match:
+//	      headers:
+//	        "content-type":
+//	          match: "regex"
+//	          regex: "^application/json"
+//	      query:
+//	        "type":
+//	          value: "admin"
+//	      client_cert:
+//	        "cn":
+//	          value: "admin-client"
+//	      body:
+//	        "$.user.id":
+//	          match: "integer"
+//	    body: |
+//	      {"admin": true}
+type Match struct {
+	// Path is a matcher applied to the request URL path.
+	Path Matcher `yaml:"path"`
+
+	// Headers is a map of HTTP request headers and the matchers to apply to them.
+	Headers map[string]Matcher `yaml:"headers"`
+
+	// Query is a map of query parameters and the matchers to apply to them.
+	Query map[string]Matcher `yaml:"query"`
+
+	// ClientCert is a map of TLS client certificate fields and the matchers to apply to
+	// them, one of cn, subject, issuer, serial, sans or verified.
+	ClientCert map[string]Matcher `yaml:"client_cert"`
+
+	// Body is a map of JSON paths (e.g. $.user.id) and the matchers to apply to the
+	// values found within a JSON request body.
+	Body map[string]Matcher `yaml:"body"`
+
+	// paths are the parsed Body JSON paths, set by Compile.
+	paths map[string][]pathToken
+
+	// compiled is set once Compile succeeds, making later calls a no-op.
+	compiled bool
+}
+
+// Matcher defines a single comparison performed against a request value. The
+// supported match types follow the Pact specification.
+type Matcher struct {
+	// Match is the type of comparison to perform, one of equality, regex, type,
+	// include, integer, decimal, number, boolean or null. Default is equality.
+	Match string `yaml:"match"`
+
+	// Value is the expected value used by the equality, include and type matches.
+	Value interface{} `yaml:"value"`
+
+	// Regex is the regular expression used by the regex match.
+	Regex string `yaml:"regex"`
+
+	// Min is the minimum number of array elements allowed by a type match.
+	Min int `yaml:"min"`
+
+	// Max is the maximum number of array elements allowed by a type match.
+	Max int `yaml:"max"`
+
+	// All is a list of matchers which must all be satisfied. When All or Any are set
+	// the other fields of the matcher are ignored.
+	All []Matcher `yaml:"all"`
+
+	// Any is a list of matchers of which at least one must be satisfied.
+	Any []Matcher `yaml:"any"`
+
+	// re is the compiled Regex, set by Compile.
+	re *regexp.Regexp
+}
+
+// matchTypes are the supported match types.
+var matchTypes = []string{"", "equality", "regex", "type", "include", "integer", "decimal", "number", "boolean", "null"}
+
+// Compile will compile the regular expressions and JSON paths of the match criteria,
+// returning an error for invalid ones. Criteria which are not compiled are parsed on
+// each request, and criteria which are already compiled are left as they are.
+func (m *Match) Compile() error {
+	if m.compiled {
+		return nil
+	}
+	for k := range m.ClientCert {
+		if !slices.Contains(ClientCertFields, k) {
+			return fmt.Errorf("unknown client_cert field %s, expected one of %s", k, strings.Join(ClientCertFields, ", "))
+		}
+	}
+
+	err := m.Path.compile()
+	if err != nil {
+		return fmt.Errorf("invalid path matcher - %s", err)
+	}
+	for name, matchers := range map[string]map[string]Matcher{"header": m.Headers, "query": m.Query, "client_cert": m.ClientCert, "body": m.Body} {
+		for k, v := range matchers {
+			err := v.compile()
+			if err != nil {
+				return fmt.Errorf("invalid %s matcher %s - %s", name, k, err)
+			}
+			matchers[k] = v
+		}
+	}
+
+	m.paths = make(map[string][]pathToken, len(m.Body))
+	for k := range m.Body {
+		path, err := parsePath(k)
+		if err != nil {
+			return err
+		}
+		m.paths[k] = path
+	}
+	m.compiled = true
+	return nil
+}
+
+// compile will check the match type and compile the matcher's regular expression.
+func (m *Matcher) compile() error {
+	if !slices.Contains(matchTypes, m.Match) {
+		return fmt.Errorf("unknown match type %s", m.Match)
+	}
+	for _, list := range [][]Matcher{m.All, m.Any} {
+		for i := range list {
+			err := list[i].compile()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if m.Match != "regex" {
+		return nil
+	}
+	re, err := regexp.Compile(m.Regex)
+	if err != nil {
+		return fmt.Errorf("invalid regex %q - %s", m.Regex, err)
+	}
+	m.re = re
+	return nil
+}
+
+// IsZero will return true if the Matcher has not been defined.
+func (m Matcher) IsZero() bool {
+	return m.Match == "" && m.Value == nil && m.Regex == "" && m.Min == 0 && m.Max == 0 && len(m.All) == 0 && len(m.Any) == 0
+}
+
+// combined returns the result of applying match to the All and Any matchers, ok is
+// false when neither are set.
+func (m Matcher) combined(match func(Matcher) bool) (result bool, ok bool) {
+	if len(m.All) == 0 && len(m.Any) == 0 {
+		return false, false
+	}
+	for _, c := range m.All {
+		if !match(c) {
+			return false, true
+		}
+	}
+	if len(m.Any) == 0 {
+		return true, true
+	}
+	for _, c := range m.Any {
+		if match(c) {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// Matches will return true if the HTTP request satisfies the route's method and
+// match criteria.
+func (r Route) Matches(req *http.Request) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, req.Method) {
+		return false
+	}
+	return r.Match.Matches(req)
+}
+
+// Matches will return true if the HTTP request satisfies all of the match criteria.
+func (m Match) Matches(req *http.Request) bool {
+	if !m.Path.IsZero() && !m.Path.matchString(req.URL.Path, true) {
+		return false
+	}
+
+	for k, v := range m.Headers {
+		vals, ok := req.Header[http.CanonicalHeaderKey(k)]
+		if !v.matchStrings(vals, ok) {
+			return false
+		}
+	}
+
+	query := req.URL.Query()
+	for k, v := range m.Query {
+		vals, ok := query[k]
+		if !v.matchStrings(vals, ok) {
+			return false
+		}
+	}
+
+	if len(m.ClientCert) > 0 {
+		fields := clientCertFields(req)
+		for k, v := range m.ClientCert {
+			vals, ok := fields[k]
+			if !v.matchStrings(vals, ok) {
+				return false
+			}
+		}
+	}
+
+	if len(m.Body) > 0 {
+		doc, err := variable.JSONBody(req)
+		if err != nil {
+			return false
+		}
+		for k, v := range m.Body {
+			path, ok := m.paths[k]
+			if !ok {
+				var err error
+				path, err = parsePath(k)
+				if err != nil {
+					return false
+				}
+			}
+			if !v.matchJSON(selectPath(doc, path)) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// ClientCertFields are the client certificate fields supported by client_cert matchers.
+var ClientCertFields = []string{"cn", "subject", "issuer", "serial", "sans", "verified"}
+
+// clientCertFields will return the fields of the request's client certificate, nil is
+// returned when no certificate was presented.
+func clientCertFields(req *http.Request) map[string][]string {
+	if req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	cert := req.TLS.PeerCertificates[0]
+	return map[string][]string{
+		"cn":       {cert.Subject.CommonName},
+		"subject":  {cert.Subject.String()},
+		"issuer":   {cert.Issuer.String()},
+		"serial":   {cert.SerialNumber.String()},
+		"sans":     variable.CertSANs(cert),
+		"verified": {strconv.FormatBool(len(req.TLS.VerifiedChains) > 0)},
+	}
+}
+
+// decodeJSON will decode JSON data preserving the original number formatting.
+func decodeJSON(b []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// normalize will convert a value decoded from YAML into the same types produced when
+// decoding JSON.
+func normalize(v interface{}) interface{} {
+	b, err := json.Marshal(yamlToJSON(v))
+	if err != nil {
+		return v
+	}
+	var n interface{}
+	if err := decodeJSON(b, &n); err != nil {
+		return v
+	}
+	return n
+}
+
+// yamlToJSON will convert the map[interface{}]interface{} values created by the YAML
+// parser into map[string]interface{} values which can be encoded as JSON.
+func yamlToJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[fmt.Sprintf("%v", k)] = yamlToJSON(val)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(t))
+		for i, val := range t {
+			l[i] = yamlToJSON(val)
+		}
+		return l
+	}
+	return v
+}
+
+// matchStrings will compare a list of request values (such as headers or query
+// parameters) against the matcher.
+func (m Matcher) matchStrings(vals []string, present bool) bool {
+	if r, ok := m.combined(func(c Matcher) bool { return c.matchStrings(vals, present) }); ok {
+		return r
+	}
+	if list, ok := m.Value.([]interface{}); ok && (m.Match == "" || m.Match == "equality") {
+		if len(list) != len(vals) {
+			return false
+		}
+		for i, v := range list {
+			if fmt.Sprintf("%v", v) != vals[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !present || len(vals) == 0 {
+		return m.Match == "null"
+	}
+	for _, v := range vals {
+		if !m.matchString(v, true) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchString will compare a single string value against the matcher.
+func (m Matcher) matchString(v string, present bool) bool {
+	if r, ok := m.combined(func(c Matcher) bool { return c.matchString(v, present) }); ok {
+		return r
+	}
+	switch m.Match {
+	case "", "equality":
+		return present && v == fmt.Sprintf("%v", m.Value)
+	case "regex":
+		return present && m.regexMatch(v)
+	case "include":
+		return present && strings.Contains(v, fmt.Sprintf("%v", m.Value))
+	case "type":
+		return present
+	case "integer":
+		_, err := strconv.ParseInt(v, 10, 64)
+		return present && err == nil
+	case "decimal":
+		_, err := strconv.ParseFloat(v, 64)
+		return present && err == nil && strings.Contains(v, ".")
+	case "number":
+		_, err := strconv.ParseFloat(v, 64)
+		return present && err == nil
+	case "boolean":
+		_, err := strconv.ParseBool(v)
+		return present && err == nil
+	case "null":
+		return !present
+	}
+	return false
+}
+
+// matchJSON will compare the values selected from a JSON document against the
+// matcher. All selected values must match, and at least one must be found.
+func (m Matcher) matchJSON(vals []interface{}) bool {
+	if r, ok := m.combined(func(c Matcher) bool { return c.matchJSON(vals) }); ok {
+		return r
+	}
+	if len(vals) == 0 {
+		return m.Match == "null"
+	}
+	for _, v := range vals {
+		if !m.matchValue(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchValue will compare a single decoded JSON value against the matcher.
+func (m Matcher) matchValue(v interface{}) bool {
+	switch m.Match {
+	case "", "equality":
+		return jsonEqual(normalize(m.Value), v)
+	case "regex":
+		s, ok := scalarString(v)
+		return ok && m.regexMatch(s)
+	case "include":
+		s, ok := scalarString(v)
+		return ok && strings.Contains(s, fmt.Sprintf("%v", m.Value))
+	case "type":
+		if l, ok := v.([]interface{}); ok {
+			if m.Min > 0 && len(l) < m.Min {
+				return false
+			}
+			if m.Max > 0 && len(l) > m.Max {
+				return false
+			}
+		}
+		if m.Value == nil {
+			return true
+		}
+		return typeEqual(normalize(m.Value), v)
+	case "integer":
+		n, ok := v.(json.Number)
+		return ok && !strings.ContainsAny(n.String(), ".eE")
+	case "decimal":
+		n, ok := v.(json.Number)
+		return ok && strings.Contains(n.String(), ".")
+	case "number":
+		_, ok := v.(json.Number)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	}
+	return false
+}
+
+// regexMatch will return true if the value matches the matcher's regular expression,
+// which is compiled on each call if the matcher was not compiled.
+func (m Matcher) regexMatch(v string) bool {
+	if m.re != nil {
+		return m.re.MatchString(v)
+	}
+	ok, err := regexp.MatchString(m.Regex, v)
+	return err == nil && ok
+}
+
+// scalarString will convert a JSON scalar into a string.
+func scalarString(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case json.Number:
+		return t.String(), true
+	case bool:
+		return strconv.FormatBool(t), true
+	}
+	return "", false
+}
+
+// jsonEqual will compare two decoded JSON values, numbers are compared by value.
+func jsonEqual(a, b interface{}) bool {
+	an, aok := a.(json.Number)
+	bn, bok := b.(json.Number)
+	if aok && bok {
+		af, aerr := an.Float64()
+		bf, berr := bn.Float64()
+		return aerr == nil && berr == nil && af == bf
+	}
+
+	switch at := a.(type) {
+	case map[string]interface{}:
+		bt, ok := b.(map[string]interface{})
+		if !ok || len(at) != len(bt) {
+			return false
+		}
+		for k, v := range at {
+			if !jsonEqual(v, bt[k]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bt, ok := b.([]interface{})
+		if !ok || len(at) != len(bt) {
+			return false
+		}
+		for i := range at {
+			if !jsonEqual(at[i], bt[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// typeEqual will compare the shape of two decoded JSON values. Objects must contain
+// every key of the example, and all array elements must match the first example
+// element.
+func typeEqual(example, v interface{}) bool {
+	switch et := example.(type) {
+	case map[string]interface{}:
+		vt, ok := v.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, ev := range et {
+			val, ok := vt[k]
+			if !ok || !typeEqual(ev, val) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		vt, ok := v.([]interface{})
+		if !ok {
+			return false
+		}
+		if len(et) == 0 {
+			return true
+		}
+		for _, val := range vt {
+			if !typeEqual(et[0], val) {
+				return false
+			}
+		}
+		return true
+	case nil:
+		return v == nil
+	}
+	return reflect.TypeOf(example) == reflect.TypeOf(v)
+}
+
+// pathToken is a single step within a JSON path expression.
+type pathToken struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parsePath will parse simple JSON path expressions such as $.a.b[0]['c.d'][*].
+func parsePath(p string) ([]pathToken, error) {
+	if !strings.HasPrefix(p, "$") {
+		return nil, fmt.Errorf("json path %s must start with $", p)
+	}
+
+	var tokens []pathToken
+	s := p[1:]
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, ".*"):
+			tokens = append(tokens, pathToken{wildcard: true})
+			s = s[2:]
+		case s[0] == '.':
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end == -1 {
+				end = len(s)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("json path %s contains an empty key", p)
+			}
+			tokens = append(tokens, pathToken{key: s[:end]})
+			s = s[end:]
+		case s[0] == '[':
+			end := strings.Index(s, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("json path %s contains an unterminated bracket", p)
+			}
+			inner := s[1:end]
+			s = s[end+1:]
+			switch {
+			case inner == "*":
+				tokens = append(tokens, pathToken{wildcard: true})
+			case len(inner) > 1 && (inner[0] == '\'' || inner[0] == '"'):
+				tokens = append(tokens, pathToken{key: strings.Trim(inner, `'"`)})
+			default:
+				i, err := strconv.Atoi(inner)
+				if err != nil || i < 0 {
+					return nil, fmt.Errorf("json path %s contains an invalid index %s", p, inner)
+				}
+				tokens = append(tokens, pathToken{index: i, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("json path %s is invalid at %s", p, s)
+		}
+	}
+	return tokens, nil
+}
+
+// selectPath will return all values within the decoded JSON document found at the
+// provided path.
+func selectPath(doc interface{}, path []pathToken) []interface{} {
+	current := []interface{}{doc}
+	for _, t := range path {
+		var next []interface{}
+		for _, c := range current {
+			switch ct := c.(type) {
+			case map[string]interface{}:
+				if t.wildcard {
+					for _, v := range ct {
+						next = append(next, v)
+					}
+					continue
+				}
+				if v, ok := ct[t.key]; ok && !t.isIndex {
+					next = append(next, v)
+				}
+			case []interface{}:
+				if t.wildcard {
+					next = append(next, ct...)
+					continue
+				}
+				if t.isIndex && t.index >= 0 && t.index < len(ct) {
+					next = append(next, ct[t.index])
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
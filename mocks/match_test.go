@@ -0,0 +1,361 @@
+package mocks
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/madflojo/mockitout/variable"
+)
+
+func TestMatches(t *testing.T) {
+	type tc struct {
+		route   Route
+		method  string
+		url     string
+		headers map[string]string
+		body    string
+		tls     *tls.ConnectionState
+		pass    bool
+	}
+
+	cert := &x509.Certificate{
+		Subject:      pkix.Name{CommonName: "admin-client"},
+		Issuer:       pkix.Name{CommonName: "Test CA"},
+		SerialNumber: big.NewInt(7),
+		DNSNames:     []string{"admin.example.com"},
+	}
+
+	cases := map[string]tc{
+		"no criteria": {
+			route:  Route{},
+			method: "GET",
+			url:    "/test",
+			pass:   true,
+		},
+		"method": {
+			route:  Route{Method: "post"},
+			method: "POST",
+			url:    "/test",
+			pass:   true,
+		},
+		"wrong method": {
+			route:  Route{Method: "POST"},
+			method: "GET",
+			url:    "/test",
+			pass:   false,
+		},
+		"path regex": {
+			route:  Route{Match: Match{Path: Matcher{Match: "regex", Regex: `^/users/\d+$`}}},
+			method: "GET",
+			url:    "/users/10",
+			pass:   true,
+		},
+		"bad path regex": {
+			route:  Route{Match: Match{Path: Matcher{Match: "regex", Regex: `^/users/\d+$`}}},
+			method: "GET",
+			url:    "/users/abc",
+			pass:   false,
+		},
+		"header equality": {
+			route:   Route{Match: Match{Headers: map[string]Matcher{"x-test": {Value: "yes"}}}},
+			method:  "GET",
+			url:     "/test",
+			headers: map[string]string{"X-Test": "yes"},
+			pass:    true,
+		},
+		"missing header": {
+			route:  Route{Match: Match{Headers: map[string]Matcher{"x-test": {Value: "yes"}}}},
+			method: "GET",
+			url:    "/test",
+			pass:   false,
+		},
+		"null header": {
+			route:  Route{Match: Match{Headers: map[string]Matcher{"x-test": {Match: "null"}}}},
+			method: "GET",
+			url:    "/test",
+			pass:   true,
+		},
+		"query integer": {
+			route:  Route{Match: Match{Query: map[string]Matcher{"page": {Match: "integer"}}}},
+			method: "GET",
+			url:    "/test?page=2",
+			pass:   true,
+		},
+		"query not integer": {
+			route:  Route{Match: Match{Query: map[string]Matcher{"page": {Match: "integer"}}}},
+			method: "GET",
+			url:    "/test?page=two",
+			pass:   false,
+		},
+		"query list": {
+			route:  Route{Match: Match{Query: map[string]Matcher{"id": {Value: []interface{}{"1", "2"}}}}},
+			method: "GET",
+			url:    "/test?id=1&id=2",
+			pass:   true,
+		},
+		"body equality": {
+			route:  Route{Match: Match{Body: map[string]Matcher{"$.user.id": {Value: 10}}}},
+			method: "POST",
+			url:    "/test",
+			body:   `{"user": {"id": 10}}`,
+			pass:   true,
+		},
+		"body wrong value": {
+			route:  Route{Match: Match{Body: map[string]Matcher{"$.user.id": {Value: 10}}}},
+			method: "POST",
+			url:    "/test",
+			body:   `{"user": {"id": 11}}`,
+			pass:   false,
+		},
+		"body wildcard type": {
+			route:  Route{Match: Match{Body: map[string]Matcher{"$.items[*].id": {Match: "type", Value: 1}}}},
+			method: "POST",
+			url:    "/test",
+			body:   `{"items": [{"id": 1}, {"id": 2}]}`,
+			pass:   true,
+		},
+		"body wildcard wrong type": {
+			route:  Route{Match: Match{Body: map[string]Matcher{"$.items[*].id": {Match: "type", Value: 1}}}},
+			method: "POST",
+			url:    "/test",
+			body:   `{"items": [{"id": 1}, {"id": "2"}]}`,
+			pass:   false,
+		},
+		"body array min": {
+			route:  Route{Match: Match{Body: map[string]Matcher{"$.items": {Match: "type", Min: 2}}}},
+			method: "POST",
+			url:    "/test",
+			body:   `{"items": [1]}`,
+			pass:   false,
+		},
+		"body decimal": {
+			route:  Route{Match: Match{Body: map[string]Matcher{"$['price.usd']": {Match: "decimal"}}}},
+			method: "POST",
+			url:    "/test",
+			body:   `{"price.usd": 1.50}`,
+			pass:   true,
+		},
+		"body invalid json": {
+			route:  Route{Match: Match{Body: map[string]Matcher{"$.id": {Match: "type"}}}},
+			method: "POST",
+			url:    "/test",
+			body:   `{"id":`,
+			pass:   false,
+		},
+		"all matchers": {
+			route:  Route{Match: Match{Query: map[string]Matcher{"id": {All: []Matcher{{Match: "integer"}, {Match: "regex", Regex: `^1`}}}}}},
+			method: "GET",
+			url:    "/test?id=12",
+			pass:   true,
+		},
+		"all matchers failed": {
+			route:  Route{Match: Match{Query: map[string]Matcher{"id": {All: []Matcher{{Match: "integer"}, {Match: "regex", Regex: `^1`}}}}}},
+			method: "GET",
+			url:    "/test?id=22",
+			pass:   false,
+		},
+		"any matchers": {
+			route:  Route{Match: Match{Body: map[string]Matcher{"$.id": {Any: []Matcher{{Match: "integer"}, {Match: "null"}}}}}},
+			method: "POST",
+			url:    "/test",
+			body:   `{"name": "test"}`,
+			pass:   true,
+		},
+		"any matchers failed": {
+			route:  Route{Match: Match{Body: map[string]Matcher{"$.id": {Any: []Matcher{{Match: "integer"}, {Match: "null"}}}}}},
+			method: "POST",
+			url:    "/test",
+			body:   `{"id": "abc"}`,
+			pass:   false,
+		},
+		"client cert cn": {
+			route:  Route{Match: Match{ClientCert: map[string]Matcher{"cn": {Value: "admin-client"}, "sans": {Value: "admin.example.com"}}}},
+			method: "GET",
+			url:    "/test",
+			tls:    &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+			pass:   true,
+		},
+		"client cert wrong cn": {
+			route:  Route{Match: Match{ClientCert: map[string]Matcher{"cn": {Value: "other-client"}}}},
+			method: "GET",
+			url:    "/test",
+			tls:    &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+			pass:   false,
+		},
+		"client cert verified": {
+			route:  Route{Match: Match{ClientCert: map[string]Matcher{"verified": {Match: "boolean"}, "issuer": {Match: "include", Value: "Test CA"}}}},
+			method: "GET",
+			url:    "/test",
+			tls:    &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}, VerifiedChains: [][]*x509.Certificate{{cert}}},
+			pass:   true,
+		},
+		"client cert not verified": {
+			route:  Route{Match: Match{ClientCert: map[string]Matcher{"verified": {Value: true}}}},
+			method: "GET",
+			url:    "/test",
+			tls:    &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+			pass:   false,
+		},
+		"client cert missing": {
+			route:  Route{Match: Match{ClientCert: map[string]Matcher{"cn": {Value: "admin-client"}}}},
+			method: "GET",
+			url:    "/test",
+			pass:   false,
+		},
+		"client cert null": {
+			route:  Route{Match: Match{ClientCert: map[string]Matcher{"cn": {Match: "null"}}}},
+			method: "GET",
+			url:    "/test",
+			pass:   true,
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			r, err := http.NewRequest(v.method, v.url, strings.NewReader(v.body))
+			if err != nil {
+				t.Fatalf("Unable to create request - %s", err)
+			}
+			for h, val := range v.headers {
+				r.Header.Set(h, val)
+			}
+			r.TLS = v.tls
+
+			if v.route.Matches(r) != v.pass {
+				t.Errorf("Unexpected match result, expected %t", v.pass)
+			}
+
+			// Compiled criteria should match the same requests
+			err = v.route.Match.Compile()
+			if err != nil {
+				t.Fatalf("Unexpected error compiling match criteria - %s", err)
+			}
+			r.Body = ioutil.NopCloser(strings.NewReader(v.body))
+			if v.route.Matches(r) != v.pass {
+				t.Errorf("Unexpected compiled match result, expected %t", v.pass)
+			}
+
+			// Body should still be readable after matching
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil || string(b) != v.body {
+				t.Errorf("Request body was not preserved - %s", b)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	m := Mocks{}
+	m.AddRoute("a_found", Route{Path: "/users/:id", Method: "GET", ProviderStates: []string{"user exists"}, ReturnCode: 200})
+	m.AddRoute("b_missing", Route{Path: "/users/:id", Method: "GET", ProviderStates: []string{"user missing"}, ReturnCode: 404})
+	m.AddRoute("c_create", Route{Path: "/users/:id", Method: "PATCH", ReturnCode: 201})
+
+	t.Run("Default route", func(t *testing.T) {
+		r, _ := http.NewRequest("GET", "/users/1", nil)
+		name, _, ok := m.Lookup("/users/:id", r)
+		if !ok || name != "a_found" {
+			t.Errorf("Unexpected route %s", name)
+		}
+	})
+
+	t.Run("Provider state", func(t *testing.T) {
+		r, _ := http.NewRequest("GET", "/users/1", nil)
+		r.Header.Set(ProviderStateHeader, "user missing")
+		name, route, ok := m.Lookup("/users/:id", r)
+		if !ok || name != "b_missing" || route.ReturnCode != 404 {
+			t.Errorf("Unexpected route %s", name)
+		}
+	})
+
+	t.Run("Unknown provider state", func(t *testing.T) {
+		r, _ := http.NewRequest("GET", "/users/1", nil)
+		r.Header.Set(ProviderStateHeader, "no users")
+		_, _, ok := m.Lookup("/users/:id", r)
+		if ok {
+			t.Errorf("Expected no route to match")
+		}
+	})
+
+	t.Run("Method", func(t *testing.T) {
+		r, _ := http.NewRequest("PATCH", "/users/1", nil)
+		name, _, ok := m.Lookup("/users/:id", r)
+		if !ok || name != "c_create" {
+			t.Errorf("Unexpected route %s", name)
+		}
+	})
+
+	t.Run("Methods", func(t *testing.T) {
+		methods := m.Methods("/users/:id")
+		if len(methods) != 5 || methods[4] != "PATCH" {
+			t.Errorf("Unexpected methods %v", methods)
+		}
+	})
+
+	t.Run("Replace route", func(t *testing.T) {
+		m.AddRoute("c_create", Route{Path: "/create", Method: "PATCH"})
+		if len(m.Paths["/users/:id"]) != 2 || len(m.Paths["/create"]) != 1 {
+			t.Errorf("Unexpected paths %v", m.Paths)
+		}
+	})
+}
+
+func TestLookupGRPC(t *testing.T) {
+	m := Mocks{
+		GRPC: map[string]GRPCMethod{
+			"a_admin": {Method: "users.v1.UserService/GetUser", Match: Match{Headers: map[string]Matcher{"x-role": {Value: "admin"}}}},
+			"b_user":  {Method: "users.v1.UserService/GetUser"},
+		},
+		GRPCMethods: map[string][]string{
+			"users.v1.UserService/GetUser": {"a_admin", "b_user"},
+		},
+	}
+
+	r, _ := http.NewRequest("POST", "/users.v1.UserService/GetUser", strings.NewReader("{}"))
+	name, _, ok := m.LookupGRPC("/users.v1.UserService/GetUser", r)
+	if !ok || name != "b_user" {
+		t.Errorf("Unexpected mock %s", name)
+	}
+
+	r.Header.Set("x-role", "admin")
+	name, _, ok = m.LookupGRPC("users.v1.UserService/GetUser", r)
+	if !ok || name != "a_admin" {
+		t.Errorf("Unexpected mock %s", name)
+	}
+
+	_, _, ok = m.LookupGRPC("users.v1.UserService/DeleteUser", r)
+	if ok {
+		t.Errorf("Expected no mock to match")
+	}
+}
+
+func TestMatchBodyLimit(t *testing.T) {
+	defer func(n int64) { variable.MaxBodySize = n }(variable.MaxBodySize)
+	variable.MaxBodySize = 16
+	route := Route{Match: Match{Body: map[string]Matcher{"$.id": {Match: "integer"}}}}
+
+	cases := map[string]struct {
+		body string
+		pass bool
+	}{
+		"Within Limit": {`{"id": 1}`, true},
+		"Over Limit":   {`{"id": 1, "name": "Andre"}`, false},
+	}
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			r, _ := http.NewRequest("POST", "/", strings.NewReader(v.body))
+			if route.Matches(r) != v.pass {
+				t.Errorf("Unexpected match result, expected %t", v.pass)
+			}
+			b, _ := ioutil.ReadAll(r.Body)
+			if string(b) != v.body {
+				t.Errorf("Request body was not preserved - %s", b)
+			}
+		})
+	}
+}
@@ -5,19 +5,18 @@ this service.
 
 The below is a sample mocks definition in YAML format.
 
-  routes:
-    hello:
-      path: "/hi"
-      response_headers:
-        "content-type": "application/json"
-        "server": "MockItOut"
-      # Multi-line values can be created like this
-      body: |
-        {
-          "greeting": "Hello",
-          "name": "World"
-        }
-
+	routes:
+	  hello:
+	    path: "/hi"
+	    response_headers:
+	      "content-type": "application/json"
+	      "server": "MockItOut"
+	    # Multi-line values can be created like this
+	    body: |
+	      {
+	        "greeting": "Hello",
+	        "name": "World"
+	      }
 */
 package mocks
 
@@ -25,18 +24,36 @@ import (
 	"fmt"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/madflojo/mockitout/variable"
 )
 
+// TemplateGo is the Route Template value used to enable Go text/template rendering.
+const TemplateGo = "go"
+
 // Mocks defines the main mocks file structure.
 type Mocks struct {
 	// Routes is a map of Route values, each route is a mocked URI.
 	Routes map[string]Route `yaml:"routes"`
 
+	// GRPC is a map of GRPCMethod values, each is a mocked gRPC method.
+	GRPC map[string]GRPCMethod `yaml:"grpc"`
+
+	// OIDC enables the built-in OAuth 2.0 and OpenID Connect provider.
+	OIDC *OIDC `yaml:"oidc"`
+
+	// GRPCMethods is a map of full gRPC method names to mock names, sorted by name.
+	GRPCMethods map[string][]string
+
 	// Paths is a map of Path to Route names. This can be used to quickly lookup a
-	// path and match it to a named route configuration.
-	Paths map[string]string
+	// path and match it to the named route configurations sharing that path. Names
+	// are kept in sorted order, the first route which matches a request wins.
+	Paths map[string][]string
 }
 
 // Route is the primary config for each mocked URI.
@@ -44,6 +61,18 @@ type Route struct {
 	// Path is the URI value being mocked.
 	Path string `yaml:"path"`
 
+	// Method restricts the route to a single HTTP method. When empty the route will
+	// respond to GET, POST, PUT and DELETE requests.
+	Method string `yaml:"method"`
+
+	// Match defines additional request criteria that must be satisfied for the route
+	// to respond. This allows multiple routes to share the same path.
+	Match Match `yaml:"match"`
+
+	// ProviderStates is a list of Pact provider states this route represents. When a
+	// request includes the ProviderStateHeader only routes with that state will match.
+	ProviderStates []string `yaml:"provider_states"`
+
 	// ResponseHeaders is a map of custom HTTP response headers.
 	ResponseHeaders map[string]string `yaml:"response_headers"`
 
@@ -52,6 +81,40 @@ type Route struct {
 
 	// Body is the HTTP payload returned to be returned by the server.
 	Body string `yaml:"body"`
+
+	// Template selects the engine used to render the Body, headers and streamed
+	// messages. When empty {{ variable }} substitution is used, when set to TemplateGo
+	// values are rendered as Go text/template templates.
+	Template string `yaml:"template"`
+
+	// StrictVariables fails requests with a diagnostic 500 when variables cannot be
+	// resolved. When not set the server default is used.
+	StrictVariables *bool `yaml:"strict_variables"`
+
+	// Auth defines the authentication requests must provide, when not set any request
+	// is accepted.
+	Auth *Auth `yaml:"auth"`
+
+	// WebSocket defines a scripted WebSocket exchange. When set the request is
+	// upgraded to a WebSocket connection and the script is played.
+	WebSocket *WebSocket `yaml:"websocket"`
+
+	// SSE defines a Server-Sent Events stream. When set the events are streamed to
+	// the client instead of the Body.
+	SSE *SSE `yaml:"sse"`
+
+	// Chunks is a list of body fragments written and flushed to the client one at a
+	// time. When set the chunks are written instead of the Body.
+	Chunks []Chunk `yaml:"chunks"`
+
+	// Stream defines how the Body is streamed to the client, allowing slow and
+	// partial responses to be simulated.
+	Stream *Stream `yaml:"stream"`
+
+	// body and headers are the compiled Body and ResponseHeaders templates, these are
+	// compiled once when the route is added.
+	body    *variable.Template
+	headers map[string]*variable.Template
 }
 
 // FromFile will read the Mocks file from the specified file path and return a
@@ -72,26 +135,295 @@ func FromFile(filepath string) (Mocks, error) {
 	}
 
 	// Check validity of Mocks file
-	if len(m.Routes) < 1 {
+	if len(m.Routes) < 1 && len(m.GRPC) < 1 && m.OIDC == nil {
 		return m, fmt.Errorf("no routes defined in Mocks file")
 	}
 
 	// Setup helper values
-	m.Paths = make(map[string]string)
-	for k, v := range m.Routes {
-		match, err := regexp.MatchString(`\*$`, v.Path)
-		if err == nil && match {
-			v.Path = v.Path + "wildcard"
-			m.Routes[k] = v
+	routes := m.Routes
+	m.Routes = make(map[string]Route)
+	m.Paths = make(map[string][]string)
+	for k, v := range routes {
+		err = v.Validate()
+		if err != nil {
+			return m, fmt.Errorf("invalid route %s - %s", k, err)
 		}
+		err = m.AddRoute(k, v)
+		if err != nil {
+			return m, fmt.Errorf("invalid route %s - %s", k, err)
+		}
+	}
 
-		// Create lookup map
-		m.Paths[v.Path] = k
+	m.GRPCMethods = make(map[string][]string)
+	for k, v := range m.GRPC {
+		err = v.validate()
+		if err != nil {
+			return m, fmt.Errorf("invalid grpc mock %s - %s", k, err)
+		}
+		m.GRPC[k] = v
+		method := strings.TrimPrefix(v.Method, "/")
+		m.GRPCMethods[method] = append(m.GRPCMethods[method], k)
+		sort.Strings(m.GRPCMethods[method])
+	}
+
+	if m.OIDC != nil {
+		err = m.OIDC.validate()
+		if err != nil {
+			return m, fmt.Errorf("invalid oidc provider - %s", err)
+		}
 	}
 
 	return m, nil
 }
 
+// Validate will check the route configuration for errors, preparing any values
+// used while serving the route. Match criteria are compiled and checked by AddRoute.
+func (r Route) Validate() error {
+	validate := variable.ValidateVariables
+	switch r.Template {
+	case "":
+	case TemplateGo:
+		validate = variable.ValidateTemplate
+	default:
+		return fmt.Errorf("unknown template engine %s", r.Template)
+	}
+	for _, v := range r.values() {
+		err := validate(v)
+		if err != nil {
+			return fmt.Errorf("invalid value %q - %s", v, err)
+		}
+	}
+
+	if r.Auth != nil {
+		err := r.Auth.validate()
+		if err != nil {
+			return err
+		}
+	}
+	if r.WebSocket != nil {
+		err := r.WebSocket.validate()
+		if err != nil {
+			return err
+		}
+	}
+	if r.SSE != nil {
+		err := r.SSE.validate()
+		if err != nil {
+			return err
+		}
+	}
+	if r.Stream != nil {
+		if len(r.Chunks) > 0 {
+			return fmt.Errorf("chunks and stream cannot be used together")
+		}
+		err := r.Stream.validate()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// values returns the route's response values which support variables.
+func (r Route) values() []string {
+	v := []string{r.Body}
+	for _, h := range r.ResponseHeaders {
+		v = append(v, h)
+	}
+	for _, c := range r.Chunks {
+		v = append(v, c.Body)
+	}
+	if r.SSE != nil {
+		for _, e := range r.SSE.Events {
+			v = append(v, e.ID, e.Event, e.Data)
+		}
+	}
+	if r.WebSocket != nil {
+		v = append(v, r.WebSocket.values()...)
+	}
+	return v
+}
+
+// compile will parse the route's response values into templates, storing each one
+// alongside its value. Values which fail to compile are left out and report their
+// error when rendered.
+func (r *Route) compile() {
+	compile := func(v string) *variable.Template {
+		t, err := r.Compiled(v)
+		if err != nil {
+			return nil
+		}
+		return t
+	}
+
+	r.body = compile(r.Body)
+	r.headers = make(map[string]*variable.Template, len(r.ResponseHeaders))
+	for k, v := range r.ResponseHeaders {
+		r.headers[k] = compile(v)
+	}
+	for i := range r.Chunks {
+		r.Chunks[i].body = compile(r.Chunks[i].Body)
+	}
+	if r.SSE != nil {
+		for i, e := range r.SSE.Events {
+			r.SSE.Events[i].id, r.SSE.Events[i].event, r.SSE.Events[i].data = compile(e.ID), compile(e.Event), compile(e.Data)
+		}
+	}
+	if r.WebSocket != nil {
+		r.WebSocket.compile(compile)
+	}
+}
+
+// Compiled will parse one of the route's response values using the route's template
+// engine. This is used for values which were not compiled when the route was added.
+func (r Route) Compiled(v string) (*variable.Template, error) {
+	if r.Template == TemplateGo {
+		return variable.CompileTemplate(v)
+	}
+	return variable.Compile(v), nil
+}
+
+// CompiledBody will return the compiled Body template, nil is returned if the route
+// was not compiled when added.
+func (r Route) CompiledBody() *variable.Template {
+	return r.body
+}
+
+// CompiledHeader will return the compiled template of the named response header, nil
+// is returned if the route was not compiled when added.
+func (r Route) CompiledHeader(k string) *variable.Template {
+	return r.headers[k]
+}
+
+// AddRoute will add the named Route to the Mocks configuration, updating the path
+// lookup map as it goes. Existing routes with the same name are replaced. The match
+// criteria are compiled on the stored route, returning an error if they are invalid.
+func (m *Mocks) AddRoute(name string, r Route) error {
+	if m.Routes == nil {
+		m.Routes = make(map[string]Route)
+	}
+	if m.Paths == nil {
+		m.Paths = make(map[string][]string)
+	}
+
+	err := r.Match.Compile()
+	if err != nil {
+		return err
+	}
+	r.compile()
+
+	if old, ok := m.Routes[name]; ok {
+		m.removePath(old.Path, name)
+	}
+
+	match, err := regexp.MatchString(`\*$`, r.Path)
+	if err == nil && match {
+		r.Path = r.Path + "wildcard"
+	}
+	m.Routes[name] = r
+
+	// Create lookup map
+	m.Paths[r.Path] = append(m.Paths[r.Path], name)
+	sort.Strings(m.Paths[r.Path])
+	return nil
+}
+
+// removePath will remove the route name from the path lookup map.
+func (m *Mocks) removePath(path, name string) {
+	names := m.Paths[path]
+	for i, n := range names {
+		if n == name {
+			m.Paths[path] = append(names[:i], names[i+1:]...)
+			break
+		}
+	}
+	if len(m.Paths[path]) == 0 {
+		delete(m.Paths, path)
+	}
+}
+
+// Methods returns the list of HTTP methods that should be registered for the
+// provided path.
+func (m Mocks) Methods(path string) []string {
+	methods := []string{"GET", "POST", "PUT", "DELETE"}
+	for _, n := range m.Paths[path] {
+		r := m.Routes[n]
+		if r.Method == "" {
+			continue
+		}
+		found := false
+		for _, v := range methods {
+			if strings.EqualFold(v, r.Method) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			methods = append(methods, strings.ToUpper(r.Method))
+		}
+	}
+	return methods
+}
+
+// Lookup will find the first route registered under the provided path which matches
+// the HTTP request. When the request specifies a provider state, routes representing
+// that state are preferred over routes without any provider states. The name of the
+// route is returned along with the route itself.
+func (m Mocks) Lookup(path string, r *http.Request) (string, Route, bool) {
+	state := r.Header.Get(ProviderStateHeader)
+	if state != "" {
+		for _, n := range m.Paths[path] {
+			route, ok := m.Routes[n]
+			if ok && route.hasState(state) && route.Matches(r) {
+				return n, route, true
+			}
+		}
+	}
+
+	for _, n := range m.Paths[path] {
+		route, ok := m.Routes[n]
+		if !ok || (state != "" && len(route.ProviderStates) > 0) {
+			continue
+		}
+		if route.Matches(r) {
+			return n, route, true
+		}
+	}
+	return "", Route{}, false
+}
+
+// LookupGRPC will find the first gRPC mock for the full method name which matches the
+// request. The request should contain the metadata as headers and the JSON encoded
+// message as the body.
+func (m Mocks) LookupGRPC(method string, r *http.Request) (string, GRPCMethod, bool) {
+	for _, n := range m.GRPCMethods[strings.TrimPrefix(method, "/")] {
+		g, ok := m.GRPC[n]
+		if ok && g.Match.Matches(r) {
+			return n, g, true
+		}
+	}
+	return "", GRPCMethod{}, false
+}
+
+// IsStrict will return true if unresolved variables should fail requests to the
+// route, def is used when the route does not set StrictVariables.
+func (r Route) IsStrict(def bool) bool {
+	if r.StrictVariables == nil {
+		return def
+	}
+	return *r.StrictVariables
+}
+
+// hasState will return true if the route represents the provided provider state.
+func (r Route) hasState(state string) bool {
+	for _, s := range r.ProviderStates {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
 // GenExampleFile will generate a basic example Mocks file. This is used
 // primarily for testing.
 func GenExampleFile() (*os.File, error) {
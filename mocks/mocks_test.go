@@ -4,6 +4,9 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
+
+	"github.com/madflojo/mockitout/variable"
 )
 
 func TestFromFile(t *testing.T) {
@@ -24,7 +27,142 @@ routes:
     '''
     return_code: 200
   `)
+	data["websocket yaml"] = []byte(`
+routes:
+  ws:
+    path: "/ws"
+    websocket:
+      on_connect:
+        - message: "hello"
+          delay: 100ms
+      replies:
+        - match: "^ping$"
+          messages:
+            - message: "pong"
+      periodic:
+        - interval: 5s
+          message: "tick"
+      close:
+        after: 1m
+        code: 1000
+  `)
 
+	data["chunks yaml"] = []byte(`
+routes:
+  slow:
+    path: "/slow"
+    chunks:
+      - body: '{"items": ['
+      - body: '{"id": 1}]}'
+        delay: 500ms
+  `)
+	data["stream yaml"] = []byte(`
+routes:
+  download:
+    path: "/download"
+    stream:
+      size: 1024
+      rate: 4096
+    body: "data"
+  `)
+	data["grpc yaml"] = []byte(`
+grpc:
+  get_user:
+    method: "users.v1.UserService/GetUser"
+    match:
+      body:
+        "$.id":
+          value: "1"
+    response: |
+      {"id": "{{ body.id }}"}
+  watch_users:
+    method: "users.v1.UserService/WatchUsers"
+    stream:
+      - response: '{"id": "1"}'
+        delay: 1s
+  `)
+	data["go template yaml"] = []byte(`
+routes:
+  users:
+    path: "/users"
+    template: go
+    response_headers:
+      "x-type": '{{ .Query.type | default "user" }}'
+    body: |
+      {{ if eq .Query.type "admin" }}{"admin": true}{{ else }}{"admin": false}{{ end }}
+  `)
+	data["random arguments yaml"] = []byte(`
+routes:
+  random:
+    path: "/random"
+    response_headers:
+      "x-id": "{{ $randomAlphaNumberic(8) }}"
+    body: '{"age": {{ $randomInt(18, 99) }}, "role": "{{ $oneOf(admin, user) }}", "name": "{{ header.name }}"}'
+  `)
+	data["filters yaml"] = []byte(`
+routes:
+  filters:
+    path: "/filters"
+    strict_variables: true
+    response_headers:
+      "expires": '{{ now "+1h" http }}'
+    body: '{"page": "{{ query.page | default "1" }}", "echo": "{{ body | json }}", "literal": "\{{ body }}"}'
+  `)
+	data["sse yaml"] = []byte(`
+routes:
+  sse:
+    path: "/sse"
+    sse:
+      loop: true
+      events:
+        - id: "1"
+          event: "tick"
+          data: "tick"
+          retry: 1000
+          delay: 1s
+  `)
+
+	data["oidc yaml"] = []byte(`
+oidc:
+  path: "/realms/test"
+  token_expiry: 5m
+  claims:
+    address:
+      country: "NZ"
+  clients:
+    web:
+      redirect_uris: ["http://localhost:3000/callback"]
+  users:
+    alice:
+      claims:
+        roles: ["admin"]
+  `)
+	data["auth yaml"] = []byte(`
+routes:
+  basic:
+    path: "/basic"
+    auth:
+      realm: "orders"
+      basic:
+        users:
+          alice: "s3cret"
+  bearer:
+    path: "/bearer"
+    auth:
+      bearer:
+        tokens: ["static-token"]
+        jwt:
+          issuer: "https://idp.example.com"
+          audience: "orders"
+          scopes: ["orders:read"]
+          claims:
+            role: "admin"
+      api_key:
+        query: "api_key"
+        keys: ["abc"]
+      client_cert:
+        subjects: ["orders-client"]
+  `)
 	for k, v := range data {
 		t.Run("Testing "+k, func(t *testing.T) {
 
@@ -63,6 +201,220 @@ routes:
       - "content-type": "application/json"
   `)
 
+	data["invalid websocket reply"] = []byte(`
+routes:
+  ws:
+    path: "/ws"
+    websocket:
+      replies:
+        - match: "(unclosed"
+  `)
+	data["empty sse events"] = []byte(`
+routes:
+  sse:
+    path: "/sse"
+    sse:
+      events: []
+  `)
+	data["sse loop without delay"] = []byte(`
+routes:
+  sse:
+    path: "/sse"
+    sse:
+      loop: true
+      events:
+        - data: "tick"
+  `)
+	data["chunks and stream"] = []byte(`
+routes:
+  slow:
+    path: "/slow"
+    chunks:
+      - body: "a"
+    stream:
+      size: 1
+  `)
+	data["stream without size"] = []byte(`
+routes:
+  slow:
+    path: "/slow"
+    stream:
+      rate: 10
+  `)
+	data["invalid grpc method"] = []byte(`
+grpc:
+  get_user:
+    method: "GetUser"
+  `)
+	data["grpc status without code"] = []byte(`
+grpc:
+  get_user:
+    method: "users.v1.UserService/GetUser"
+    status:
+      message: "not found"
+  `)
+	data["invalid go template"] = []byte(`
+routes:
+  users:
+    path: "/users"
+    template: go
+    body: "{{ if .Query.type }}"
+  `)
+	data["unknown template engine"] = []byte(`
+routes:
+  users:
+    path: "/users"
+    template: jinja
+  `)
+	data["invalid random arguments"] = []byte(`
+routes:
+  random:
+    path: "/random"
+    body: "{{ $randomInt(99, 18) }}"
+  `)
+	data["unknown filter"] = []byte(`
+routes:
+  filters:
+    path: "/filters"
+    body: "{{ body | reverse }}"
+  `)
+	data["invalid time offset"] = []byte(`
+routes:
+  token:
+    path: "/token"
+    body: '{{ now "+1q" }}'
+  `)
+	data["unknown random variable"] = []byte(`
+routes:
+  ws:
+    path: "/ws"
+    websocket:
+      on_connect:
+        - message: "{{ $notARandom }}"
+  `)
+	data["invalid grpc random arguments"] = []byte(`
+grpc:
+  get_user:
+    method: "users.v1.UserService/GetUser"
+    response: '{"id": "{{ $randomInt(a, b) }}"}'
+  `)
+	data["invalid websocket interval"] = []byte(`
+routes:
+  ws:
+    path: "/ws"
+    websocket:
+      periodic:
+        - message: "tick"
+  `)
+
+	data["invalid oidc issuer"] = []byte(`
+oidc:
+  issuer: "not a url"
+  `)
+	data["invalid oidc redirect uri"] = []byte(`
+oidc:
+  clients:
+    web:
+      redirect_uris: ["/callback"]
+  `)
+	data["invalid match regex"] = []byte(`
+routes:
+  hello:
+    path: "/hi"
+    match:
+      headers:
+        "x-id":
+          match: "regex"
+          regex: "([a-z"
+  `)
+	data["negative match json path index"] = []byte(`
+routes:
+  hello:
+    path: "/hi"
+    match:
+      body:
+        "$.items[-1]":
+          match: "integer"
+  `)
+	data["invalid match json path"] = []byte(`
+routes:
+  hello:
+    path: "/hi"
+    match:
+      body:
+        "user.id":
+          match: "integer"
+  `)
+	data["invalid match type"] = []byte(`
+routes:
+  hello:
+    path: "/hi"
+    match:
+      query:
+        "page":
+          match: "numeric"
+  `)
+	data["invalid combined match regex"] = []byte(`
+routes:
+  hello:
+    path: "/hi"
+    match:
+      path:
+        any:
+          - match: "regex"
+            regex: "(("
+  `)
+	data["invalid client cert match"] = []byte(`
+routes:
+  hello:
+    path: "/hi"
+    match:
+      client_cert:
+        "email":
+          value: "a@example.com"
+  `)
+	data["invalid empty auth"] = []byte(`
+routes:
+  hello:
+    path: "/hi"
+    auth:
+      realm: "orders"
+  `)
+	data["invalid basic auth without users"] = []byte(`
+routes:
+  hello:
+    path: "/hi"
+    auth:
+      basic: {}
+  `)
+	data["invalid basic and bearer auth"] = []byte(`
+routes:
+  hello:
+    path: "/hi"
+    auth:
+      basic:
+        users:
+          alice: "s3cret"
+      bearer:
+        tokens: ["static-token"]
+  `)
+	data["invalid bearer auth key file"] = []byte(`
+routes:
+  hello:
+    path: "/hi"
+    auth:
+      bearer:
+        jwt:
+          key_file: "/doesnotexist.pem"
+  `)
+	data["invalid api key auth without keys"] = []byte(`
+routes:
+  hello:
+    path: "/hi"
+    auth:
+      api_key:
+        header: "X-Key"
+  `)
 	for k, v := range data {
 		t.Run("Testing "+k, func(t *testing.T) {
 
@@ -109,3 +461,116 @@ func TestGenExampleFile(t *testing.T) {
 		t.Fatalf("Unexpected error reading example YAML - %s", err)
 	}
 }
+
+func TestCompiled(t *testing.T) {
+	m := Mocks{}
+	m.AddRoute("vars", Route{
+		Path:            "/vars",
+		ResponseHeaders: map[string]string{"x-id": "{{ param.id }}"},
+		Body:            "{{ header.x-test }}",
+		Chunks:          []Chunk{{Body: "{{ query.id }}"}},
+		SSE:             &SSE{Events: []SSEEvent{{ID: "{{ $guid }}", Data: "data"}}},
+	})
+	m.AddRoute("ws", Route{
+		Path: "/ws",
+		WebSocket: &WebSocket{
+			OnConnect: []WebSocketMessage{{Message: "{{ param.id }}"}},
+			Replies:   []WebSocketReply{{Messages: []WebSocketMessage{{Message: "{{ body }}"}}}},
+			Periodic:  []WebSocketPeriodic{{Message: "ping", Interval: time.Second}},
+		},
+	})
+	m.AddRoute("tmpl", Route{
+		Path:     "/tmpl",
+		Template: TemplateGo,
+		Body:     "{{ .Method }}",
+	})
+	m.AddRoute("invalid", Route{
+		Path:     "/invalid",
+		Template: TemplateGo,
+		Body:     "{{ if }}",
+	})
+
+	vars, ws := m.Routes["vars"], m.Routes["ws"].WebSocket
+	cases := map[string]struct {
+		tmpl  *variable.Template
+		value string
+	}{
+		"Body":              {tmpl: vars.CompiledBody(), value: "{{ header.x-test }}"},
+		"Header":            {tmpl: vars.CompiledHeader("x-id"), value: "{{ param.id }}"},
+		"Chunk":             {tmpl: vars.Chunks[0].CompiledBody(), value: "{{ query.id }}"},
+		"SSE ID":            {tmpl: vars.SSE.Events[0].CompiledID(), value: "{{ $guid }}"},
+		"SSE Data":          {tmpl: vars.SSE.Events[0].CompiledData(), value: "data"},
+		"WebSocket Connect": {tmpl: ws.OnConnect[0].CompiledMessage(), value: "{{ param.id }}"},
+		"WebSocket Reply":   {tmpl: ws.Replies[0].Messages[0].CompiledMessage(), value: "{{ body }}"},
+		"WebSocket Ping":    {tmpl: ws.Periodic[0].CompiledMessage(), value: "ping"},
+		"Go Template":       {tmpl: m.Routes["tmpl"].CompiledBody(), value: "{{ .Method }}"},
+	}
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			if v.tmpl == nil {
+				t.Fatalf("Expected %s to be compiled when the route was added", v.value)
+			}
+			if v.tmpl.String() != v.value {
+				t.Errorf("Unexpected compiled value %s", v.tmpl)
+			}
+		})
+	}
+
+	t.Run("Not Compiled", func(t *testing.T) {
+		r := Route{Body: "{{ query.id }}"}
+		if r.CompiledBody() != nil || r.CompiledHeader("x-id") != nil {
+			t.Errorf("Expected no templates for a route which was not added")
+		}
+		c, err := r.Compiled(r.Body)
+		if err != nil || c.String() != r.Body {
+			t.Errorf("Unexpected compiled value %s - %v", c, err)
+		}
+	})
+
+	t.Run("Invalid Template", func(t *testing.T) {
+		r := m.Routes["invalid"]
+		if r.CompiledBody() != nil {
+			t.Errorf("Expected invalid template to be left out")
+		}
+		_, err := r.Compiled(r.Body)
+		if err == nil {
+			t.Errorf("Expected error compiling %s, got nil", r.Body)
+		}
+	})
+}
+
+func TestOIDC(t *testing.T) {
+	fh, err := ioutil.TempFile("", "mocks_example")
+	if err != nil {
+		t.Fatalf("Error creating temp file - %s", err)
+	}
+	defer os.Remove(fh.Name())
+	_, _ = fh.WriteString(`
+oidc:
+  path: "/realms/test/"
+  claims:
+    address:
+      country: "NZ"
+  users:
+    bob: {}
+    alice: {}
+`)
+	fh.Close()
+
+	m, err := FromFile(fh.Name())
+	if err != nil {
+		t.Fatalf("Unexpected error loading mocks - %s", err)
+	}
+	if _, ok := m.OIDC.Claims["address"].(map[string]interface{}); !ok {
+		t.Errorf("Unexpected nested claim type %T", m.OIDC.Claims["address"])
+	}
+	if m.OIDC.DefaultUser() != "alice" {
+		t.Errorf("Unexpected default user %s", m.OIDC.DefaultUser())
+	}
+	if p := m.OIDC.Endpoint("discovery"); p != "/realms/test/.well-known/openid-configuration" {
+		t.Errorf("Unexpected discovery path %s", p)
+	}
+	if p := m.OIDC.Endpoint("token"); p != "/realms/test/oauth2/token" {
+		t.Errorf("Unexpected token path %s", p)
+	}
+}
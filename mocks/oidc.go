@@ -0,0 +1,160 @@
+package mocks
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OIDC is the config of the built-in OAuth 2.0 and OpenID Connect provider. When set
+// the discovery, authorize, token, userinfo and JWKS end-points are served.
+//
+// The below is a sample provider in YAML format.
+//
+//	oidc:
+//	  claims:
+//	    tenant: "acme"
+//	  clients:
+//	    orders-service:
+//	      secret: "s3cret"
+//	      scopes: ["orders:read", "orders:write"]
+//	    web:
+//	      redirect_uris: ["http://localhost:3000/callback"]
+//	  users:
+//	    alice:
+//	      claims:
+//	        email: "alice@example.com"
+//	        roles: ["admin"]
+type OIDC struct {
+	// Issuer is the iss claim and base URL of the provider. When empty the issuer is
+	// the scheme and host of each request followed by the Path.
+	Issuer string `yaml:"issuer"`
+
+	// Path is the prefix of the provider end-points, e.g. /realms/test.
+	Path string `yaml:"path"`
+
+	// TokenExpiry is the lifetime of access and ID tokens, defaults to an hour.
+	TokenExpiry time.Duration `yaml:"token_expiry"`
+
+	// Claims are added to every access and ID token.
+	Claims map[string]interface{} `yaml:"claims"`
+
+	// Clients is a map of client IDs to their config. When empty any client is
+	// accepted.
+	Clients map[string]OIDCClient `yaml:"clients"`
+
+	// Users is a map of usernames to their config. The user logged in by the authorize
+	// end-point is selected with the login_hint parameter, defaulting to the first user
+	// by name. When empty any user is accepted.
+	Users map[string]OIDCUser `yaml:"users"`
+}
+
+// OIDCClient is the config of an OAuth 2.0 client.
+type OIDCClient struct {
+	// Secret is the client secret. When empty the client is public and does not
+	// authenticate.
+	Secret string `yaml:"secret"`
+
+	// RedirectURIs are the allowed redirect URIs. When empty any URI is allowed.
+	RedirectURIs []string `yaml:"redirect_uris"`
+
+	// Scopes are the scopes the client may request. When empty any scope is allowed.
+	Scopes []string `yaml:"scopes"`
+
+	// Claims are added to access tokens issued to the client.
+	Claims map[string]interface{} `yaml:"claims"`
+}
+
+// OIDCUser is the config of a user of the provider.
+type OIDCUser struct {
+	// Claims are returned by the userinfo end-point and added to ID and access tokens.
+	// The sub claim defaults to the username.
+	Claims map[string]interface{} `yaml:"claims"`
+}
+
+// Endpoint returns the path of the named provider end-point, e.g. token.
+func (o *OIDC) Endpoint(name string) string {
+	p := strings.TrimSuffix(o.Path, "/")
+	if name == "discovery" {
+		return p + "/.well-known/openid-configuration"
+	}
+	return p + "/oauth2/" + name
+}
+
+// DefaultUser returns the name of the user logged in when no login_hint is given, this
+// is "user" when no users are defined.
+func (o *OIDC) DefaultUser() string {
+	names := make([]string, 0, len(o.Users))
+	for k := range o.Users {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return "user"
+	}
+	return names[0]
+}
+
+// validate will check the provider configuration, converting claims to JSON
+// compatible values.
+func (o *OIDC) validate() error {
+	o.Claims = jsonClaims(o.Claims)
+	for k, c := range o.Clients {
+		c.Claims = jsonClaims(c.Claims)
+		o.Clients[k] = c
+	}
+	for k, u := range o.Users {
+		u.Claims = jsonClaims(u.Claims)
+		o.Users[k] = u
+	}
+
+	if o.Issuer != "" {
+		u, err := url.Parse(o.Issuer)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid issuer %q, expected an absolute URL", o.Issuer)
+		}
+	}
+	if o.Path != "" && !strings.HasPrefix(o.Path, "/") {
+		return fmt.Errorf("invalid path %q, expected a leading /", o.Path)
+	}
+	if o.TokenExpiry < 0 {
+		return fmt.Errorf("token expiry must not be negative")
+	}
+	for k, c := range o.Clients {
+		for _, u := range c.RedirectURIs {
+			p, err := url.Parse(u)
+			if err != nil || p.Scheme == "" {
+				return fmt.Errorf("invalid redirect uri %q for client %s", u, k)
+			}
+		}
+	}
+	return nil
+}
+
+// jsonClaims converts the YAML decoded claims, whose nested maps use interface keys,
+// to values which can be encoded as JSON.
+func jsonClaims(claims map[string]interface{}) map[string]interface{} {
+	for k, v := range claims {
+		claims[k] = jsonValue(v)
+	}
+	return claims
+}
+
+// jsonValue converts nested YAML maps within v to string keyed maps.
+func jsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[fmt.Sprint(k)] = jsonValue(v)
+		}
+		return m
+	case []interface{}:
+		for i, v := range t {
+			t[i] = jsonValue(v)
+		}
+	}
+	return v
+}
@@ -0,0 +1,90 @@
+package mocks
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/madflojo/mockitout/variable"
+)
+
+// SSE defines a Server-Sent Events stream. The list of events is streamed to the
+// client, flushing after each event.
+//
+// The below is a sample Server-Sent Events route in YAML format.
+//
+//	routes:
+//	  prices:
+//	    path: "/prices"
+//	    sse:
+//	      loop: true
+//	      events:
+//	        - id: "{{ $guid }}"
+//	          event: "price"
+//	          data: '{"price": "{{ $randomPrice }}"}'
+//	          retry: 5000
+//	          delay: 1s
+type SSE struct {
+	// Events is the list of events to stream.
+	Events []SSEEvent `yaml:"events"`
+
+	// Loop will restart the list of events once the last event has been sent. The
+	// stream ends when the client disconnects.
+	Loop bool `yaml:"loop"`
+}
+
+// SSEEvent is a single Server-Sent Event. Variables are supported within the id,
+// event and data fields.
+type SSEEvent struct {
+	// ID is the event id.
+	ID string `yaml:"id"`
+
+	// Event is the event type.
+	Event string `yaml:"event"`
+
+	// Data is the event payload, multi-line values are sent as multiple data lines.
+	Data string `yaml:"data"`
+
+	// Retry is the client reconnection time in milliseconds.
+	Retry int `yaml:"retry"`
+
+	// Delay is the time to wait before sending the event.
+	Delay time.Duration `yaml:"delay"`
+
+	// id, event and data are the compiled ID, Event and Data templates.
+	id, event, data *variable.Template
+}
+
+// CompiledID will return the compiled ID template, nil is returned if the route was
+// not compiled when added.
+func (e SSEEvent) CompiledID() *variable.Template {
+	return e.id
+}
+
+// CompiledEvent will return the compiled Event template, nil is returned if the route
+// was not compiled when added.
+func (e SSEEvent) CompiledEvent() *variable.Template {
+	return e.event
+}
+
+// CompiledData will return the compiled Data template, nil is returned if the route
+// was not compiled when added.
+func (e SSEEvent) CompiledData() *variable.Template {
+	return e.data
+}
+
+// validate will check the Server-Sent Events configuration.
+func (s *SSE) validate() error {
+	if len(s.Events) < 1 {
+		return fmt.Errorf("no sse events defined")
+	}
+
+	if s.Loop {
+		for _, e := range s.Events {
+			if e.Delay > 0 {
+				return nil
+			}
+		}
+		return fmt.Errorf("looping sse events require at least one event delay")
+	}
+	return nil
+}
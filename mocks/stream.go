@@ -0,0 +1,80 @@
+package mocks
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/madflojo/mockitout/variable"
+)
+
+// Chunk is a fragment of the response body which is written and flushed to the
+// client separately. Variables are supported within the chunk body.
+//
+// The below is a sample chunked route in YAML format.
+//
+//	routes:
+//	  slow:
+//	    path: "/slow"
+//	    chunks:
+//	      - body: '{"items": ['
+//	      - body: '{"id": 1},'
+//	        delay: 500ms
+//	      - body: '{"id": 2}]}'
+//	        delay: 1s
+type Chunk struct {
+	// Body is the fragment of the response body.
+	Body string `yaml:"body"`
+
+	// Delay is the time to wait before writing the chunk.
+	Delay time.Duration `yaml:"delay"`
+
+	// body is the compiled Body template.
+	body *variable.Template
+}
+
+// CompiledBody will return the compiled Body template, nil is returned if the route
+// was not compiled when added.
+func (c Chunk) CompiledBody() *variable.Template {
+	return c.body
+}
+
+// Stream defines how the response body is streamed to the client. The body is
+// written in fixed size chunks, throttled to the defined rate.
+//
+// The below is a sample streamed route in YAML format.
+//
+//	routes:
+//	  download:
+//	    path: "/download"
+//	    stream:
+//	      size: 1024
+//	      rate: 4096
+//	    body: |
+//	      ...
+type Stream struct {
+	// Size is the number of bytes written and flushed per chunk.
+	Size int `yaml:"size"`
+
+	// Rate is the number of bytes written per second. When zero chunks are written
+	// without delay.
+	Rate int `yaml:"rate"`
+}
+
+// Delay will return the time to wait between each chunk to maintain the rate.
+func (s Stream) Delay() time.Duration {
+	if s.Rate <= 0 {
+		return 0
+	}
+	return time.Duration(s.Size) * time.Second / time.Duration(s.Rate)
+}
+
+// validate will check the stream configuration.
+func (s *Stream) validate() error {
+	if s.Size <= 0 {
+		return fmt.Errorf("stream size must be greater than zero")
+	}
+	if s.Rate < 0 {
+		return fmt.Errorf("stream rate must not be negative")
+	}
+	return nil
+}
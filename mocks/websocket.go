@@ -0,0 +1,179 @@
+package mocks
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/madflojo/mockitout/variable"
+)
+
+// WebSocket defines a scripted WebSocket exchange. After the connection is upgraded
+// the script is played, sending messages on connect, replying to incoming messages,
+// sending periodic messages and finally closing the connection.
+//
+// The below is a sample WebSocket route in YAML format.
+//
+//	routes:
+//	  notifications:
+//	    path: "/ws"
+//	    websocket:
+//	      on_connect:
+//	        - message: '{"type": "hello", "id": "{{ $guid }}"}'
+//	      replies:
+//	        - match: "^ping$"
+//	          messages:
+//	            - message: "pong"
+//	              delay: 100ms
+//	      periodic:
+//	        - interval: 5s
+//	          message: '{"type": "tick", "time": "{{ $timestamp }}"}'
+//	      close:
+//	        after: 1m
+//	        code: 1000
+//	        reason: "bye"
+type WebSocket struct {
+	// OnConnect is a list of messages sent once the connection is established.
+	OnConnect []WebSocketMessage `yaml:"on_connect"`
+
+	// Replies is a list of replies sent when incoming messages match a pattern. Only
+	// the first matching reply is used.
+	Replies []WebSocketReply `yaml:"replies"`
+
+	// Periodic is a list of messages sent repeatedly at a fixed interval.
+	Periodic []WebSocketPeriodic `yaml:"periodic"`
+
+	// Close defines when and how the server will close the connection. When not set
+	// the connection stays open until the client closes it.
+	Close *WebSocketClose `yaml:"close"`
+}
+
+// WebSocketMessage is a single message sent by the server.
+type WebSocketMessage struct {
+	// Message is the text payload of the message. Variables are supported.
+	Message string `yaml:"message"`
+
+	// Delay is the time to wait before sending the message.
+	Delay time.Duration `yaml:"delay"`
+
+	// message is the compiled Message template.
+	message *variable.Template
+}
+
+// CompiledMessage will return the compiled Message template, nil is returned if the
+// route was not compiled when added.
+func (m WebSocketMessage) CompiledMessage() *variable.Template {
+	return m.message
+}
+
+// WebSocketReply defines the messages sent in response to matching incoming messages.
+type WebSocketReply struct {
+	// Match is a regular expression matched against incoming messages. When empty all
+	// messages are matched.
+	Match string `yaml:"match"`
+
+	// Messages is the list of messages to reply with. Within these messages the
+	// incoming message is available via the body variables.
+	Messages []WebSocketMessage `yaml:"messages"`
+
+	// Close will close the connection after the reply messages have been sent.
+	Close *WebSocketClose `yaml:"close"`
+
+	// re is the compiled Match expression.
+	re *regexp.Regexp
+}
+
+// WebSocketPeriodic is a message sent repeatedly at a fixed interval.
+type WebSocketPeriodic struct {
+	// Interval is the time between each message.
+	Interval time.Duration `yaml:"interval"`
+
+	// Message is the text payload of the message. Variables are supported.
+	Message string `yaml:"message"`
+
+	// Count is the number of times to send the message, when zero the message is sent
+	// until the connection is closed.
+	Count int `yaml:"count"`
+
+	// message is the compiled Message template.
+	message *variable.Template
+}
+
+// CompiledMessage will return the compiled Message template, nil is returned if the
+// route was not compiled when added.
+func (p WebSocketPeriodic) CompiledMessage() *variable.Template {
+	return p.message
+}
+
+// WebSocketClose defines how the server closes the connection.
+type WebSocketClose struct {
+	// After is the time to wait before closing the connection.
+	After time.Duration `yaml:"after"`
+
+	// Code is the WebSocket close status code. Default is 1000 (normal closure).
+	Code int `yaml:"code"`
+
+	// Reason is the close reason sent to the client.
+	Reason string `yaml:"reason"`
+}
+
+// Matches will return true if the incoming message matches the reply.
+func (r WebSocketReply) Matches(msg []byte) bool {
+	if r.re == nil {
+		return r.Match == ""
+	}
+	return r.re.Match(msg)
+}
+
+// values returns the messages within the script which support variables.
+func (ws *WebSocket) values() []string {
+	var v []string
+	for _, m := range ws.OnConnect {
+		v = append(v, m.Message)
+	}
+	for _, r := range ws.Replies {
+		for _, m := range r.Messages {
+			v = append(v, m.Message)
+		}
+	}
+	for _, p := range ws.Periodic {
+		v = append(v, p.Message)
+	}
+	return v
+}
+
+// compile will store the compiled template of each message within the script.
+func (ws *WebSocket) compile(compile func(string) *variable.Template) {
+	for i := range ws.OnConnect {
+		ws.OnConnect[i].message = compile(ws.OnConnect[i].Message)
+	}
+	for i := range ws.Replies {
+		for j := range ws.Replies[i].Messages {
+			ws.Replies[i].Messages[j].message = compile(ws.Replies[i].Messages[j].Message)
+		}
+	}
+	for i := range ws.Periodic {
+		ws.Periodic[i].message = compile(ws.Periodic[i].Message)
+	}
+}
+
+// validate will check the WebSocket script and compile any expressions.
+func (ws *WebSocket) validate() error {
+	for i := range ws.Replies {
+		if ws.Replies[i].Match == "" {
+			continue
+		}
+		re, err := regexp.Compile(ws.Replies[i].Match)
+		if err != nil {
+			return fmt.Errorf("invalid websocket reply match %s - %s", ws.Replies[i].Match, err)
+		}
+		ws.Replies[i].re = re
+	}
+
+	for _, p := range ws.Periodic {
+		if p.Interval <= 0 {
+			return fmt.Errorf("websocket periodic messages must have an interval")
+		}
+	}
+	return nil
+}
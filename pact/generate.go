@@ -0,0 +1,414 @@
+package pact
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/madflojo/mockitout/mocks"
+)
+
+// SpecificationVersion is the Pact specification version used when generating contracts.
+const SpecificationVersion = "3.0.0"
+
+// pathParam matches named and wildcard parameters within a route path.
+var pathParam = regexp.MustCompile(`[:*][^/]+`)
+
+// FromMocks will generate a Pact contract from the routes within a Mocks
+// configuration. Interactions are ordered by route name.
+func FromMocks(m mocks.Mocks, consumer, provider string) Pact {
+	p := Pact{
+		Consumer: Pacticipant{Name: consumer},
+		Provider: Pacticipant{Name: provider},
+	}
+	p.Metadata.PactSpecification.Version = SpecificationVersion
+
+	names := make([]string, 0, len(m.Routes))
+	for k := range m.Routes {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		p.Interactions = append(p.Interactions, FromRoute(name, m.Routes[name]))
+	}
+	return p
+}
+
+// FromRoute will convert a mock route into a Pact interaction.
+func FromRoute(name string, r mocks.Route) Interaction {
+	n := Interaction{
+		Description: name,
+		Request: Request{
+			Method: strings.ToUpper(r.Method),
+			Path:   r.Path,
+		},
+		Response: Response{
+			Status: r.ReturnCode,
+		},
+	}
+	if n.Request.Method == "" {
+		n.Request.Method = "GET"
+	}
+	if n.Response.Status == 0 {
+		n.Response.Status = 200
+	}
+
+	for _, s := range r.ProviderStates {
+		n.ProviderStates = append(n.ProviderStates, ProviderState{Name: s})
+	}
+
+	reqRules := &MatchingRules{}
+
+	// Replace path parameters with example values
+	if pathParam.MatchString(r.Path) {
+		n.Request.Path = pathParam.ReplaceAllStringFunc(r.Path, func(p string) string {
+			return p[1:]
+		})
+		segments := strings.Split(r.Path, "/")
+		for i, seg := range segments {
+			switch {
+			case strings.HasPrefix(seg, ":"):
+				segments[i] = "[^/]+"
+			case strings.HasPrefix(seg, "*"):
+				segments[i] = ".*"
+			default:
+				segments[i] = regexp.QuoteMeta(seg)
+			}
+		}
+		reqRules.Path = &Rule{Matchers: []Matcher{{Match: "regex", Regex: "^" + strings.Join(segments, "/") + "$"}}}
+	} else if !r.Match.Path.IsZero() {
+		reqRules.Path = toRule(r.Match.Path)
+	}
+
+	for k, v := range r.Match.Query {
+		if n.Request.Query == nil {
+			n.Request.Query = make(map[string][]string)
+		}
+		if ex := exampleStrings(v); ex != nil {
+			n.Request.Query[k] = ex
+		}
+		if !isEquality(v) {
+			if reqRules.Query == nil {
+				reqRules.Query = make(map[string]Rule)
+			}
+			reqRules.Query[k] = *toRule(v)
+		}
+	}
+
+	for k, v := range r.Match.Headers {
+		if n.Request.Headers == nil {
+			n.Request.Headers = make(Headers)
+		}
+		if ex := exampleStrings(v); ex != nil {
+			n.Request.Headers[k] = strings.Join(ex, ", ")
+		}
+		if !isEquality(v) {
+			if reqRules.Header == nil {
+				reqRules.Header = make(map[string]Rule)
+			}
+			reqRules.Header[k] = *toRule(v)
+		}
+	}
+
+	if len(r.Match.Body) > 0 {
+		var body interface{}
+		reqRules.Body = make(map[string]Rule)
+		for k, v := range r.Match.Body {
+			if ex, ok := exampleValue(v); ok {
+				body = setExample(body, splitPath(k), ex)
+			}
+			if !isEquality(v) {
+				reqRules.Body[k] = *toRule(v)
+			}
+		}
+		if len(reqRules.Body) == 0 {
+			reqRules.Body = nil
+		}
+		n.Request.Body = &Body{Content: body}
+	}
+
+	if reqRules.Path != nil || reqRules.Query != nil || reqRules.Header != nil || reqRules.Body != nil {
+		n.Request.MatchingRules = reqRules
+	}
+
+	// Response values containing variables can only be verified by type
+	respRules := &MatchingRules{}
+	for k, v := range r.ResponseHeaders {
+		if n.Response.Headers == nil {
+			n.Response.Headers = make(Headers)
+		}
+		n.Response.Headers[k] = v
+		if strings.Contains(v, "{{") {
+			if respRules.Header == nil {
+				respRules.Header = make(map[string]Rule)
+			}
+			respRules.Header[k] = Rule{Matchers: []Matcher{{Match: "regex", Regex: ".*"}}}
+		}
+	}
+
+	if r.Body != "" {
+		var body interface{}
+		err := json.Unmarshal([]byte(r.Body), &body)
+		if err != nil {
+			body = r.Body
+		} else {
+			respRules.Body = make(map[string]Rule)
+			variableRules("$", body, respRules.Body)
+			if len(respRules.Body) == 0 {
+				respRules.Body = nil
+			}
+		}
+		n.Response.Body = &Body{Content: body}
+	}
+
+	if respRules.Header != nil || respRules.Body != nil {
+		n.Response.MatchingRules = respRules
+	}
+
+	return n
+}
+
+// WriteFile will write the Pact contract to the specified file path.
+func (p Pact) WriteFile(filepath string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode Pact - %s", err)
+	}
+
+	err = ioutil.WriteFile(filepath, data, 0644)
+	if err != nil {
+		return fmt.Errorf("could not write Pact file at %s - %s", filepath, err)
+	}
+	return nil
+}
+
+// isEquality will return true if the matcher is a simple equality match.
+func isEquality(m mocks.Matcher) bool {
+	return (m.Match == "" || m.Match == "equality") && len(m.All) == 0 && len(m.Any) == 0
+}
+
+// toRule will convert a mock matcher into a Pact matching rule.
+func toRule(m mocks.Matcher) *Rule {
+	switch {
+	case len(m.Any) > 0:
+		return &Rule{Matchers: toMatchers(m.Any), Combine: "OR"}
+	case len(m.All) > 0:
+		return &Rule{Matchers: toMatchers(m.All), Combine: "AND"}
+	}
+	return &Rule{Matchers: []Matcher{toMatcher(m)}}
+}
+
+// toMatchers will convert a list of mock matchers into Pact matchers.
+func toMatchers(list []mocks.Matcher) []Matcher {
+	out := make([]Matcher, len(list))
+	for i, m := range list {
+		out[i] = toMatcher(m)
+	}
+	return out
+}
+
+// toMatcher will convert a single mock matcher into a Pact matcher.
+func toMatcher(m mocks.Matcher) Matcher {
+	pm := Matcher{
+		Match: m.Match,
+		Regex: m.Regex,
+		Min:   m.Min,
+		Max:   m.Max,
+	}
+	if pm.Match == "" {
+		pm.Match = "equality"
+	}
+	if pm.Match == "include" {
+		pm.Value = m.Value
+	}
+	return pm
+}
+
+// exampleStrings will return example string values for a matcher, nil is returned
+// when no example matching a regex could be generated.
+func exampleStrings(m mocks.Matcher) []string {
+	if l, ok := m.Value.([]interface{}); ok {
+		vals := make([]string, len(l))
+		for i, v := range l {
+			vals[i] = fmt.Sprintf("%v", v)
+		}
+		return vals
+	}
+	if m.Value == nil {
+		if m.Match == "regex" {
+			ex, ok := regexExample(m.Regex)
+			if !ok {
+				return nil
+			}
+			return []string{ex}
+		}
+		return []string{""}
+	}
+	return []string{fmt.Sprintf("%v", m.Value)}
+}
+
+// exampleValue will return an example JSON value for a matcher, false is returned
+// when no example matching a regex could be generated.
+func exampleValue(m mocks.Matcher) (interface{}, bool) {
+	if m.Value != nil {
+		return jsonValue(m.Value), true
+	}
+	switch m.Match {
+	case "integer", "number":
+		return 1, true
+	case "decimal":
+		return 1.5, true
+	case "boolean":
+		return true, true
+	case "regex":
+		return regexExample(m.Regex)
+	}
+	return nil, true
+}
+
+// regexExample will generate a string matching the regex, false is returned when the
+// generated string does not match, e.g. for regexes using word boundaries.
+func regexExample(expr string) (string, bool) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return "", false
+	}
+	parsed, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	var b strings.Builder
+	writeExample(&b, parsed.Simplify())
+	return b.String(), re.MatchString(b.String())
+}
+
+// writeExample will write the shortest text matching the parsed regex, picking the
+// first alternative and a readable character of each class.
+func writeExample(b *strings.Builder, re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		b.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		b.WriteRune(classExample(re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		b.WriteRune('a')
+	case syntax.OpCapture, syntax.OpPlus, syntax.OpAlternate:
+		writeExample(b, re.Sub[0])
+	case syntax.OpRepeat:
+		for i := 0; i < re.Min; i++ {
+			writeExample(b, re.Sub[0])
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			writeExample(b, sub)
+		}
+	}
+}
+
+// classExample will return a character within the class ranges, preferring letters
+// and digits.
+func classExample(ranges []rune) rune {
+	in := func(c rune) bool {
+		for i := 0; i+1 < len(ranges); i += 2 {
+			if c >= ranges[i] && c <= ranges[i+1] {
+				return true
+			}
+		}
+		return false
+	}
+	for _, c := range "a0A-_." {
+		if in(c) {
+			return c
+		}
+	}
+	for i := 0; i+1 < len(ranges); i += 2 {
+		for c := ranges[i]; c <= ranges[i+1] && c < ranges[i]+128; c++ {
+			if unicode.IsPrint(c) {
+				return c
+			}
+		}
+	}
+	if len(ranges) == 0 {
+		return 'a'
+	}
+	return ranges[0]
+}
+
+// jsonValue will convert values decoded from YAML into values which can be encoded
+// as JSON.
+func jsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[fmt.Sprintf("%v", k)] = jsonValue(val)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(t))
+		for i, val := range t {
+			l[i] = jsonValue(val)
+		}
+		return l
+	}
+	return v
+}
+
+// setExample will place the value within the example document at the provided path
+// steps. Wildcard steps are treated as the first array element, values at negative
+// indexes are left out.
+func setExample(doc interface{}, steps []string, v interface{}) interface{} {
+	if len(steps) == 0 {
+		return v
+	}
+
+	step := steps[0]
+	i, err := strconv.Atoi(step)
+	if step == "*" {
+		i, err = 0, nil
+	}
+	if err == nil && i < 0 {
+		return doc
+	}
+	if err == nil {
+		l, _ := doc.([]interface{})
+		for len(l) <= i {
+			l = append(l, nil)
+		}
+		l[i] = setExample(l[i], steps[1:], v)
+		return l
+	}
+
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		m = make(map[string]interface{})
+	}
+	m[step] = setExample(m[step], steps[1:], v)
+	return m
+}
+
+// variableRules will add a type matching rule for every string value within the
+// response body which contains a variable.
+func variableRules(path string, v interface{}, out map[string]Rule) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			variableRules(path+childKey(k), val, out)
+		}
+	case []interface{}:
+		for i, val := range t {
+			variableRules(fmt.Sprintf("%s[%d]", path, i), val, out)
+		}
+	case string:
+		if strings.Contains(t, "{{") {
+			out[path] = Rule{Matchers: []Matcher{{Match: "type"}}}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package pact
+
+import (
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/madflojo/mockitout/mocks"
+)
+
+func TestFromMocks(t *testing.T) {
+	m := mocks.Mocks{}
+	m.AddRoute("hello", mocks.Route{
+		Path:            "/hi",
+		ResponseHeaders: map[string]string{"content-type": "application/json"},
+		Body:            `{"greeting": "Hello", "id": "{{ $guid }}"}`,
+	})
+	m.AddRoute("names", mocks.Route{
+		Path:       "/names/:id",
+		Method:     "post",
+		ReturnCode: 201,
+		Body:       "created",
+		Match: mocks.Match{
+			Headers: map[string]mocks.Matcher{"x-tenant": {Match: "regex", Regex: "^t-"}},
+			Body:    map[string]mocks.Matcher{"$.user.age": {Match: "integer"}, "$.user.name": {Value: "Andre"}},
+		},
+		ProviderStates: []string{"tenant exists"},
+	})
+	m.AddRoute("wild", mocks.Route{Path: "/files/*"})
+
+	p := FromMocks(m, "frontend", "backend")
+	if len(p.Interactions) != 3 {
+		t.Fatalf("Unexpected number of interactions - %d", len(p.Interactions))
+	}
+	if p.Consumer.Name != "frontend" || p.Provider.Name != "backend" {
+		t.Errorf("Unexpected pacticipants - %+v %+v", p.Consumer, p.Provider)
+	}
+
+	hello := p.Interactions[0]
+	if hello.Request.Method != "GET" || hello.Request.Path != "/hi" || hello.Response.Status != 200 {
+		t.Errorf("Unexpected interaction - %+v", hello)
+	}
+	if hello.Response.MatchingRules == nil || len(hello.Response.MatchingRules.Body) != 1 {
+		t.Errorf("Expected variable body value to have a type matcher - %+v", hello.Response.MatchingRules)
+	}
+
+	names := p.Interactions[1]
+	if names.Request.Method != "POST" || names.Request.Path != "/names/id" || names.Response.Status != 201 {
+		t.Errorf("Unexpected interaction - %+v", names)
+	}
+	if len(names.ProviderStates) != 1 {
+		t.Errorf("Unexpected provider states - %+v", names.ProviderStates)
+	}
+	if names.Request.MatchingRules == nil || names.Request.MatchingRules.Path == nil || names.Request.MatchingRules.Path.Matchers[0].Regex != "^/names/[^/]+$" {
+		t.Errorf("Unexpected path matching rule - %+v", names.Request.MatchingRules)
+	}
+
+	wild := p.Interactions[2]
+	if wild.Request.MatchingRules == nil || wild.Request.MatchingRules.Path.Matchers[0].Regex != "^/files/.*$" {
+		t.Errorf("Unexpected path matching rule - %+v", wild.Request.MatchingRules)
+	}
+
+	t.Run("Round trip", func(t *testing.T) {
+		f := writeTemp(t, "")
+		defer os.Remove(f)
+
+		err := p.WriteFile(f)
+		if err != nil {
+			t.Fatalf("Unexpected error writing Pact - %s", err)
+		}
+
+		n, err := FromFile(f)
+		if err != nil {
+			t.Fatalf("Unexpected error reading generated Pact - %s", err)
+		}
+
+		routes, err := n.Routes()
+		if err != nil {
+			t.Fatalf("Unexpected error converting generated Pact - %s", err)
+		}
+
+		r, _ := http.NewRequest("POST", "/names/id", strings.NewReader(`{"user": {"age": 99, "name": "Andre"}}`))
+		r.Header.Set("X-Tenant", "t-123")
+		if !routes["pact_frontend_backend_0001"].Matches(r) {
+			t.Errorf("Expected example request to match generated route - %+v", routes["pact_frontend_backend_0001"])
+		}
+	})
+
+	t.Run("Write to bad path", func(t *testing.T) {
+		err := p.WriteFile("/doesnotexist/pact.json")
+		if err == nil {
+			t.Errorf("Expected error writing Pact to invalid path")
+		}
+	})
+}
+
+func TestExamples(t *testing.T) {
+	// Routes are set directly as AddRoute rejects the negative index
+	m := mocks.Mocks{Routes: map[string]mocks.Route{}}
+	m.Routes["regex"] = mocks.Route{
+		Path: "/orders",
+		Match: mocks.Match{
+			Headers: map[string]mocks.Matcher{"x-tenant": {Match: "regex", Regex: `^t-[0-9a-f]{4}$`}},
+			Query:   map[string]mocks.Matcher{"type": {Match: "regex", Regex: `^(retail|wholesale)$`}},
+			Body: map[string]mocks.Matcher{
+				"$.code":       {Match: "regex", Regex: `^[A-Z]{2}-\d+$`},
+				"$.items[1]":   {Match: "integer"},
+				"$.items[-1]":  {Match: "integer"},
+				"$.ref['1ab']": {Value: "x"},
+				"$.word":       {Match: "regex", Regex: `\bx\b\B`},
+			},
+		},
+	}
+
+	p := FromMocks(m, "frontend", "backend")
+	req := p.Interactions[0].Request
+	if ok, _ := regexp.MatchString(`^t-[0-9a-f]{4}$`, req.Headers["x-tenant"]); !ok {
+		t.Errorf("Header example %q does not match the regex", req.Headers["x-tenant"])
+	}
+	if q := req.Query["type"]; len(q) != 1 || q[0] != "retail" {
+		t.Errorf("Unexpected query example %v", q)
+	}
+
+	body, _ := req.Body.Content.(map[string]interface{})
+	if code, _ := body["code"].(string); !regexp.MustCompile(`^[A-Z]{2}-\d+$`).MatchString(code) {
+		t.Errorf("Body example %q does not match the regex", code)
+	}
+	if _, ok := body["word"]; ok {
+		t.Errorf("Expected body example to be left out when no match can be generated - %+v", body)
+	}
+	if items, _ := body["items"].([]interface{}); len(items) != 2 || items[1] != 1 {
+		t.Errorf("Unexpected items example %+v", body["items"])
+	}
+	if ref, _ := body["ref"].(map[string]interface{}); ref["1ab"] != "x" {
+		t.Errorf("Unexpected ref example %+v, keys starting with digits are not indexes", body["ref"])
+	}
+}
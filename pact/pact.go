@@ -0,0 +1,621 @@
+/*
+Package pact is used to provide support for Pact contract files. Pact v3 and v4
+contracts can be read and converted into mock routes, allowing consumer contract tests
+to run against MockItOut. Pact contracts can also be generated from a Mocks
+configuration so that providers can verify the mocked behavior.
+*/
+package pact
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/madflojo/mockitout/mocks"
+)
+
+// Pact defines the structure of a Pact contract file.
+type Pact struct {
+	// Consumer is the consuming service of the contract.
+	Consumer Pacticipant `json:"consumer"`
+
+	// Provider is the providing service of the contract.
+	Provider Pacticipant `json:"provider"`
+
+	// Interactions is the list of request and response pairs within the contract.
+	Interactions []Interaction `json:"interactions"`
+
+	// Metadata holds the Pact specification details.
+	Metadata Metadata `json:"metadata"`
+}
+
+// Pacticipant is a named participant of a Pact contract.
+type Pacticipant struct {
+	// Name is the name of the service.
+	Name string `json:"name"`
+}
+
+// Metadata holds details about the Pact contract file.
+type Metadata struct {
+	// PactSpecification holds the specification version of the contract.
+	PactSpecification Specification `json:"pactSpecification"`
+}
+
+// Specification holds the Pact specification version.
+type Specification struct {
+	// Version is the Pact specification version, e.g. 3.0.0.
+	Version string `json:"version"`
+}
+
+// Interaction is a single request and response pair within a Pact contract.
+type Interaction struct {
+	// Type is the interaction type, this is only set by v4 contracts.
+	Type string `json:"type,omitempty"`
+
+	// Description is a human readable description of the interaction.
+	Description string `json:"description"`
+
+	// ProviderStates is a list of states the provider should be in for this
+	// interaction.
+	ProviderStates []ProviderState `json:"providerStates,omitempty"`
+
+	// Request is the expected HTTP request.
+	Request Request `json:"request"`
+
+	// Response is the HTTP response to return.
+	Response Response `json:"response"`
+}
+
+// ProviderState is a named state the provider must be in for an interaction.
+type ProviderState struct {
+	// Name is the name of the provider state.
+	Name string `json:"name"`
+
+	// Params are optional parameters for the provider state.
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// Request is the expected HTTP request of an interaction.
+type Request struct {
+	// Method is the HTTP method of the request.
+	Method string `json:"method"`
+
+	// Path is the URI path of the request.
+	Path string `json:"path"`
+
+	// Query is a map of query parameters and their values.
+	Query map[string][]string `json:"query,omitempty"`
+
+	// Headers is a map of HTTP headers and their values.
+	Headers Headers `json:"headers,omitempty"`
+
+	// Body is the request body.
+	Body *Body `json:"body,omitempty"`
+
+	// MatchingRules are the rules used to match requests against the interaction.
+	MatchingRules *MatchingRules `json:"matchingRules,omitempty"`
+}
+
+// Response is the HTTP response of an interaction.
+type Response struct {
+	// Status is the HTTP status code of the response.
+	Status int `json:"status"`
+
+	// Headers is a map of HTTP headers and their values.
+	Headers Headers `json:"headers,omitempty"`
+
+	// Body is the response body.
+	Body *Body `json:"body,omitempty"`
+
+	// MatchingRules are the rules used by providers to verify the response.
+	MatchingRules *MatchingRules `json:"matchingRules,omitempty"`
+}
+
+// Headers is a map of HTTP headers. Pact v3 contracts store header values as strings
+// while v4 contracts store them as lists, both are supported.
+type Headers map[string]string
+
+// UnmarshalJSON will decode either string or list header values.
+func (h *Headers) UnmarshalJSON(b []byte) error {
+	raw := make(map[string]interface{})
+	err := json.Unmarshal(b, &raw)
+	if err != nil {
+		return err
+	}
+	*h = make(Headers)
+	for k, v := range raw {
+		switch t := v.(type) {
+		case string:
+			(*h)[k] = t
+		case []interface{}:
+			var vals []string
+			for _, val := range t {
+				vals = append(vals, fmt.Sprintf("%v", val))
+			}
+			(*h)[k] = strings.Join(vals, ", ")
+		default:
+			return fmt.Errorf("invalid value for header %s", k)
+		}
+	}
+	return nil
+}
+
+// Body is the body of a request or response. Pact v3 contracts store bodies as raw
+// JSON values while v4 contracts wrap them with content details, both are supported.
+type Body struct {
+	// Content is the decoded body content.
+	Content interface{}
+
+	// ContentType is the body content type when provided by a v4 contract.
+	ContentType string
+}
+
+// UnmarshalJSON will decode either a raw v3 body or a v4 body structure.
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+
+	if m, ok := v.(map[string]interface{}); ok {
+		if content, ok := m["content"]; ok {
+			if ct, ok := m["contentType"].(string); ok {
+				b.ContentType = ct
+			}
+			if encoded, ok := m["encoded"]; ok && encoded != false {
+				return fmt.Errorf("encoded body content is not supported")
+			}
+			b.Content = content
+			return nil
+		}
+	}
+	b.Content = v
+	return nil
+}
+
+// MarshalJSON will encode the body as a raw v3 body.
+func (b Body) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.Content)
+}
+
+// String will return the body content as a string.
+func (b Body) String() string {
+	if b.Content == nil {
+		return ""
+	}
+	if s, ok := b.Content.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(b.Content)
+	if err != nil {
+		return fmt.Sprintf("%v", b.Content)
+	}
+	return string(data)
+}
+
+// MatchingRules is the collection of request matching rules by category.
+type MatchingRules struct {
+	// Path holds the matchers for the request path.
+	Path *Rule `json:"path,omitempty"`
+
+	// Query holds the matchers for each query parameter.
+	Query map[string]Rule `json:"query,omitempty"`
+
+	// Header holds the matchers for each HTTP header.
+	Header map[string]Rule `json:"header,omitempty"`
+
+	// Body holds the matchers for each JSON path of the body.
+	Body map[string]Rule `json:"body,omitempty"`
+}
+
+// Rule is a list of matchers applied to a single value.
+type Rule struct {
+	// Matchers is the list of matchers to apply.
+	Matchers []Matcher `json:"matchers"`
+
+	// Combine defines how matchers are combined, either AND (default) or OR.
+	Combine string `json:"combine,omitempty"`
+}
+
+// Matcher is a single Pact matcher.
+type Matcher struct {
+	// Match is the type of match, e.g. regex, type, integer...
+	Match string `json:"match"`
+
+	// Regex is the regular expression for regex matches.
+	Regex string `json:"regex,omitempty"`
+
+	// Value is the expected value for include matches.
+	Value interface{} `json:"value,omitempty"`
+
+	// Min is the minimum array length for type matches.
+	Min int `json:"min,omitempty"`
+
+	// Max is the maximum array length for type matches.
+	Max int `json:"max,omitempty"`
+}
+
+// FromFile will read the Pact contract from the specified file path.
+func FromFile(filepath string) (Pact, error) {
+	var p Pact
+
+	c, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return p, fmt.Errorf("could not read Pact file at %s - %s", filepath, err)
+	}
+
+	err = json.Unmarshal(c, &p)
+	if err != nil {
+		return p, fmt.Errorf("error parsing Pact file - %s", err)
+	}
+
+	if !strings.HasPrefix(p.Metadata.PactSpecification.Version, "3") && !strings.HasPrefix(p.Metadata.PactSpecification.Version, "4") {
+		return p, fmt.Errorf("unsupported Pact specification version %q", p.Metadata.PactSpecification.Version)
+	}
+
+	if len(p.Interactions) < 1 {
+		return p, fmt.Errorf("no interactions defined in Pact file")
+	}
+
+	return p, nil
+}
+
+// Routes will convert each HTTP interaction within the Pact contract into a mock
+// route. Routes are named using the consumer and provider names and the interaction
+// index, e.g. pact_frontend_users_0000.
+func (p Pact) Routes() (map[string]mocks.Route, error) {
+	routes := make(map[string]mocks.Route)
+	for i, n := range p.Interactions {
+		if n.Type != "" && n.Type != "Synchronous/HTTP" {
+			continue
+		}
+
+		r, err := n.Route()
+		if err != nil {
+			return routes, fmt.Errorf("could not convert interaction %q - %s", n.Description, err)
+		}
+		routes[fmt.Sprintf("pact_%s_%s_%04d", p.Consumer.Name, p.Provider.Name, i)] = r
+	}
+	return routes, nil
+}
+
+// Route will convert the interaction into a mock route.
+func (n Interaction) Route() (mocks.Route, error) {
+	r := mocks.Route{
+		Path:            n.Request.Path,
+		Method:          strings.ToUpper(n.Request.Method),
+		ReturnCode:      n.Response.Status,
+		ResponseHeaders: map[string]string(n.Response.Headers),
+		Match: mocks.Match{
+			Headers: make(map[string]mocks.Matcher),
+			Query:   make(map[string]mocks.Matcher),
+			Body:    make(map[string]mocks.Matcher),
+		},
+	}
+
+	if r.Path == "" {
+		return r, fmt.Errorf("request path is not defined")
+	}
+	if r.Path != "/" && strings.HasSuffix(r.Path, "/") {
+		r.Path = strings.TrimSuffix(r.Path, "/")
+	}
+	if r.Method == "" {
+		r.Method = "GET"
+	}
+
+	if n.Response.Body != nil {
+		r.Body = n.Response.Body.String()
+	}
+
+	for _, s := range n.ProviderStates {
+		r.ProviderStates = append(r.ProviderStates, s.Name)
+	}
+
+	var rules MatchingRules
+	if n.Request.MatchingRules != nil {
+		rules = *n.Request.MatchingRules
+	}
+	if rules.Path != nil {
+		m, err := rules.Path.matcher(nil)
+		if err != nil {
+			return r, fmt.Errorf("invalid path matching rule - %s", err)
+		}
+		r.Path, err = routePath(r.Path, m)
+		if err != nil {
+			return r, fmt.Errorf("invalid path matching rule - %s", err)
+		}
+		r.Match.Path = m
+	}
+
+	for k, v := range n.Request.Query {
+		vals := make([]interface{}, len(v))
+		for i, val := range v {
+			vals[i] = val
+		}
+		r.Match.Query[k] = mocks.Matcher{Value: vals}
+	}
+	for k, v := range rules.Query {
+		var example interface{}
+		if vals, ok := n.Request.Query[k]; ok && len(vals) > 0 {
+			example = vals[0]
+		}
+		m, err := v.matcher(example)
+		if err != nil {
+			return r, fmt.Errorf("invalid query matching rule for %s - %s", k, err)
+		}
+		r.Match.Query[k] = m
+	}
+
+	for k, v := range n.Request.Headers {
+		r.Match.Headers[k] = mocks.Matcher{Value: v}
+	}
+	for k, v := range rules.Header {
+		example, ok := n.Request.Headers[k]
+		if !ok {
+			example = ""
+		}
+		m, err := v.matcher(example)
+		if err != nil {
+			return r, fmt.Errorf("invalid header matching rule for %s - %s", k, err)
+		}
+		for h := range r.Match.Headers {
+			if strings.EqualFold(h, k) {
+				delete(r.Match.Headers, h)
+			}
+		}
+		r.Match.Headers[k] = m
+	}
+
+	if n.Request.Body != nil && n.Request.Body.Content != nil {
+		for k, v := range rules.Body {
+			m, err := v.matcher(nil)
+			if err != nil {
+				return r, fmt.Errorf("invalid body matching rule for %s - %s", k, err)
+			}
+			if m.Match == "type" && m.Value == nil {
+				m.Value = lookup(n.Request.Body.Content, k)
+			}
+			r.Match.Body[k] = m
+		}
+		flatten("$", n.Request.Body.Content, rules.Body, r.Match.Body)
+	}
+
+	err := r.Match.Compile()
+	if err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// routePath will convert the example path of a path matching rule into the path a
+// route is registered at, so that every request path matched by the rule reaches the
+// route. Segments after the literal prefix of a regex become parameters, or a catch-all
+// when the regex also matches deeper paths. Only regex and equality rules are supported.
+func routePath(example string, m mocks.Matcher) (string, error) {
+	switch {
+	case m.Match == "equality" && len(m.All) == 0 && len(m.Any) == 0:
+		return example, nil
+	case m.Match != "regex":
+		return "", fmt.Errorf("only a single regex or equality matcher is supported for paths")
+	}
+
+	re, err := regexp.Compile(m.Regex)
+	if err != nil {
+		return "", fmt.Errorf("invalid regex %q - %s", m.Regex, err)
+	}
+	if !re.MatchString(example) {
+		return "", fmt.Errorf("example path %s does not match regex %q", example, m.Regex)
+	}
+
+	// keep the whole segments of the literal prefix shared with the example
+	prefix := literalPrefix(m.Regex)
+	fixed := prefix[:strings.LastIndex(prefix, "/")+1]
+	if fixed == "" {
+		fixed = "/"
+	}
+	if fixed == example || !strings.HasPrefix(example, fixed) {
+		return example, nil
+	}
+
+	// paths with extra segments reaching the regex need a catch-all
+	rest := strings.TrimPrefix(example, fixed)
+	if re.MatchString(fixed+"pact/"+rest) || re.MatchString(strings.TrimSuffix(example, "/")+"/pact") {
+		return fixed + "*pact_path", nil
+	}
+	segments := strings.Split(rest, "/")
+	for i, s := range segments {
+		if s != "" {
+			segments[i] = fmt.Sprintf(":pact_path_%d", i+1)
+		}
+	}
+	return fixed + strings.Join(segments, "/"), nil
+}
+
+// literalPrefix returns the literal text an anchored regex starts with, or an empty
+// string when the regex is not anchored to the start.
+func literalPrefix(expr string) string {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil || re.Op != syntax.OpConcat {
+		return ""
+	}
+	var prefix []rune
+	for i, sub := range re.Sub {
+		switch {
+		case i == 0 && (sub.Op == syntax.OpBeginText || sub.Op == syntax.OpBeginLine):
+		case i == 0:
+			return ""
+		case sub.Op == syntax.OpLiteral && sub.Flags&syntax.FoldCase == 0:
+			prefix = append(prefix, sub.Rune...)
+		default:
+			return string(prefix)
+		}
+	}
+	return string(prefix)
+}
+
+// matcher will convert a Pact matching rule into a mock matcher. Rules with many
+// matchers are combined with AND (the default) or OR.
+func (r Rule) matcher(example interface{}) (mocks.Matcher, error) {
+	var m mocks.Matcher
+	if len(r.Matchers) < 1 {
+		return m, fmt.Errorf("no matchers defined")
+	}
+	combine := strings.ToUpper(r.Combine)
+	if combine != "" && combine != "AND" && combine != "OR" {
+		return m, fmt.Errorf("matcher combine %s is not supported", r.Combine)
+	}
+
+	list := make([]mocks.Matcher, 0, len(r.Matchers))
+	for _, pm := range r.Matchers {
+		c, err := pm.matcher(example)
+		if err != nil {
+			return m, err
+		}
+		list = append(list, c)
+	}
+	switch {
+	case len(list) == 1:
+		return list[0], nil
+	case combine == "OR":
+		return mocks.Matcher{Any: list}, nil
+	}
+	return mocks.Matcher{All: list}, nil
+}
+
+// matcher will convert a single Pact matcher into a mock matcher.
+func (pm Matcher) matcher(example interface{}) (mocks.Matcher, error) {
+	var m mocks.Matcher
+	switch pm.Match {
+	case "equality", "regex", "type", "include", "integer", "decimal", "number", "boolean", "null":
+	default:
+		return m, fmt.Errorf("matcher type %q is not supported", pm.Match)
+	}
+
+	m = mocks.Matcher{
+		Match: pm.Match,
+		Regex: pm.Regex,
+		Value: pm.Value,
+		Min:   pm.Min,
+		Max:   pm.Max,
+	}
+	if m.Value == nil && m.Match == "equality" {
+		m.Value = example
+	}
+	return m, nil
+}
+
+// flatten will add an equality matcher for every leaf value within the example body
+// which is not already covered by a matching rule.
+func flatten(path string, v interface{}, rules map[string]Rule, out map[string]mocks.Matcher) {
+	if covered(path, rules) {
+		return
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			flatten(path+childKey(k), t[k], rules, out)
+		}
+	case []interface{}:
+		for i, val := range t {
+			flatten(fmt.Sprintf("%s[%d]", path, i), val, rules, out)
+		}
+	default:
+		out[path] = mocks.Matcher{Value: v}
+	}
+}
+
+// childKey will return the JSON path step for an object key.
+func childKey(k string) string {
+	if strings.ContainsAny(k, ".[]'* ") {
+		return "['" + k + "']"
+	}
+	return "." + k
+}
+
+// covered will return true if the path, or one of its parents, has a matching rule.
+func covered(path string, rules map[string]Rule) bool {
+	steps := splitPath(path)
+	for k := range rules {
+		rule := splitPath(k)
+		if len(rule) > len(steps) {
+			continue
+		}
+		match := true
+		for i := range rule {
+			if rule[i] != steps[i] && rule[i] != "*" {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPath will split a JSON path into its individual steps.
+func splitPath(path string) []string {
+	path = strings.TrimPrefix(path, "$")
+	var steps []string
+	for len(path) > 0 {
+		switch {
+		case path[0] == '.':
+			path = path[1:]
+			end := strings.IndexAny(path, ".[")
+			if end == -1 {
+				end = len(path)
+			}
+			steps = append(steps, path[:end])
+			path = path[end:]
+		case path[0] == '[':
+			end := strings.Index(path, "]")
+			if end == -1 {
+				return append(steps, path)
+			}
+			steps = append(steps, strings.Trim(path[1:end], `'"`))
+			path = path[end+1:]
+		default:
+			return append(steps, path)
+		}
+	}
+	return steps
+}
+
+// lookup will return the value within the example body at the provided path.
+func lookup(v interface{}, path string) interface{} {
+	for _, step := range splitPath(path) {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			v = t[step]
+		case []interface{}:
+			if step == "*" {
+				if len(t) == 0 {
+					return nil
+				}
+				v = t[0]
+				continue
+			}
+			i, err := strconv.Atoi(step)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil
+			}
+			v = t[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
@@ -0,0 +1,337 @@
+package pact
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+const v3Pact = `{
+  "consumer": {"name": "frontend"},
+  "provider": {"name": "users"},
+  "interactions": [
+    {
+      "description": "get an existing user",
+      "providerStates": [{"name": "user exists"}],
+      "request": {
+        "method": "GET",
+        "path": "/users/1",
+        "query": {"verbose": ["true"]},
+        "headers": {"Accept": "application/json"}
+      },
+      "response": {
+        "status": 200,
+        "headers": {"Content-Type": "application/json"},
+        "body": {"id": 1, "name": "Andre"}
+      }
+    },
+    {
+      "description": "create a user",
+      "request": {
+        "method": "POST",
+        "path": "/users",
+        "body": {"name": "Andre", "age": 30, "tags": ["a", "b"]},
+        "matchingRules": {
+          "body": {
+            "$.age": {"matchers": [{"match": "integer"}]},
+            "$.tags": {"matchers": [{"match": "type", "min": 1}]}
+          },
+          "header": {
+            "Content-Type": {"matchers": [{"match": "regex", "regex": "^application/json"}]}
+          }
+        }
+      },
+      "response": {
+        "status": 201,
+        "body": "created"
+      }
+    }
+  ],
+  "metadata": {"pactSpecification": {"version": "3.0.0"}}
+}`
+
+const v4Pact = `{
+  "consumer": {"name": "frontend"},
+  "provider": {"name": "orders"},
+  "interactions": [
+    {
+      "type": "Synchronous/HTTP",
+      "description": "list orders",
+      "request": {
+        "method": "GET",
+        "path": "/orders",
+        "headers": {"Accept": ["application/json"]}
+      },
+      "response": {
+        "status": 200,
+        "headers": {"Content-Type": ["application/json"]},
+        "body": {"content": [{"id": 1}], "contentType": "application/json", "encoded": false}
+      }
+    },
+    {
+      "type": "Asynchronous/Messages",
+      "description": "an order event"
+    }
+  ],
+  "metadata": {"pactSpecification": {"version": "4.0"}}
+}`
+
+// writeTemp will write the data to a temporary file returning its name.
+func writeTemp(t *testing.T, data string) string {
+	fh, err := ioutil.TempFile("", "pact_example")
+	if err != nil {
+		t.Fatalf("Error creating temp file - %s", err)
+	}
+	_, err = fh.Write([]byte(data))
+	if err != nil {
+		t.Fatalf("Error writing temp file data - %s", err)
+	}
+	err = fh.Close()
+	if err != nil {
+		t.Fatalf("Error closing temp file - %s", err)
+	}
+	return fh.Name()
+}
+
+func TestFromFile(t *testing.T) {
+	valid := map[string]string{"v3": v3Pact, "v4": v4Pact}
+	for k, v := range valid {
+		t.Run("Testing "+k, func(t *testing.T) {
+			f := writeTemp(t, v)
+			defer os.Remove(f)
+
+			_, err := FromFile(f)
+			if err != nil {
+				t.Fatalf("Unexpected failure when loading valid Pact - %s", err)
+			}
+		})
+	}
+
+	invalid := map[string]string{
+		"invalid json":     `{"consumer":`,
+		"v2 pact":          `{"interactions": [{"request": {"path": "/"}}], "metadata": {"pactSpecification": {"version": "2.0.0"}}}`,
+		"no interactions":  `{"interactions": [], "metadata": {"pactSpecification": {"version": "3.0.0"}}}`,
+		"encoded v4 body":  `{"interactions": [{"request": {"path": "/", "body": {"content": "aGk=", "encoded": "base64"}}}], "metadata": {"pactSpecification": {"version": "4.0"}}}`,
+		"bad header value": `{"interactions": [{"request": {"path": "/", "headers": {"a": 1}}}], "metadata": {"pactSpecification": {"version": "3.0.0"}}}`,
+	}
+	for k, v := range invalid {
+		t.Run("Testing "+k, func(t *testing.T) {
+			f := writeTemp(t, v)
+			defer os.Remove(f)
+
+			_, err := FromFile(f)
+			if err == nil {
+				t.Fatalf("Expected failure when loading invalid Pact, got nil")
+			}
+		})
+	}
+
+	t.Run("Testing with no file", func(t *testing.T) {
+		_, err := FromFile("thisfilewillneverexistoratleastiwillalwaysthinkso")
+		if err == nil {
+			t.Fatalf("Expected failure when loading missing file, got nil")
+		}
+	})
+}
+
+func TestRoutes(t *testing.T) {
+	f := writeTemp(t, v3Pact)
+	defer os.Remove(f)
+
+	p, err := FromFile(f)
+	if err != nil {
+		t.Fatalf("Unexpected failure when loading valid Pact - %s", err)
+	}
+
+	routes, err := p.Routes()
+	if err != nil {
+		t.Fatalf("Unexpected failure converting Pact - %s", err)
+	}
+	if len(routes) != 2 {
+		t.Fatalf("Unexpected number of routes - %d", len(routes))
+	}
+
+	get, ok := routes["pact_frontend_users_0000"]
+	if !ok {
+		t.Fatalf("Could not find expected route - %+v", routes)
+	}
+	if get.Method != "GET" || get.ReturnCode != 200 || get.Body != `{"id":1,"name":"Andre"}` {
+		t.Errorf("Unexpected route values - %+v", get)
+	}
+	if len(get.ProviderStates) != 1 || get.ProviderStates[0] != "user exists" {
+		t.Errorf("Unexpected provider states - %+v", get.ProviderStates)
+	}
+
+	t.Run("Matching GET request", func(t *testing.T) {
+		r, _ := http.NewRequest("GET", "/users/1?verbose=true", nil)
+		r.Header.Set("Accept", "application/json")
+		if !get.Matches(r) {
+			t.Errorf("Expected request to match")
+		}
+	})
+
+	t.Run("Missing query", func(t *testing.T) {
+		r, _ := http.NewRequest("GET", "/users/1", nil)
+		r.Header.Set("Accept", "application/json")
+		if get.Matches(r) {
+			t.Errorf("Expected request to not match")
+		}
+	})
+
+	post := routes["pact_frontend_users_0001"]
+	requests := map[string]struct {
+		body string
+		ct   string
+		pass bool
+	}{
+		"example body":    {`{"name": "Andre", "age": 30, "tags": ["a", "b"]}`, "application/json", true},
+		"matched by rule": {`{"name": "Andre", "age": 45, "tags": ["c"]}`, "application/json; charset=utf-8", true},
+		"wrong name":      {`{"name": "Jim", "age": 30, "tags": ["a"]}`, "application/json", false},
+		"age not integer": {`{"name": "Andre", "age": 30.5, "tags": ["a"]}`, "application/json", false},
+		"empty tags":      {`{"name": "Andre", "age": 30, "tags": []}`, "application/json", false},
+		"wrong content":   {`{"name": "Andre", "age": 30, "tags": ["a"]}`, "text/plain", false},
+	}
+	for k, v := range requests {
+		t.Run("POST with "+k, func(t *testing.T) {
+			r, _ := http.NewRequest("POST", "/users", strings.NewReader(v.body))
+			r.Header.Set("Content-Type", v.ct)
+			if post.Matches(r) != v.pass {
+				t.Errorf("Unexpected match result, expected %t", v.pass)
+			}
+		})
+	}
+}
+
+func TestV4Routes(t *testing.T) {
+	f := writeTemp(t, v4Pact)
+	defer os.Remove(f)
+
+	p, err := FromFile(f)
+	if err != nil {
+		t.Fatalf("Unexpected failure when loading valid Pact - %s", err)
+	}
+
+	routes, err := p.Routes()
+	if err != nil {
+		t.Fatalf("Unexpected failure converting Pact - %s", err)
+	}
+	if len(routes) != 1 {
+		t.Fatalf("Unexpected number of routes, message interactions should be skipped - %d", len(routes))
+	}
+
+	r := routes["pact_frontend_orders_0000"]
+	if r.Body != `[{"id":1}]` || r.ResponseHeaders["Content-Type"] != "application/json" {
+		t.Errorf("Unexpected route values - %+v", r)
+	}
+}
+
+const rulesPact = `{
+  "consumer": {"name": "frontend"},
+  "provider": {"name": "users"},
+  "interactions": [
+    {
+      "description": "get a user",
+      "request": {
+        "method": "GET",
+        "path": "/users/1",
+        "headers": {"Accept": "application/json"},
+        "matchingRules": {
+          "path": {"matchers": [{"match": "regex", "regex": "^/users/[0-9]+$"}]},
+          "header": {
+            "Accept": {"combine": "OR", "matchers": [
+              {"match": "regex", "regex": "^application/json"},
+              {"match": "equality", "value": "*/*"}
+            ]}
+          }
+        }
+      },
+      "response": {"status": 200}
+    },
+    {
+      "description": "get a file",
+      "request": {
+        "method": "GET",
+        "path": "/files/docs/a.txt",
+        "matchingRules": {
+          "path": {"matchers": [{"match": "regex", "regex": "^/files/.*\\.txt$"}]}
+        }
+      },
+      "response": {"status": 200}
+    }
+  ],
+  "metadata": {"pactSpecification": {"version": "3.0.0"}}
+}`
+
+func TestMatchingRules(t *testing.T) {
+	f := writeTemp(t, rulesPact)
+	defer os.Remove(f)
+
+	p, err := FromFile(f)
+	if err != nil {
+		t.Fatalf("Unexpected failure when loading valid Pact - %s", err)
+	}
+	routes, err := p.Routes()
+	if err != nil {
+		t.Fatalf("Unexpected failure converting Pact - %s", err)
+	}
+
+	user := routes["pact_frontend_users_0000"]
+	if user.Path != "/users/:pact_path_1" {
+		t.Errorf("Unexpected route path for regex path rule - %s", user.Path)
+	}
+	file := routes["pact_frontend_users_0001"]
+	if file.Path != "/files/*pact_path" {
+		t.Errorf("Unexpected route path for regex path rule - %s", file.Path)
+	}
+
+	requests := map[string]struct {
+		route  string
+		path   string
+		accept string
+		pass   bool
+	}{
+		"example path":          {"pact_frontend_users_0000", "/users/1", "application/json", true},
+		"path matched by rule":  {"pact_frontend_users_0000", "/users/42", "application/json", true},
+		"path not matched":      {"pact_frontend_users_0000", "/users/abc", "application/json", false},
+		"second any matcher":    {"pact_frontend_users_0000", "/users/1", "*/*", true},
+		"no any matcher":        {"pact_frontend_users_0000", "/users/1", "text/plain", false},
+		"nested path":           {"pact_frontend_users_0001", "/files/a/b/c.txt", "", true},
+		"nested path not match": {"pact_frontend_users_0001", "/files/a/b/c.pdf", "", false},
+	}
+	for k, v := range requests {
+		t.Run(k, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", v.path, nil)
+			r.Header.Set("Accept", v.accept)
+			rt := routes[v.route]
+			if rt.Matches(r) != v.pass {
+				t.Errorf("Unexpected match result, expected %t", v.pass)
+			}
+		})
+	}
+
+	invalid := map[string]string{
+		"invalid regex":          `{"path": {"matchers": [{"match": "regex", "regex": "(("}]}}`,
+		"example not matched":    `{"path": {"matchers": [{"match": "regex", "regex": "^/orders/[0-9]+$"}]}}`,
+		"unsupported path rule":  `{"path": {"matchers": [{"match": "type"}]}}`,
+		"unsupported combine":    `{"header": {"Accept": {"combine": "XOR", "matchers": [{"match": "type"}, {"match": "type"}]}}}`,
+		"invalid combined regex": `{"header": {"Accept": {"combine": "AND", "matchers": [{"match": "type"}, {"match": "regex", "regex": "(("}]}}}`,
+	}
+	for k, v := range invalid {
+		t.Run(k, func(t *testing.T) {
+			data := strings.Replace(rulesPact, `"matchingRules": {
+          "path": {"matchers": [{"match": "regex", "regex": "^/users/[0-9]+$"}]},`, `"matchingRules": `+v+`, "x": {`, 1)
+			f := writeTemp(t, data)
+			defer os.Remove(f)
+			p, err := FromFile(f)
+			if err != nil {
+				t.Fatalf("Unexpected failure when loading Pact - %s", err)
+			}
+			_, err = p.Routes()
+			if err == nil {
+				t.Errorf("Expected error converting Pact with %s", k)
+			}
+		})
+	}
+}
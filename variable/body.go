@@ -0,0 +1,306 @@
+package variable
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+)
+
+var (
+	// ErrInvalidFormBody is returned when the form body is unable to be parsed
+	ErrInvalidFormBody = errors.New("unable to parse form body")
+
+	// ErrInvalidFormVar is returned when the form field or file is not found
+	ErrInvalidFormVar = errors.New("unable to find form variable")
+
+	// ErrInvalidXmlBody is returned when the xml body is unable to be parsed
+	ErrInvalidXmlBody = errors.New("unable to parse xml body")
+
+	// ErrInvalidXmlVar is returned when the xpath expression is invalid or matches nothing
+	ErrInvalidXmlVar = errors.New("unable to find xml variable")
+
+	// ErrBodyTooLarge is returned when the request body is larger than MaxBodySize
+	ErrBodyTooLarge = errors.New("request body too large")
+)
+
+// MaxBodySize is the maximum number of bytes of the request body read for body variables
+var MaxBodySize int64 = 10 << 20
+
+// MaxMultipartMemory is the maximum number of bytes of a multipart body held in memory, the remainder of
+// uploaded files are stored on disk
+var MaxMultipartMemory int64 = 32 << 20
+
+// requestBody holds the buffered request body and its parsed representations, each is parsed once on first
+// use
+type requestBody struct {
+	raw []byte
+	err error
+
+	json    interface{}
+	jsonErr error
+	jsonOK  bool
+
+	xml    *xmlquery.Node
+	xmlErr error
+
+	form    url.Values
+	formErr error
+
+	multipart    *multipart.Form
+	multipartErr error
+}
+
+// bufferedBody is a request body buffered by ReadBody, it replaces the request body so that the body can be
+// read again and is only read once by every reader
+type bufferedBody struct {
+	io.Reader
+	io.Closer
+
+	raw []byte
+	err error
+
+	// doc is the body decoded by JSONBody
+	doc    interface{}
+	docErr error
+	docOK  bool
+}
+
+// ReadBody reads and buffers up to MaxBodySize bytes of the request body, the request body is replaced with
+// the buffered copy so it can be read again. Later calls return the buffered body without reading it again
+func ReadBody(req *http.Request) ([]byte, error) {
+	if b, ok := req.Body.(*bufferedBody); ok {
+		return b.raw, b.err
+	}
+	if req.Body == nil || req.Body == http.NoBody || req.ContentLength == 0 {
+		return nil, ErrNoBody
+	}
+	if req.ContentLength > MaxBodySize {
+		return nil, ErrBodyTooLarge
+	}
+
+	orig := req.Body
+	raw, err := io.ReadAll(io.LimitReader(orig, MaxBodySize+1))
+	b := &bufferedBody{Closer: orig, raw: raw}
+	switch {
+	case err != nil:
+		b.err = err
+	case int64(len(raw)) > MaxBodySize:
+		b.err = ErrBodyTooLarge
+	}
+
+	// keep the unread remainder available to later readers
+	b.Reader = io.MultiReader(bytes.NewReader(raw), orig)
+	if b.err != nil {
+		b.raw = nil
+	}
+	req.Body = b
+	return b.raw, b.err
+}
+
+// JSONBody returns the request body read by ReadBody decoded as JSON, numbers are kept as json.Number to
+// preserve their formatting. The body is decoded once and shared by later calls
+func JSONBody(req *http.Request) (interface{}, error) {
+	raw, err := ReadBody(req)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := req.Body.(*bufferedBody)
+	if !ok {
+		return nil, ErrNoBody
+	}
+	if !b.docOK {
+		b.docOK = true
+		d := json.NewDecoder(bytes.NewReader(raw))
+		d.UseNumber()
+		err = d.Decode(&b.doc)
+		if err != nil {
+			b.docErr = ErrInvalidJsonBody
+		}
+	}
+	return b.doc, b.docErr
+}
+
+// readBody reads and buffers the request body using ReadBody
+func (r *VariableInstance) readBody() ([]byte, error) {
+	if r.body != nil {
+		return r.body.raw, r.body.err
+	}
+	raw, err := ReadBody(r.r)
+	r.body = &requestBody{raw: raw, err: err}
+	return raw, err
+}
+
+// resetBody rewinds the request body to the start of the buffered body
+func (r *VariableInstance) resetBody() {
+	if b, ok := r.r.Body.(*bufferedBody); ok && b.err == nil {
+		b.Reader = bytes.NewReader(b.raw)
+		return
+	}
+	r.r.Body = io.NopCloser(bytes.NewReader(r.body.raw))
+}
+
+// jsonBody returns the decoded JSON body
+func (r *VariableInstance) jsonBody() (interface{}, error) {
+	raw, err := r.readBody()
+	if err != nil {
+		return nil, err
+	}
+	if !r.body.jsonOK {
+		r.body.jsonOK = true
+		err = json.Unmarshal(raw, &r.body.json)
+		if err != nil {
+			r.body.jsonErr = ErrInvalidJsonBody
+		}
+	}
+	return r.body.json, r.body.jsonErr
+}
+
+// xmlBody returns the parsed XML body
+func (r *VariableInstance) xmlBody() (*xmlquery.Node, error) {
+	raw, err := r.readBody()
+	if err != nil {
+		return nil, err
+	}
+	if r.body.xml == nil && r.body.xmlErr == nil {
+		r.body.xml, err = xmlquery.Parse(bytes.NewReader(raw))
+		if err != nil {
+			r.body.xmlErr = ErrInvalidXmlBody
+		}
+	}
+	return r.body.xml, r.body.xmlErr
+}
+
+// formBody returns the parsed application/x-www-form-urlencoded body
+func (r *VariableInstance) formBody() (url.Values, error) {
+	_, err := r.readBody()
+	if err != nil {
+		return nil, err
+	}
+	if r.body.form == nil && r.body.formErr == nil {
+		r.resetBody()
+		defer r.resetBody()
+
+		// parse a copy of the request so the buffered body is used even if the request was already parsed
+		req := *r.r
+		req.PostForm = nil
+		err = req.ParseForm()
+		if err != nil {
+			r.body.formErr = fmt.Errorf("%w - %s", ErrInvalidFormBody, err)
+		}
+		r.body.form = req.PostForm
+	}
+	return r.body.form, r.body.formErr
+}
+
+// multipartBody returns the parsed multipart/form-data body
+func (r *VariableInstance) multipartBody() (*multipart.Form, error) {
+	_, err := r.readBody()
+	if err != nil {
+		return nil, err
+	}
+	if r.body.multipart == nil && r.body.multipartErr == nil {
+		r.resetBody()
+		defer r.resetBody()
+
+		req := *r.r
+		req.MultipartForm = nil
+		err = req.ParseMultipartForm(MaxMultipartMemory)
+		if err != nil {
+			r.body.multipartErr = fmt.Errorf("%w - %s", ErrInvalidFormBody, err)
+		}
+		r.body.multipart = req.MultipartForm
+	}
+	return r.body.multipart, r.body.multipartErr
+}
+
+// getFormVariable returns the named field of an application/x-www-form-urlencoded body
+func (r *VariableInstance) getFormVariable(variable string) (string, error) {
+	form, err := r.formBody()
+	if err != nil {
+		return "", err
+	}
+
+	values, ok := form[variable]
+	if !ok || len(values) == 0 {
+		return "", ErrInvalidFormVar
+	}
+	return values[0], nil
+}
+
+// getMultipartVariable returns the named field of a multipart/form-data body
+func (r *VariableInstance) getMultipartVariable(variable string) (string, error) {
+	form, err := r.multipartBody()
+	if err != nil {
+		return "", err
+	}
+
+	values, ok := form.Value[variable]
+	if !ok || len(values) == 0 {
+		return "", ErrInvalidFormVar
+	}
+	return values[0], nil
+}
+
+// getMultipartFileVariable returns details of an uploaded file using the format name.filename, name.size or
+// name.content_type
+func (r *VariableInstance) getMultipartFileVariable(variable string) (string, error) {
+	i := strings.LastIndex(variable, ".")
+	if i < 1 {
+		return "", ErrInvalidVariableFormat
+	}
+	name, attr := variable[:i], variable[i+1:]
+
+	form, err := r.multipartBody()
+	if err != nil {
+		return "", err
+	}
+
+	files, ok := form.File[name]
+	if !ok || len(files) == 0 {
+		return "", ErrInvalidFormVar
+	}
+
+	switch attr {
+	case "filename":
+		return files[0].Filename, nil
+	case "size":
+		return fmt.Sprint(files[0].Size), nil
+	case "content_type":
+		return files[0].Header.Get("Content-Type"), nil
+	}
+	return "", ErrInvalidVariableFormat
+}
+
+// getXmlVariable selects a value from the XML body using an XPath expression, e.g.
+// {{ xml.//soap:Body/GetUser/id }} or {{ xml.count(//item) }}
+func (r *VariableInstance) getXmlVariable(variable string) (string, error) {
+	expr, err := xpath.Compile(variable)
+	if err != nil {
+		return "", fmt.Errorf("%w - %s", ErrInvalidXmlVar, err)
+	}
+
+	doc, err := r.xmlBody()
+	if err != nil {
+		return "", err
+	}
+
+	switch v := expr.Evaluate(xmlquery.CreateXPathNavigator(doc)).(type) {
+	case *xpath.NodeIterator:
+		if !v.MoveNext() {
+			return "", ErrInvalidXmlVar
+		}
+		return v.Current().Value(), nil
+	case float64, string, bool:
+		return fmt.Sprint(v), nil
+	}
+	return "", ErrInvalidXmlVar
+}
@@ -0,0 +1,186 @@
+package variable
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormVariables(t *testing.T) {
+	newRequest := func() *VariableInstance {
+		r, _ := http.NewRequest("POST", "http://test:8080/form?type=admin", strings.NewReader("name=Jim&role=admin&role=user"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return NewVariableInstance(r, nil, nil)
+	}
+
+	testMatrix := map[string]struct {
+		inputData   string
+		expectValue string
+	}{
+		"Valid Field":      {"{{ form.name }} {{ form.role }}", "Jim admin"},
+		"Missing Field":    {"{{ form.missing }}", "{{ form.missing }}"},
+		"Not Query Values": {"{{ form.type }}", "{{ form.type }}"},
+	}
+
+	for name, tc := range testMatrix {
+		t.Run(name, func(t *testing.T) {
+			value, err := newRequest().ReplaceVariables(tc.inputData)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectValue, value)
+		})
+	}
+
+	t.Run("No Body", func(t *testing.T) {
+		r, _ := http.NewRequest("POST", "http://test:8080/form", nil)
+		_, err := NewVariableInstance(r, nil, nil).ParseVariable("form.name")
+		assert.ErrorIs(t, err, ErrNoBody)
+	})
+}
+
+func TestMultipartVariables(t *testing.T) {
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	_ = mw.WriteField("name", "Jim")
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="avatar"; filename="jim.png"`)
+	h.Set("Content-Type", "image/png")
+	fw, _ := mw.CreatePart(h)
+	_, _ = fw.Write([]byte("not really a png"))
+	_ = mw.Close()
+
+	newRequest := func() *VariableInstance {
+		r, _ := http.NewRequest("POST", "http://test:8080/upload", bytes.NewReader(b.Bytes()))
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		return NewVariableInstance(r, nil, nil)
+	}
+
+	testMatrix := map[string]struct {
+		inputData   string
+		expectValue string
+	}{
+		"Field":             {"{{ multipart.name }}", "Jim"},
+		"File Details":      {"{{ multipart.file.avatar.filename }} {{ multipart.file.avatar.size }} {{ multipart.file.avatar.content_type }}", "jim.png 16 image/png"},
+		"Missing File":      {"{{ multipart.file.missing.filename }}", "{{ multipart.file.missing.filename }}"},
+		"Invalid Attribute": {"{{ multipart.file.avatar.owner }}", "{{ multipart.file.avatar.owner }}"},
+		"Missing Field":     {"{{ multipart.missing }}", "{{ multipart.missing }}"},
+	}
+
+	for name, tc := range testMatrix {
+		t.Run(name, func(t *testing.T) {
+			value, err := newRequest().ReplaceVariables(tc.inputData)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectValue, value)
+		})
+	}
+
+	t.Run("Not Multipart", func(t *testing.T) {
+		r, _ := http.NewRequest("POST", "http://test:8080/upload", strings.NewReader("name=Jim"))
+		_, err := NewVariableInstance(r, nil, nil).ParseVariable("multipart.name")
+		assert.ErrorIs(t, err, ErrInvalidFormBody)
+	})
+}
+
+func TestXmlVariables(t *testing.T) {
+	body := `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope">
+  <soap:Body>
+    <GetUser>
+      <id type="int">10</id>
+      <item>a</item>
+      <item>b</item>
+    </GetUser>
+  </soap:Body>
+</soap:Envelope>`
+
+	testMatrix := map[string]struct {
+		inputVariable string
+		inputBody     string
+		expectError   bool
+		expectValue   string
+	}{
+		"Element":       {inputVariable: "//soap:Body/GetUser/id", inputBody: body, expectValue: "10"},
+		"Attribute":     {inputVariable: "//GetUser/id/@type", inputBody: body, expectValue: "int"},
+		"Predicate":     {inputVariable: "//item[2]", inputBody: body, expectValue: "b"},
+		"Function":      {inputVariable: "count(//item)", inputBody: body, expectValue: "2"},
+		"Missing":       {inputVariable: "//missing", inputBody: body, expectError: true},
+		"Invalid XPath": {inputVariable: "//[", inputBody: body, expectError: true},
+		"Invalid Body":  {inputVariable: "//id", inputBody: "<a><b></a>", expectError: true},
+		"Blank Body":    {inputVariable: "//id", inputBody: "", expectError: true},
+	}
+
+	for name, tc := range testMatrix {
+		t.Run(name, func(t *testing.T) {
+			r, _ := http.NewRequest("POST", "http://test:8080/soap", strings.NewReader(tc.inputBody))
+			value, err := NewVariableInstance(r, nil, nil).getXmlVariable(tc.inputVariable)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectValue, value)
+		})
+	}
+
+	t.Run("Replace Variables", func(t *testing.T) {
+		r, _ := http.NewRequest("POST", "http://test:8080/soap", strings.NewReader(body))
+		value, err := NewVariableInstance(r, nil, nil).ReplaceVariables("<id>{{ xml.//soap:Body/GetUser/id }}</id>")
+		assert.NoError(t, err)
+		assert.Equal(t, "<id>10</id>", value)
+	})
+}
+
+func TestBodyReadOnce(t *testing.T) {
+	t.Run("Multiple Variables", func(t *testing.T) {
+		r, _ := http.NewRequest("POST", "http://test:8080/", strings.NewReader(`{"a": "1", "b": "2"}`))
+		value, err := NewVariableInstance(r, nil, nil).ReplaceVariables("{{ body.a }} {{ body.b }} {{ body }} {{ body }}")
+		assert.NoError(t, err)
+		assert.Equal(t, `1 2 {"a": "1", "b": "2"} {"a": "1", "b": "2"}`, value)
+	})
+
+	t.Run("Request Body Restored", func(t *testing.T) {
+		r, _ := http.NewRequest("POST", "http://test:8080/", strings.NewReader("name=Jim"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		v := NewVariableInstance(r, nil, nil)
+		value, err := v.ReplaceVariables("{{ form.name }} {{ form.name }} {{ body }}")
+		assert.NoError(t, err)
+		assert.Equal(t, "Jim Jim name=Jim", value)
+
+		b, err := io.ReadAll(v.Request().Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "name=Jim", string(b))
+	})
+
+	t.Run("Parsed Once", func(t *testing.T) {
+		r, _ := http.NewRequest("POST", "http://test:8080/", strings.NewReader(`<a><b>1</b></a>`))
+		v := NewVariableInstance(r, nil, nil)
+		a, err := v.xmlBody()
+		assert.NoError(t, err)
+		b, err := v.xmlBody()
+		assert.NoError(t, err)
+		assert.Same(t, a, b)
+	})
+
+	t.Run("Too Large", func(t *testing.T) {
+		defer func(n int64) { MaxBodySize = n }(MaxBodySize)
+		MaxBodySize = 4
+
+		r, _ := http.NewRequest("POST", "http://test:8080/", strings.NewReader("too large"))
+		_, err := NewVariableInstance(r, nil, nil).ParseVariable("body")
+		assert.ErrorIs(t, err, ErrBodyTooLarge)
+
+		// chunked bodies have an unknown length and are capped while reading
+		r, _ = http.NewRequest("POST", "http://test:8080/", io.MultiReader(strings.NewReader("too large")))
+		r.ContentLength = -1
+		v := NewVariableInstance(r, nil, nil)
+		_, err = v.ParseVariable("body")
+		assert.ErrorIs(t, err, ErrBodyTooLarge)
+		b, _ := io.ReadAll(v.Request().Body)
+		assert.Equal(t, "too large", string(b))
+	})
+}
@@ -0,0 +1,249 @@
+package variable
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+)
+
+// Template is a response value parsed once into literal text and variables, allowing it to be rendered for
+// every request without scanning the value again. Templates are safe for concurrent use
+type Template struct {
+	// raw is the value the Template was compiled from
+	raw string
+
+	// segments holds the literal text and variables of the value in order
+	segments []segment
+
+	// tmpl is the parsed Go template, this is only set for templates compiled with CompileTemplate
+	tmpl *template.Template
+
+	// bound and strictBound are pools of boundTemplate copies of tmpl, reused across requests. Copies
+	// within strictBound fail on missing map keys
+	bound       sync.Pool
+	strictBound sync.Pool
+
+	// static is true when the value holds no variables, text is then rendered as is
+	static bool
+	text   string
+}
+
+// segment is either literal text or a variable of a compiled Template
+type segment struct {
+	// text is the literal text, or the variable instance including braces which is used when the variable
+	// cannot be resolved
+	text string
+
+	// variable is the variable name, this is empty for literal text
+	variable string
+
+	// filters are applied in order to the resolved variable
+	filters []filter
+
+	// err is set when the variable cannot be parsed, the variable is then left as is
+	err error
+
+	// prefix and resolver are the Resolver matched when compiled, they are used while the registry is
+	// unchanged
+	prefix     string
+	resolver   Resolver
+	generation uint64
+}
+
+// Compile parses data into a Template of {{ variable }} substitutions
+func Compile(data string) *Template {
+	t := &Template{raw: data}
+
+	last := 0
+	for _, loc := range varRegex.FindAllStringIndex(data, -1) {
+		// escaped variables, e.g. \{{ literal }}, are kept as text without the escape
+		if loc[0] > 0 && data[loc[0]-1] == '\\' {
+			t.segments = append(t.segments, segment{text: unescape(data[last : loc[0]-1])}, segment{text: data[loc[0]:loc[1]]})
+			last = loc[1]
+			continue
+		}
+
+		if loc[0] > last {
+			t.segments = append(t.segments, segment{text: unescape(data[last:loc[0]])})
+		}
+		s := segment{text: data[loc[0]:loc[1]]}
+		s.variable, s.filters, s.err = splitFilters(removeBraces(s.text))
+		if s.err == nil {
+			s.prefix, s.resolver, _ = lookupResolver(s.variable)
+			s.generation = resolversGeneration.Load()
+		}
+		t.segments = append(t.segments, s)
+		last = loc[1]
+	}
+	if last < len(data) {
+		t.segments = append(t.segments, segment{text: unescape(data[last:])})
+	}
+
+	// values without variables are rendered as a single string
+	t.static = true
+	for _, s := range t.segments {
+		if s.variable != "" || s.err != nil {
+			t.static = false
+			break
+		}
+	}
+	if t.static {
+		var text strings.Builder
+		for _, s := range t.segments {
+			text.WriteString(s.text)
+		}
+		t.text = text.String()
+	}
+	return t
+}
+
+// unescape removes the escape from literal braces, \{{ is rendered as {{
+func unescape(text string) string {
+	return strings.ReplaceAll(text, `\{{`, "{{")
+}
+
+// CompileTemplate parses data into a Template rendered with the Go text/template engine
+func CompileTemplate(data string) (*Template, error) {
+	tmpl, err := parseTemplate(data)
+	if err != nil {
+		return nil, err
+	}
+	t := &Template{raw: data, tmpl: tmpl}
+	t.bound.New = newBoundTemplate(tmpl, "missingkey=default")
+	t.strictBound.New = newBoundTemplate(tmpl, "missingkey=error")
+
+	// templates without actions always render the same text
+	var text strings.Builder
+	t.static = true
+	if tmpl.Tree != nil {
+		for _, n := range tmpl.Tree.Root.Nodes {
+			tn, ok := n.(*parse.TextNode)
+			if !ok {
+				t.static = false
+				break
+			}
+			text.Write(tn.Text)
+		}
+	}
+	if t.static {
+		t.text = text.String()
+	}
+	return t, nil
+}
+
+// String returns the value the Template was compiled from
+func (t *Template) String() string {
+	return t.raw
+}
+
+// Render replaces the variables of the compiled Template, variables which cannot be resolved are left as is
+// and reported as an UnresolvedError in strict mode. Templates without variables are returned without
+// allocating
+func (r *VariableInstance) Render(t *Template) (string, error) {
+	r.unresolved = nil
+	if t.static {
+		return t.text, nil
+	}
+
+	var b strings.Builder
+	b.Grow(len(t.raw))
+	var unresolved []UnresolvedVariable
+	generation := resolversGeneration.Load()
+	for _, s := range t.segments {
+		if s.variable == "" && s.err == nil {
+			b.WriteString(s.text)
+			continue
+		}
+
+		value, err := r.resolveSegment(s, generation)
+		if err != nil {
+			// on error leave the variable instance in place
+			value = s.text
+			unresolved = append(unresolved, UnresolvedVariable{Variable: s.text, Err: err})
+		}
+		b.WriteString(value)
+	}
+
+	r.unresolved = unresolved
+	if r.strict && len(unresolved) > 0 {
+		return b.String(), &UnresolvedError{Variables: unresolved}
+	}
+	return b.String(), nil
+}
+
+// resolveSegment resolves the variable of the segment and applies its filters
+func (r *VariableInstance) resolveSegment(s segment, generation uint64) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+
+	var value string
+	var err error
+	if s.resolver != nil && s.generation == generation {
+		value, err = s.resolver.Resolve(r, s.variable[len(s.prefix):])
+	} else {
+		value, err = r.ParseVariable(s.variable)
+	}
+	if len(s.filters) > 0 {
+		return applyFilters(value, err, s.filters)
+	}
+	return value, err
+}
+
+// Render executes the compiled Go template against the request data, Templates compiled with Compile are
+// compiled as Go templates first
+func (t *templateInstance) Render(c *Template) (string, error) {
+	if c.tmpl == nil {
+		compiled, err := CompileTemplate(c.raw)
+		if err != nil {
+			return c.raw, err
+		}
+		c = compiled
+	}
+	if c.static {
+		return c.text, nil
+	}
+
+	if t.data == nil {
+		t.data = t.templateData()
+	}
+
+	// the parsed template is shared, execute a copy bound to this instance
+	pool := &c.bound
+	if t.strict {
+		pool = &c.strictBound
+	}
+	bt := pool.Get().(*boundTemplate)
+	bt.instance = t
+	defer func() {
+		bt.instance = nil
+		pool.Put(bt)
+	}()
+
+	var b bytes.Buffer
+	err := bt.tmpl.Execute(&b, t.data)
+	if err != nil {
+		err = fmt.Errorf("error executing template - %w", err)
+		if t.strict {
+			return b.String(), &UnresolvedError{Variables: []UnresolvedVariable{{Variable: templateAction(err), Err: err}}}
+		}
+		return b.String(), err
+	}
+	return b.String(), nil
+}
+
+// execActionRegex matches the action reported within template execution errors
+var execActionRegex = regexp.MustCompile(`at <([^>]*)>`)
+
+// templateAction returns the template action which failed to execute, e.g. {{ .Query.page }}
+func templateAction(err error) string {
+	m := execActionRegex.FindStringSubmatch(err.Error())
+	if m == nil {
+		return "template"
+	}
+	return "{{ " + m[1] + " }}"
+}
@@ -0,0 +1,157 @@
+package variable
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompile(t *testing.T) {
+	testMatrix := map[string]struct {
+		inputData   string
+		expectValue string
+	}{
+		"Static":      {"no variables here", "no variables here"},
+		"Empty":       {"", ""},
+		"Single":      {"{{ header.testheader }}", "headervalue"},
+		"Surrounded":  {"a {{ query.testquery }} b {{ param.testparam }} c", "a queryvalue b paramvalue c"},
+		"Repeated":    {"{{ $randomMock }}-{{ $randomMock }}", "randomValue-randomValue"},
+		"Unresolved":  {"{{ header.missing }} {{ header.testheader }}", "{{ header.missing }} headervalue"},
+		"Body Twice":  {"{{ body.test }} {{ body.test }}", "body body"},
+		"Braces Only": {"{{ }}", "{{ }}"},
+	}
+
+	for name, tc := range testMatrix {
+		t.Run(name, func(t *testing.T) {
+			c := Compile(tc.inputData)
+			assert.Equal(t, tc.inputData, c.String())
+
+			value, err := createTestRequestContext().Render(c)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectValue, value)
+		})
+	}
+
+	t.Run("Resolver Registered After Compile", func(t *testing.T) {
+		c := Compile("{{ header.vault.secret }}")
+		err := RegisterResolver("header.vault.", ResolverFunc(func(_ *VariableInstance, v string) (string, error) {
+			return "vault-" + v, nil
+		}))
+		assert.NoError(t, err)
+		defer UnregisterResolver("header.vault.")
+
+		value, err := createTestRequestContext().Render(c)
+		assert.NoError(t, err)
+		assert.Equal(t, "vault-secret", value)
+	})
+
+	t.Run("Static Without Allocating", func(t *testing.T) {
+		c := Compile(`{"greeting": "Hello", "name": "World"}`)
+		r := createTestRequestContext()
+		allocs := testing.AllocsPerRun(100, func() {
+			_, _ = r.Render(c)
+		})
+		assert.Equal(t, float64(0), allocs)
+	})
+}
+
+func TestCompileTemplate(t *testing.T) {
+	t.Run("Dynamic", func(t *testing.T) {
+		c, err := CompileTemplate(`{{ .Method }} {{ random "randomMock" }}`)
+		assert.NoError(t, err)
+
+		// the compiled template is shared between instances
+		for i := 0; i < 2; i++ {
+			value, err := createTestTemplateInstance().Render(c)
+			assert.NoError(t, err)
+			assert.Equal(t, "POST randomValue", value)
+		}
+	})
+
+	t.Run("Static", func(t *testing.T) {
+		c, err := CompileTemplate("static {{- /* comment */ -}} text")
+		assert.NoError(t, err)
+		ti := createTestTemplateInstance()
+		allocs := testing.AllocsPerRun(100, func() {
+			_, _ = ti.Render(c)
+		})
+		assert.Equal(t, float64(0), allocs)
+
+		value, err := ti.Render(c)
+		assert.NoError(t, err)
+		assert.Equal(t, "statictext", value)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := CompileTemplate("{{ if }}")
+		assert.Error(t, err)
+	})
+
+	t.Run("Not Compiled As Go Template", func(t *testing.T) {
+		value, err := createTestTemplateInstance().Render(Compile("{{ .Params.id }}"))
+		assert.NoError(t, err)
+		assert.Equal(t, "10", value)
+	})
+}
+
+func BenchmarkRender(b *testing.B) {
+	r, _ := http.NewRequest("POST", "http://test:8080/users/10?type=admin", nil)
+	r.Header.Set("X-Test", "headervalue")
+	p := httprouter.Params{{Key: "id", Value: "10"}}
+
+	benchmarks := map[string]string{
+		"Static":    `{"greeting": "Hello", "name": "World"}`,
+		"Variables": `{"id": "{{ param.id }}", "type": "{{ query.type }}", "test": "{{ header.X-Test }}"}`,
+		"Random":    `{"id": "{{ $guid }}", "name": "{{ $randomFirstName }}"}`,
+		"Large":     strings.Repeat(`{"id": "{{ param.id }}", "padding": "some static text"},`, 100),
+	}
+
+	for name, data := range benchmarks {
+		// Fixtures should render every variable, an unresolved one would skew the results
+		value, err := NewVariableInstance(r, nil, p).ReplaceVariables(data)
+		if err != nil || strings.Contains(value, "{{") {
+			b.Fatalf("Unexpected render of %s fixture %q - %v", name, value, err)
+		}
+
+		b.Run(name+"/Compiled", func(b *testing.B) {
+			c := Compile(data)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = NewVariableInstance(r, nil, p).Render(c)
+			}
+		})
+
+		b.Run(name+"/ReplaceVariables", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = NewVariableInstance(r, nil, p).ReplaceVariables(data)
+			}
+		})
+	}
+}
+
+func BenchmarkRenderTemplate(b *testing.B) {
+	r, _ := http.NewRequest("GET", "http://test:8080/users/10?type=admin", nil)
+	p := httprouter.Params{{Key: "id", Value: "10"}}
+
+	benchmarks := map[string]string{
+		"Static":  `{"greeting": "Hello", "name": "World"}`,
+		"Dynamic": `{"id": "{{ .Params.id }}", "type": "{{ .Query.type | default "user" }}"}`,
+	}
+
+	for name, data := range benchmarks {
+		b.Run(name, func(b *testing.B) {
+			c, err := CompileTemplate(data)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = NewTemplateInstance(r, nil, p).Render(c)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package variable
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrInvalidFilter is returned when the filter is not found
+	ErrInvalidFilter = errors.New("error filter not found")
+
+	// ErrInvalidFilterArgs is returned when the arguments given to a filter are invalid
+	ErrInvalidFilterArgs = errors.New("error invalid filter arguments")
+)
+
+// DefaultFilter is the filter providing a value for variables which cannot be resolved or are empty, e.g.
+// {{ query.page | default "1" }}
+const DefaultFilter = "default"
+
+// FilterFunc is a function type that transforms the value of a variable, e.g. {{ body | json }}. Arguments
+// given to the filter are passed as args
+type FilterFunc func(value string, args ...string) (string, error)
+
+// FilterMap is a map of the filters available to variables and the FilterFuncs that apply them
+var FilterMap = map[string]FilterFunc{
+	"upper":     FilterWrapper(strings.ToUpper),
+	"lower":     FilterWrapper(strings.ToLower),
+	"json":      FilterWrapper(jsonEscape),
+	"base64":    FilterWrapper(base64Encode),
+	"urlencode": FilterWrapper(url.QueryEscape),
+}
+
+// FilterWrapper wraps a function without arguments as a FilterFunc
+func FilterWrapper(fn func(string) string) FilterFunc {
+	return func(value string, args ...string) (string, error) {
+		if len(args) > 0 {
+			return "", ErrInvalidFilterArgs
+		}
+		return fn(value), nil
+	}
+}
+
+// filter is a filter applied to a variable along with its arguments
+type filter struct {
+	name string
+	args []string
+}
+
+// applyFilters applies the filters in order to the resolved value. The default filter replaces errors and
+// empty values, other filters are skipped while the value is unresolved
+func applyFilters(value string, err error, filters []filter) (string, error) {
+	for _, f := range filters {
+		if f.name == DefaultFilter {
+			if len(f.args) != 1 {
+				return "", ErrInvalidFilterArgs
+			}
+			if err != nil || value == "" {
+				value, err = f.args[0], nil
+			}
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		fn, ok := FilterMap[f.name]
+		if !ok {
+			return "", fmt.Errorf("%w - %s", ErrInvalidFilter, f.name)
+		}
+		value, err = fn(value, f.args...)
+	}
+	return value, err
+}
+
+// validateFilters checks the filters exist and accept their arguments
+func validateFilters(filters []filter) error {
+	for _, f := range filters {
+		_, err := applyFilters("", nil, []filter{f})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitFilters splits a variable with the format variable | filter arg ... into the variable and its
+// filters. Pipes within quotes, brackets or parentheses are part of the variable
+func splitFilters(variable string) (string, []filter, error) {
+	parts := []string{}
+	var quote rune
+	depth, start := 0, 0
+	for i, c := range variable {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case c == '|' && depth == 0:
+			parts = append(parts, variable[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, variable[start:])
+
+	filters := make([]filter, 0, len(parts)-1)
+	for _, p := range parts[1:] {
+		fields, err := splitFields(p)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(fields) == 0 {
+			return "", nil, ErrInvalidVariableFormat
+		}
+		filters = append(filters, filter{name: fields[0], args: fields[1:]})
+	}
+	return strings.TrimSpace(parts[0]), filters, nil
+}
+
+// splitFields splits data on whitespace, fields may be quoted with single or double quotes to include spaces
+func splitFields(data string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	var quote rune
+	inField := false
+	for _, c := range data {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			field.WriteRune(c)
+		case c == '"' || c == '\'':
+			quote, inField = c, true
+		case unicode.IsSpace(c):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(c)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, ErrInvalidVariableFormat
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+// jsonEscape escapes the value for use within a JSON string, the surrounding quotes are not included
+func jsonEscape(value string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(value)
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSuffix(b.String(), "\n"), `"`), `"`)
+}
+
+// base64Encode returns the standard base64 encoding of the value
+func base64Encode(value string) string {
+	return base64.StdEncoding.EncodeToString([]byte(value))
+}
@@ -0,0 +1,78 @@
+package variable
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilters(t *testing.T) {
+	testMatrix := map[string]struct {
+		inputData   string
+		expectValue string
+	}{
+		"Upper":              {`{{ header.testheader | upper }}`, "HEADERVALUE"},
+		"Lower":              {`{{ header.testheader | upper | lower }}`, "headervalue"},
+		"Base64":             {`{{ query.testquery | base64 }}`, "cXVlcnl2YWx1ZQ=="},
+		"URL Encode":         {`{{ $oneOf("a b&c") | urlencode }}`, "a+b%26c"},
+		"JSON":               {`{"echo": "{{ body | json }}"}`, `{"echo": "{\"test\": \"body\", \"testint\": 10}"}`},
+		"Default Unresolved": {`{{ query.page | default "1" }}`, "1"},
+		"Default Resolved":   {`{{ query.testquery | default "1" }}`, "queryvalue"},
+		"Default Quoted":     {`{{ query.page | default 'no page' | upper }}`, "NO PAGE"},
+		"Default Empty":      {`[{{ query.page | default "" }}]`, "[]"},
+		"Default Unquoted":   {`{{ header.missing | default none }}`, "none"},
+		"Skipped Filters":    {`{{ query.page | upper }}`, `{{ query.page | upper }}`},
+		"Unknown Filter":     {`{{ query.testquery | bogus }}`, `{{ query.testquery | bogus }}`},
+		"Invalid Arguments":  {`{{ query.testquery | upper "x" }}`, `{{ query.testquery | upper "x" }}`},
+		"Random With Key":    {`{{ $randomMock key=param.testparam | upper }}`, "RANDOMVALUE"},
+		"JSONPath Union":     {`{{ body['test'] | upper }}`, "BODY"},
+		"Escaped":            {`\{{ header.testheader }} {{ header.testheader }}`, "{{ header.testheader }} headervalue"},
+		"Escaped Literal":    {`\{{ not a variable`, "{{ not a variable"},
+		"Escaped Filter":     {`\{{ query.page | default "1" }}`, `{{ query.page | default "1" }}`},
+	}
+
+	for name, tc := range testMatrix {
+		t.Run(name, func(t *testing.T) {
+			value, err := createTestRequestContext().ReplaceVariables(tc.inputData)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectValue, value)
+		})
+	}
+
+	t.Run("JSON Output Is Valid", func(t *testing.T) {
+		r := createTestRequestContext()
+		r.r.Header.Set("testheader", "quote \" backslash \\ newline \n tab \t <html>")
+		value, err := r.ReplaceVariables(`{"echo": "{{ header.testheader | json }}"}`)
+		assert.NoError(t, err)
+
+		var v map[string]string
+		assert.NoError(t, json.Unmarshal([]byte(value), &v))
+		assert.Equal(t, "quote \" backslash \\ newline \n tab \t <html>", v["echo"])
+	})
+}
+
+func TestValidateFilters(t *testing.T) {
+	testMatrix := map[string]struct {
+		inputData   string
+		expectError bool
+	}{
+		"Valid":             {inputData: `{{ query.page | default "1" | upper }} {{ body | json }}`},
+		"Unknown Filter":    {inputData: `{{ query.page | bogus }}`, expectError: true},
+		"Missing Default":   {inputData: `{{ query.page | default }}`, expectError: true},
+		"Invalid Arguments": {inputData: `{{ body | base64 "x" }}`, expectError: true},
+		"Invalid Random":    {inputData: `{{ $badRandom | upper }}`, expectError: true},
+		"Escaped":           {inputData: `\{{ query.page | bogus }}`},
+	}
+
+	for name, tc := range testMatrix {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateVariables(tc.inputData)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package variable
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/madflojo/mockitout/jwt"
+)
+
+var (
+	// ErrNoSigner is returned when a jwt variable is used without a signing key configured
+	ErrNoSigner = errors.New("jwt signing key is not configured")
+
+	// ErrInvalidClaim is returned when a jwt variable claim is not in the name=value format
+	ErrInvalidClaim = errors.New("invalid jwt claim")
+)
+
+// JWTPrefix is the variable returning a signed JSON Web Token, e.g. {{ jwt sub=body.user exp=+1h }}
+const JWTPrefix = "jwt"
+
+// JWTIssuer is the default iss claim of tokens created by jwt variables
+var JWTIssuer = "mockitout"
+
+// JWTExpiry is the default lifetime of tokens created by jwt variables
+var JWTExpiry = time.Hour
+
+// timeClaims are the claims holding times, offsets given to them are relative to the current time
+var timeClaims = map[string]bool{"exp": true, "nbf": true, "iat": true, "auth_time": true}
+
+// jwtClaim is a single name=value argument of a jwt variable
+type jwtClaim struct {
+	name, value string
+
+	// quoted values are literal strings rather than variables
+	quoted bool
+}
+
+// getJWTVariable returns a token signed by the jwt.Default Signer. The iss, iat and exp claims are set by
+// default and any claim can be given as name=value, e.g. {{ jwt sub=body.user exp=+5m scope="read write" }}.
+// Quoted values are strings, offsets given to time claims are relative to now, numbers, booleans and JSON
+// arrays are used as is and any other value is resolved as a variable
+func (r *VariableInstance) getJWTVariable(variable string) (string, error) {
+	claims, err := jwtClaims(variable)
+	if err != nil {
+		return "", err
+	}
+	signer := jwt.Default
+	if signer == nil {
+		return "", ErrNoSigner
+	}
+
+	now := Now()
+	c := map[string]interface{}{
+		"iss": JWTIssuer,
+		"iat": now.Unix(),
+		"exp": now.Add(JWTExpiry).Unix(),
+	}
+	for _, claim := range claims {
+		v, err := r.claimValue(claim, now)
+		if err != nil {
+			return "", fmt.Errorf("%w %s - %w", ErrInvalidClaim, claim.name, err)
+		}
+		c[claim.name] = v
+	}
+	return signer.Sign(c)
+}
+
+// claimValue returns the value of the claim
+func (r *VariableInstance) claimValue(c jwtClaim, now time.Time) (interface{}, error) {
+	if c.quoted {
+		return c.value, nil
+	}
+	if v, ok, err := literalClaim(c, now); ok || err != nil {
+		return v, err
+	}
+	return r.ParseVariable(c.value)
+}
+
+// literalClaim returns the value of claims which are not variables, false is returned for variables
+func literalClaim(c jwtClaim, now time.Time) (interface{}, bool, error) {
+	if timeClaims[c.name] && len(c.value) > 1 && (c.value[0] == '+' || c.value[0] == '-') && unicode.IsDigit(rune(c.value[1])) {
+		o, err := parseOffset(c.value)
+		if err != nil {
+			return nil, true, err
+		}
+		return now.AddDate(o.years, o.months, o.days).Add(o.duration).Unix(), true, nil
+	}
+	if n, err := strconv.ParseInt(c.value, 10, 64); err == nil {
+		return n, true, nil
+	}
+	if f, err := strconv.ParseFloat(c.value, 64); err == nil {
+		return f, true, nil
+	}
+	if b, err := strconv.ParseBool(c.value); err == nil && (c.value == "true" || c.value == "false") {
+		return b, true, nil
+	}
+	if strings.HasPrefix(c.value, "[") {
+		var v []interface{}
+		err := json.Unmarshal([]byte(c.value), &v)
+		if err != nil {
+			return nil, true, err
+		}
+		return v, true, nil
+	}
+	return nil, false, nil
+}
+
+// jwtClaims splits the name=value arguments of a jwt variable, the arguments must be separated from the
+// variable name. Values may be quoted to include spaces and brackets are kept together, e.g. aud=["a", "b"]
+func jwtClaims(variable string) ([]jwtClaim, error) {
+	if variable != "" && !unicode.IsSpace(rune(variable[0])) {
+		return nil, ErrInvalidVariablePrefix
+	}
+
+	var fields []string
+	var field strings.Builder
+	var quote rune
+	depth := 0
+	for _, c := range variable {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+		case unicode.IsSpace(c) && depth == 0:
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+			continue
+		}
+		field.WriteRune(c)
+	}
+	if quote != 0 || depth != 0 {
+		return nil, ErrInvalidVariableFormat
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+
+	claims := make([]jwtClaim, 0, len(fields))
+	for _, f := range fields {
+		name, value, ok := strings.Cut(f, "=")
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Errorf("%w %q, expected name=value", ErrInvalidClaim, f)
+		}
+		c := jwtClaim{name: name, value: value}
+		if len(value) > 1 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			c.value, c.quoted = value[1:len(value)-1], true
+		}
+		claims = append(claims, c)
+	}
+	return claims, nil
+}
+
+// validateJWTVariable checks the claims of jwt variables, other variables are ignored
+func validateJWTVariable(variable string) error {
+	rest, ok := strings.CutPrefix(variable, JWTPrefix)
+	if !ok {
+		return nil
+	}
+	claims, err := jwtClaims(rest)
+	if errors.Is(err, ErrInvalidVariablePrefix) {
+		// a different variable sharing the prefix
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, c := range claims {
+		if c.quoted {
+			continue
+		}
+		_, _, err := literalClaim(c, time.Time{})
+		if err != nil {
+			return fmt.Errorf("%w %s - %w", ErrInvalidClaim, c.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package variable
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madflojo/mockitout/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWTVariables(t *testing.T) {
+	fixed := time.Date(2030, time.January, 1, 23, 59, 59, 0, time.UTC)
+	defer func(now func() time.Time) { Now = now }(Now)
+	Now = func() time.Time { return fixed }
+
+	signer, err := jwt.Generate()
+	if err != nil {
+		t.Fatalf("Unable to generate signer - %s", err)
+	}
+	defer func(s *jwt.Signer) { jwt.Default = s }(jwt.Default)
+	jwt.Default = signer
+
+	testMatrix := map[string]struct {
+		inputData    string
+		expectClaims map[string]interface{}
+	}{
+		"Defaults": {
+			inputData:    `{{ jwt }}`,
+			expectClaims: map[string]interface{}{"iss": "mockitout", "iat": float64(fixed.Unix()), "exp": float64(fixed.Add(time.Hour).Unix())},
+		},
+		"Variables": {
+			inputData:    `{{ jwt sub=header.testheader tenant=param.testparam }}`,
+			expectClaims: map[string]interface{}{"sub": "headervalue", "tenant": "paramvalue"},
+		},
+		"Literals": {
+			inputData:    `{{ jwt iss="https://idp.example.com" scope="read write" level=3 admin=true aud=["a", "b"] }}`,
+			expectClaims: map[string]interface{}{"iss": "https://idp.example.com", "scope": "read write", "level": float64(3), "admin": true, "aud": []interface{}{"a", "b"}},
+		},
+		"Relative Times": {
+			inputData:    `{{ jwt exp=+5m nbf=-1h auth_time=-1d }}`,
+			expectClaims: map[string]interface{}{"exp": float64(fixed.Add(5 * time.Minute).Unix()), "nbf": float64(fixed.Add(-time.Hour).Unix()), "auth_time": float64(fixed.AddDate(0, 0, -1).Unix())},
+		},
+		"Expired": {
+			inputData:    `{{ jwt exp=-1s }}`,
+			expectClaims: map[string]interface{}{"exp": float64(fixed.Unix() - 1)},
+		},
+	}
+
+	for name, tc := range testMatrix {
+		t.Run(name, func(t *testing.T) {
+			value, err := createTestRequestContext().ReplaceVariables(tc.inputData)
+			assert.NoError(t, err)
+
+			parts := strings.Split(value, ".")
+			if !assert.Len(t, parts, 3) {
+				return
+			}
+			b, err := base64.RawURLEncoding.DecodeString(parts[1])
+			assert.NoError(t, err)
+			var claims map[string]interface{}
+			assert.NoError(t, json.Unmarshal(b, &claims))
+			for k, v := range tc.expectClaims {
+				assert.Equal(t, v, claims[k], k)
+			}
+		})
+	}
+
+	unresolved := map[string]string{
+		"Missing Variable": `{{ jwt sub=header.missing }}`,
+		"Unquoted String":  `{{ jwt sub=user }}`,
+		"Invalid Claim":    `{{ jwt sub }}`,
+		"Invalid Offset":   `{{ jwt exp=+1x }}`,
+		"Other Prefix":     `{{ jwtx }}`,
+	}
+	for name, data := range unresolved {
+		t.Run(name, func(t *testing.T) {
+			value, err := createTestRequestContext().ReplaceVariables(data)
+			assert.NoError(t, err)
+			assert.Equal(t, data, value)
+		})
+	}
+
+	t.Run("No Signer", func(t *testing.T) {
+		jwt.Default = nil
+		defer func() { jwt.Default = signer }()
+		value, err := createTestRequestContext().ReplaceVariables(`{{ jwt | default "none" }}`)
+		assert.NoError(t, err)
+		assert.Equal(t, "none", value)
+	})
+}
+
+func TestValidateJWTVariables(t *testing.T) {
+	testMatrix := map[string]struct {
+		inputData   string
+		expectError bool
+	}{
+		"Valid":          {inputData: `{{ jwt sub=body.user exp=+1h scope="a b" aud=["x"] }}`},
+		"Missing Value":  {inputData: `{{ jwt sub= }}`, expectError: true},
+		"Not Name Value": {inputData: `{{ jwt sub }}`, expectError: true},
+		"Invalid Offset": {inputData: `{{ jwt exp=+1q }}`, expectError: true},
+		"Invalid Array":  {inputData: `{{ jwt aud=[x] }}`, expectError: true},
+	}
+
+	for name, tc := range testMatrix {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateVariables(tc.inputData)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -4,163 +4,302 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strconv"
 	"time"
 
 	"github.com/brianvoe/gofakeit/v7"
 )
 
-// RandomFunc is a function type that returns a random string on call
-type RandomFunc func() string
+// RandomFunc is a function type that returns a random string on call using the provided Faker. Arguments
+// given to the variable, e.g. {{ $randomInt(1, 500) }}, are passed as args
+type RandomFunc func(f *gofakeit.Faker, args ...string) (string, error)
 
 // RandomMap is a map of the random variables and the RandomFuncs that generate them
 var RandomMap = map[string]RandomFunc{
 	// common
-	"guid":         gofakeit.UUID,
-	"timestamp":    timeNowUnixString,
-	"isoTimestamp": timeNowIso,
+	"guid":         RandomWrapper((*gofakeit.Faker).UUID),
+	"timestamp":    RandomWrapper(timeNowUnixString),
+	"isoTimestamp": RandomWrapper(timeNowIso),
+	"oneOf":        oneOf,
 	// text, numbers and colors
-	"randomAlphaNumberic": gofakeit.Letter,
-	"randomBoolean":       RandomWrapper(gofakeit.Bool),
+	"randomAlphaNumberic": randomAlphaNumeric,
+	"randomBoolean":       RandomWrapper((*gofakeit.Faker).Bool),
 	"randomInt":           randomInt,
-	"randomColor":         gofakeit.Color,
-	"randomHexColor":      gofakeit.HexColor,
-	"randomAbbreviation":  gofakeit.HackerAbbreviation,
+	"randomColor":         RandomWrapper((*gofakeit.Faker).Color),
+	"randomHexColor":      RandomWrapper((*gofakeit.Faker).HexColor),
+	"randomDate":          randomDate,
+	"randomAbbreviation":  RandomWrapper((*gofakeit.Faker).HackerAbbreviation),
 	// internet and ip addresses
-	"randomIPV4":       gofakeit.IPv4Address,
-	"randomIPV6":       gofakeit.IPv6Address,
-	"randomMacAddress": gofakeit.MacAddress,
+	"randomIPV4":       RandomWrapper((*gofakeit.Faker).IPv4Address),
+	"randomIPV6":       RandomWrapper((*gofakeit.Faker).IPv6Address),
+	"randomMacAddress": RandomWrapper((*gofakeit.Faker).MacAddress),
 	"randomPassword":   randomPassword,
-	"randomUserAgent":  gofakeit.UserAgent,
-	"randomSemver":     gofakeit.AppVersion,
+	"randomUserAgent":  RandomWrapper((*gofakeit.Faker).UserAgent),
+	"randomSemver":     RandomWrapper((*gofakeit.Faker).AppVersion),
 	// names
-	"randomFirstName":  gofakeit.FirstName,
-	"randomLastName":   gofakeit.LastName,
-	"randomNamePrefix": gofakeit.NamePrefix,
-	"randomNameSuffix": gofakeit.NameSuffix,
+	"randomFirstName":  RandomWrapper((*gofakeit.Faker).FirstName),
+	"randomLastName":   RandomWrapper((*gofakeit.Faker).LastName),
+	"randomNamePrefix": RandomWrapper((*gofakeit.Faker).NamePrefix),
+	"randomNameSuffix": RandomWrapper((*gofakeit.Faker).NameSuffix),
 	// profession
-	"randomJobTitle": gofakeit.JobTitle,
-	"randomJobType":  gofakeit.JobDescriptor,
+	"randomJobTitle": RandomWrapper((*gofakeit.Faker).JobTitle),
+	"randomJobType":  RandomWrapper((*gofakeit.Faker).JobDescriptor),
 	// phone, address and location
-	"randomPhoneNumber": gofakeit.PhoneFormatted,
-	"randomCity":        gofakeit.City,
-	"randomStreetName":  gofakeit.Street,
-	"randomCountry":     gofakeit.Country,
-	"randomCountryCode": gofakeit.CountryAbr,
-	"randomLongitude":   RandomWrapper(gofakeit.Longitude),
-	"randomLatitude":    RandomWrapper(gofakeit.Latitude),
+	"randomPhoneNumber": RandomWrapper((*gofakeit.Faker).PhoneFormatted),
+	"randomCity":        RandomWrapper((*gofakeit.Faker).City),
+	"randomStreetName":  RandomWrapper((*gofakeit.Faker).Street),
+	"randomCountry":     RandomWrapper((*gofakeit.Faker).Country),
+	"randomCountryCode": RandomWrapper((*gofakeit.Faker).CountryAbr),
+	"randomLongitude":   RandomWrapper((*gofakeit.Faker).Longitude),
+	"randomLatitude":    RandomWrapper((*gofakeit.Faker).Latitude),
 	// finance
-	"randomCreditCard":   randomCreditCard,
-	"randomCurrencyCode": gofakeit.CurrencyShort,
-	"randomCurrencyName": gofakeit.CurrencyLong,
-	"randomBitcoin":      gofakeit.BitcoinAddress,
+	"randomCreditCard":   RandomWrapper(randomCreditCard),
+	"randomCurrencyCode": RandomWrapper((*gofakeit.Faker).CurrencyShort),
+	"randomCurrencyName": RandomWrapper((*gofakeit.Faker).CurrencyLong),
+	"randomBitcoin":      RandomWrapper((*gofakeit.Faker).BitcoinAddress),
 	// business
-	"randomCompany":       gofakeit.Company,
-	"randomCompanySuffix": gofakeit.CompanySuffix,
-	"randomBs":            gofakeit.BS,
+	"randomCompany":       RandomWrapper((*gofakeit.Faker).Company),
+	"randomCompanySuffix": RandomWrapper((*gofakeit.Faker).CompanySuffix),
+	"randomBs":            RandomWrapper((*gofakeit.Faker).BS),
 	// catchphrases
-	"randomCatchPhrase":          gofakeit.Phrase,
-	"randomCatchPhraceAdjective": gofakeit.Adjective,
-	"randomCatchPhraseNoun":      gofakeit.Noun,
+	"randomCatchPhrase":          RandomWrapper((*gofakeit.Faker).Phrase),
+	"randomCatchPhraceAdjective": RandomWrapper((*gofakeit.Faker).Adjective),
+	"randomCatchPhraseNoun":      RandomWrapper((*gofakeit.Faker).Noun),
 	// domains, emails and usernames
-	"randomDomainName":   gofakeit.DomainName,
-	"randomDomainSuffix": gofakeit.DomainSuffix,
-	"randomEmail":        gofakeit.Email,
-	"randomUserName":     gofakeit.Username,
-	"randomUrl":          gofakeit.URL,
+	"randomDomainName":   RandomWrapper((*gofakeit.Faker).DomainName),
+	"randomDomainSuffix": RandomWrapper((*gofakeit.Faker).DomainSuffix),
+	"randomEmail":        RandomWrapper((*gofakeit.Faker).Email),
+	"randomUserName":     RandomWrapper((*gofakeit.Faker).Username),
+	"randomUrl":          RandomWrapper((*gofakeit.Faker).URL),
 	// files and directories
-	"randomFileExt": gofakeit.FileExtension,
+	"randomFileExt": RandomWrapper((*gofakeit.Faker).FileExtension),
 	// stores
 	"randomPrice":            randomPrice,
-	"randomProduct":          gofakeit.ProductName,
-	"randomProductMaterial":  gofakeit.ProductMaterial,
-	"randomProduectCategory": gofakeit.ProductCategory,
+	"randomProduct":          RandomWrapper((*gofakeit.Faker).ProductName),
+	"randomProductMaterial":  RandomWrapper((*gofakeit.Faker).ProductMaterial),
+	"randomProduectCategory": RandomWrapper((*gofakeit.Faker).ProductCategory),
 	// grammar
-	"randomNoun":      gofakeit.Noun,
-	"randomVerb":      gofakeit.Verb,
-	"randomIngverb":   gofakeit.VerbAction,
-	"randomAdjective": gofakeit.Adjective,
-	"randomWord":      gofakeit.Word,
+	"randomNoun":      RandomWrapper((*gofakeit.Faker).Noun),
+	"randomVerb":      RandomWrapper((*gofakeit.Faker).Verb),
+	"randomIngverb":   RandomWrapper((*gofakeit.Faker).VerbAction),
+	"randomAdjective": RandomWrapper((*gofakeit.Faker).Adjective),
+	"randomWord":      RandomWrapper((*gofakeit.Faker).Word),
 	"randomWords":     randomSentence,
-	"randomPhrase":    gofakeit.Phrase,
+	"randomPhrase":    RandomWrapper((*gofakeit.Faker).Phrase),
 	// lorem ipsum
-	"randomLoremWord":       gofakeit.LoremIpsumWord,
+	"randomLoremWord":       RandomWrapper((*gofakeit.Faker).LoremIpsumWord),
 	"randomLoremWords":      randomLoremWords,
-	"randomLoremSentence":   randomLoremSentence,
+	"randomLoremSentence":   RandomWrapper(randomLoremSentence),
 	"randomLoremSentences":  randomLoremSentences,
-	"randomLoremParagraph":  randomLoremParagraph,
+	"randomLoremParagraph":  RandomWrapper(randomLoremParagraph),
 	"randomLoremParagraphs": randomLoremParagraphs,
 	// server specific
-	"hostname": getHostname,
-	"goos":     getGoos,
-	"goarch":   getGoarch,
+	"hostname": RandomWrapper(getHostname),
+	"goos":     RandomWrapper(getGoos),
+	"goarch":   RandomWrapper(getGoarch),
 }
 
-// RandomWrapper converts a function that returns any value to a RandomFunc type
-func RandomWrapper[T any](randFunc func() T) RandomFunc {
-	return func() string {
-		return fmt.Sprintf("%v", randFunc())
+// RandomWrapper converts a function that returns any value to a RandomFunc type. The
+// returned RandomFunc does not accept arguments
+func RandomWrapper[T any](randFunc func(f *gofakeit.Faker) T) RandomFunc {
+	return func(f *gofakeit.Faker, args ...string) (string, error) {
+		err := checkArgs(args, 0, 0)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%v", randFunc(f)), nil
 	}
 }
 
+// checkArgs returns an error if the number of args is not between min and max
+func checkArgs(args []string, min, max int) error {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return fmt.Errorf("%w - expected %d arguments, got %d", ErrInvalidRandomArgs, min, len(args))
+		}
+		return fmt.Errorf("%w - expected %d to %d arguments, got %d", ErrInvalidRandomArgs, min, max, len(args))
+	}
+	return nil
+}
+
+// intArgs parses args as integers, returning defaults for missing values
+func intArgs(args []string, defaults ...int) ([]int, error) {
+	err := checkArgs(args, 0, len(defaults))
+	if err != nil {
+		return nil, err
+	}
+
+	vals := append([]int{}, defaults...)
+	for i, a := range args {
+		vals[i], err = strconv.Atoi(a)
+		if err != nil {
+			return nil, fmt.Errorf("%w - %s is not an integer", ErrInvalidRandomArgs, a)
+		}
+	}
+	return vals, nil
+}
+
+// floatArgs parses args as floats, returning defaults for missing values
+func floatArgs(args []string, defaults ...float64) ([]float64, error) {
+	err := checkArgs(args, 0, len(defaults))
+	if err != nil {
+		return nil, err
+	}
+
+	vals := append([]float64{}, defaults...)
+	for i, a := range args {
+		vals[i], err = strconv.ParseFloat(a, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w - %s is not a number", ErrInvalidRandomArgs, a)
+		}
+	}
+	return vals, nil
+}
+
+// rangeArgs parses a min and max range from args, returning an error if min is greater than max
+func rangeArgs(args []string, min, max int) (int, int, error) {
+	if len(args) == 1 {
+		return 0, 0, fmt.Errorf("%w - expected both a min and max", ErrInvalidRandomArgs)
+	}
+	vals, err := intArgs(args, min, max)
+	if err != nil {
+		return 0, 0, err
+	}
+	if vals[0] > vals[1] {
+		return 0, 0, fmt.Errorf("%w - min %d is greater than max %d", ErrInvalidRandomArgs, vals[0], vals[1])
+	}
+	return vals[0], vals[1], nil
+}
+
+// countArg parses a single positive count from args
+func countArg(args []string, def int) (int, error) {
+	vals, err := intArgs(args, def)
+	if err != nil {
+		return 0, err
+	}
+	if vals[0] < 1 {
+		return 0, fmt.Errorf("%w - %d must be greater than zero", ErrInvalidRandomArgs, vals[0])
+	}
+	return vals[0], nil
+}
+
 // the following functions are implementations of the RandomFunc type which aren't found in the gofakeit library
 
-func timeNowUnixString() string {
-	return fmt.Sprint(time.Now().Unix())
+func timeNowUnixString(_ *gofakeit.Faker) string {
+	return fmt.Sprint(Now().Unix())
+}
+
+func timeNowIso(_ *gofakeit.Faker) string {
+	return Now().Format(time.RFC3339)
+}
+
+func oneOf(f *gofakeit.Faker, args ...string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("%w - expected at least one argument", ErrInvalidRandomArgs)
+	}
+	return f.RandomString(args), nil
+}
+
+func randomAlphaNumeric(f *gofakeit.Faker, args ...string) (string, error) {
+	n, err := countArg(args, 1)
+	if err != nil {
+		return "", err
+	}
+	return f.LetterN(uint(n)), nil
 }
 
-func timeNowIso() string {
-	return time.Now().Format(time.RFC3339)
+func randomInt(f *gofakeit.Faker, args ...string) (string, error) {
+	min, max, err := rangeArgs(args, 0, 100)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v", f.Number(min, max)), nil
 }
 
-func randomInt() string {
-	return fmt.Sprintf("%v", gofakeit.Number(0, 100))
+func randomDate(f *gofakeit.Faker, args ...string) (string, error) {
+	err := checkArgs(args, 0, 1)
+	if err != nil {
+		return "", err
+	}
+	layout := time.RFC3339
+	if len(args) == 1 {
+		layout = args[0]
+	}
+	return f.Date().Format(layout), nil
 }
 
-func randomPassword() string {
-	return gofakeit.Password(true, true, true, true, false, 12)
+func randomPassword(f *gofakeit.Faker, args ...string) (string, error) {
+	n, err := countArg(args, 12)
+	if err != nil {
+		return "", err
+	}
+	return f.Password(true, true, true, true, false, n), nil
 }
 
-func randomCreditCard() string {
-	return gofakeit.CreditCard().Number
+func randomCreditCard(f *gofakeit.Faker) string {
+	return f.CreditCard().Number
 }
 
-func getHostname() string {
+func getHostname(_ *gofakeit.Faker) string {
 	hostname, _ := os.Hostname()
 	return hostname
 }
 
-func getGoos() string {
+func getGoos(_ *gofakeit.Faker) string {
 	return runtime.GOOS
 }
 
-func getGoarch() string {
+func getGoarch(_ *gofakeit.Faker) string {
 	return runtime.GOARCH
 }
 
-func randomPrice() string {
-	return fmt.Sprintf("%f", gofakeit.Price(0, 1000))
+func randomPrice(f *gofakeit.Faker, args ...string) (string, error) {
+	vals, err := floatArgs(args, 0, 1000)
+	if err != nil {
+		return "", err
+	}
+	if vals[0] > vals[1] {
+		return "", fmt.Errorf("%w - min %v is greater than max %v", ErrInvalidRandomArgs, vals[0], vals[1])
+	}
+	return fmt.Sprintf("%f", f.Price(vals[0], vals[1])), nil
 }
 
-func randomSentence() string {
-	return gofakeit.Sentence(20)
+func randomSentence(f *gofakeit.Faker, args ...string) (string, error) {
+	n, err := countArg(args, 20)
+	if err != nil {
+		return "", err
+	}
+	return f.Sentence(n), nil
 }
 
-func randomLoremWords() string {
-	return gofakeit.LoremIpsumSentence(20)
+func randomLoremWords(f *gofakeit.Faker, args ...string) (string, error) {
+	n, err := countArg(args, 20)
+	if err != nil {
+		return "", err
+	}
+	return f.LoremIpsumSentence(n), nil
 }
 
-func randomLoremSentence() string {
-	return gofakeit.LoremIpsumSentence(1)
+func randomLoremSentence(f *gofakeit.Faker) string {
+	return f.LoremIpsumSentence(1)
 }
 
-func randomLoremSentences() string {
-	return gofakeit.LoremIpsumSentence(5)
+func randomLoremSentences(f *gofakeit.Faker, args ...string) (string, error) {
+	n, err := countArg(args, 5)
+	if err != nil {
+		return "", err
+	}
+	return f.LoremIpsumSentence(n), nil
 }
 
-func randomLoremParagraph() string {
-	return gofakeit.LoremIpsumParagraph(1, 5, 12, "")
+func randomLoremParagraph(f *gofakeit.Faker) string {
+	return f.LoremIpsumParagraph(1, 5, 12, "")
 }
 
-func randomLoremParagraphs() string {
-	return gofakeit.LoremIpsumParagraph(3, 5, 12, "\n")
+func randomLoremParagraphs(f *gofakeit.Faker, args ...string) (string, error) {
+	n, err := countArg(args, 3)
+	if err != nil {
+		return "", err
+	}
+	return f.LoremIpsumParagraph(n, 5, 12, "\n"), nil
 }
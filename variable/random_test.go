@@ -1,8 +1,11 @@
 package variable
 
 import (
+	"net/http"
 	"testing"
 
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -23,7 +26,7 @@ func TestGetRandomVariable(t *testing.T) {
 
 	for name, tc := range testMatrix {
 		t.Run(name, func(t *testing.T) {
-			_, err := getRandomVariable(tc.inputVariable)
+			_, err := getRandomVariable(gofakeit.GlobalFaker, tc.inputVariable)
 			if tc.expectError {
 				assert.Error(t, err)
 			} else {
@@ -34,10 +37,149 @@ func TestGetRandomVariable(t *testing.T) {
 }
 
 func TestRandomMap(t *testing.T) {
+	// required arguments for random variables
+	args := map[string][]string{
+		"oneOf": {"a", "b"},
+	}
+
 	for name, tc := range RandomMap {
 		t.Run(name, func(t *testing.T) {
-			val := tc()
+			val, err := tc(gofakeit.GlobalFaker, args[name]...)
+			assert.NoError(t, err)
 			assert.NotEmpty(t, val)
 		})
 	}
 }
+
+func TestRandomArgs(t *testing.T) {
+	testMatrix := map[string]struct {
+		inputVariable string
+		expectError   bool
+		check         func(t *testing.T, val string)
+	}{
+		"randomInt Range": {
+			inputVariable: "randomInt(1, 1)",
+			check:         func(t *testing.T, val string) { assert.Equal(t, "1", val) },
+		},
+		"randomInt Negative Range": {
+			inputVariable: "randomInt(-5,-5)",
+			check:         func(t *testing.T, val string) { assert.Equal(t, "-5", val) },
+		},
+		"randomInt Single Argument": {
+			inputVariable: "randomInt(1)",
+			expectError:   true,
+		},
+		"randomInt Min Greater Than Max": {
+			inputVariable: "randomInt(500, 1)",
+			expectError:   true,
+		},
+		"randomInt Not Integer": {
+			inputVariable: "randomInt(a, 10)",
+			expectError:   true,
+		},
+		"randomPassword Length": {
+			inputVariable: "randomPassword(32)",
+			check:         func(t *testing.T, val string) { assert.Len(t, val, 32) },
+		},
+		"randomPassword Zero Length": {
+			inputVariable: "randomPassword(0)",
+			expectError:   true,
+		},
+		"randomAlphaNumberic Length": {
+			inputVariable: "randomAlphaNumberic(8)",
+			check:         func(t *testing.T, val string) { assert.Len(t, val, 8) },
+		},
+		"randomDate Format": {
+			inputVariable: `randomDate("2006-01-02")`,
+			check:         func(t *testing.T, val string) { assert.Regexp(t, `^\d{4}-\d{2}-\d{2}$`, val) },
+		},
+		"oneOf": {
+			inputVariable: `oneOf(a, "b, c", 'd')`,
+			check: func(t *testing.T, val string) {
+				assert.Contains(t, []string{"a", "b, c", "d"}, val)
+			},
+		},
+		"oneOf No Arguments": {
+			inputVariable: "oneOf()",
+			expectError:   true,
+		},
+		"Unclosed Quote": {
+			inputVariable: `oneOf("a, b)`,
+			expectError:   true,
+		},
+		"Arguments Not Accepted": {
+			inputVariable: "guid(1)",
+			expectError:   true,
+		},
+	}
+
+	for name, tc := range testMatrix {
+		t.Run(name, func(t *testing.T) {
+			val, err := getRandomVariable(gofakeit.GlobalFaker, tc.inputVariable)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			tc.check(t, val)
+		})
+	}
+}
+
+func TestSeededRandom(t *testing.T) {
+	newInstance := func(seed string, id string) *VariableInstance {
+		r, _ := http.NewRequest("GET", "http://test:8080/users/"+id, nil)
+		if seed != "" {
+			r.Header.Set(SeedHeader, seed)
+		}
+		return NewVariableInstance(r, nil, httprouter.Params{{Key: "id", Value: id}})
+	}
+	render := func(t *testing.T, r interface {
+		ReplaceVariables(string) (string, error)
+	}, data string) string {
+		val, err := r.ReplaceVariables(data)
+		assert.NoError(t, err)
+		return val
+	}
+	data := "{{ $guid }} {{ $randomFirstName }} {{ $randomInt(1, 1000000) }}"
+
+	t.Run("Seed", func(t *testing.T) {
+		Seed = 42
+		defer func() { Seed = 0 }()
+		assert.Equal(t, render(t, newInstance("", "1"), data), render(t, newInstance("", "1"), data))
+	})
+
+	t.Run("Seed Header", func(t *testing.T) {
+		a := render(t, newInstance("42", "1"), data)
+		assert.Equal(t, a, render(t, newInstance("42", "1"), data))
+		assert.NotEqual(t, a, render(t, newInstance("43", "1"), data))
+	})
+
+	t.Run("Unseeded", func(t *testing.T) {
+		assert.NotEqual(t, render(t, newInstance("", "1"), data), render(t, newInstance("", "1"), data))
+	})
+
+	t.Run("Stable by Key", func(t *testing.T) {
+		keyed := "{{ $guid key=param.id }} {{ $randomInt(1, 1000000) key=param.id }}"
+		a := render(t, newInstance("", "10"), keyed)
+		assert.Equal(t, a, render(t, newInstance("", "10"), keyed))
+		assert.NotEqual(t, a, render(t, newInstance("", "11"), keyed))
+	})
+
+	t.Run("Unresolved Key", func(t *testing.T) {
+		keyed := "{{ $guid key=header.missing }}"
+		assert.Equal(t, keyed, render(t, newInstance("", "10"), keyed))
+	})
+
+	t.Run("Template", func(t *testing.T) {
+		tmpl := func(seed, id string) *templateInstance {
+			return &templateInstance{VariableInstance: newInstance(seed, id)}
+		}
+		data := `{{ random "guid" }} {{ randomKey .Params.id "guid" }}`
+		a := render(t, tmpl("42", "10"), data)
+		assert.Equal(t, a, render(t, tmpl("42", "10"), data))
+
+		keyed := `{{ randomKey .Params.id "guid" }}`
+		assert.Equal(t, render(t, tmpl("", "10"), keyed), render(t, tmpl("", "10"), keyed))
+	})
+}
@@ -5,32 +5,92 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/brianvoe/gofakeit/v7"
 )
 
 // varRegex holds the compiled regular expression for matching variables
 var varRegex = regexp.MustCompile(VariableRegexp)
 
-// ReplaceVariables replaces all variables with the pattern {{ variable }} in the data string with their corresponding values
-func (r *variableInstance) ReplaceVariables(data string) (string, error) {
-	varInstances := varRegex.FindAllString(data, -1)
+// UnresolvedVariable is a variable which could not be resolved along with the reason
+type UnresolvedVariable struct {
+	// Variable is the variable instance including braces, e.g. {{ header.x-tenant }}
+	Variable string
+
+	// Err is the reason the variable could not be resolved
+	Err error
+}
+
+// UnresolvedError is returned in strict mode when variables cannot be resolved
+type UnresolvedError struct {
+	Variables []UnresolvedVariable
+}
+
+// Error lists the unresolved variables and their reasons
+func (e *UnresolvedError) Error() string {
+	s := make([]string, len(e.Variables))
+	for i, v := range e.Variables {
+		s[i] = fmt.Sprintf("%s - %s", v.Variable, v.Err)
+	}
+	return "unresolved variables: " + strings.Join(s, ", ")
+}
+
+// Unwrap returns the errors of the unresolved variables
+func (e *UnresolvedError) Unwrap() []error {
+	errs := make([]error, len(e.Variables))
+	for i, v := range e.Variables {
+		errs[i] = v.Err
+	}
+	return errs
+}
 
+// ReplaceVariables replaces all variables with the pattern {{ variable }} in the data string with their corresponding values.
+// Variables which cannot be resolved are left as is, in strict mode they are also returned as an UnresolvedError
+func (r *VariableInstance) ReplaceVariables(data string) (string, error) {
+	return r.Render(Compile(data))
+}
+
+// ValidateVariables checks the random variables, time variables and filters within data, returning an error
+// for unknown random variables, filters or invalid arguments. Request variables can only be resolved per request and are
+// not checked
+func ValidateVariables(data string) error {
 	errs := []error{}
-	for _, v := range varInstances {
-		replacement, err := r.ParseVariable(removeBraces(v))
+	for _, loc := range varRegex.FindAllStringIndex(data, -1) {
+		if loc[0] > 0 && data[loc[0]-1] == '\\' {
+			continue
+		}
+		v := data[loc[0]:loc[1]]
+		variable, filters, err := splitFilters(removeBraces(v))
+		if err == nil {
+			err = validateFilters(filters)
+		}
 		if err != nil {
-			err = fmt.Errorf("%s: %w", v, err)
-			errs = append(errs, err)
+			errs = append(errs, fmt.Errorf("%s: %w", v, err))
+			continue
+		}
 
-			// on error replace variable instance with blank string
-			replacement = ""
+		err = validateTimeVariable(variable)
+		if err == nil {
+			err = validateJWTVariable(variable)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", v, err))
+			continue
 		}
-		data = strings.Replace(data, v, replacement, 1)
-	}
 
-	if len(errs) > 0 {
-		return data, errors.Join(errs...)
+		variable, ok := strings.CutPrefix(variable, RandomPrefix)
+		if !ok {
+			continue
+		}
+		if m := keyRegex.FindStringSubmatch(variable); m != nil {
+			variable = m[1]
+		}
+		_, err = getRandomVariable(gofakeit.GlobalFaker, variable)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", v, err))
+		}
 	}
-	return data, nil
+	return errors.Join(errs...)
 }
 
 func removeBraces(data string) string {
@@ -67,6 +67,21 @@ func TestReplaceVariables(t *testing.T) {
 			expectError: false,
 			expectValue: "Test Random Data: randomValue randomValue",
 		},
+		"Valid Random With Arguments": {
+			inputData:   "Test Random Data: {{ $randomInt(7, 7) }} {{$oneOf('a')}}",
+			expectError: false,
+			expectValue: "Test Random Data: 7 a",
+		},
+		"Invalid Random Arguments": {
+			inputData:   "Test Random Data: {{ $randomInt(7) }}",
+			expectError: false,
+			expectValue: "Test Random Data: {{ $randomInt(7) }}",
+		},
+		"Valid JSONPath": {
+			inputData:   "Test Body Data: {{ body['test'] }}",
+			expectError: false,
+			expectValue: "Test Body Data: body",
+		},
 		"Invalid Random": {
 			inputData:   "Test Random Data: {{$badRandom}}",
 			expectError: false,
@@ -114,3 +129,86 @@ func TestReplaceVariables(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateVariables(t *testing.T) {
+	testMatrix := map[string]struct {
+		inputData   string
+		expectError bool
+	}{
+		"Valid":             {inputData: "{{ $randomInt(1, 500) }} {{ $guid }} {{ header.x }}"},
+		"Unknown Random":    {inputData: "{{ $badRandom }}", expectError: true},
+		"Invalid Arguments": {inputData: "{{ $randomInt(500, 1) }}", expectError: true},
+		"No Variables":      {inputData: "hello"},
+	}
+
+	for name, tc := range testMatrix {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateVariables(tc.inputData)
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestStrictReplaceVariables(t *testing.T) {
+	testMatrix := map[string]struct {
+		inputData       string
+		expectValue     string
+		expectVariables []string
+	}{
+		"Resolved":         {inputData: "{{ header.testheader }}", expectValue: "headervalue"},
+		"Default":          {inputData: `{{ header.x-tenant | default "none" }}`, expectValue: "none"},
+		"Escaped":          {inputData: `\{{ header.x-tenant }}`, expectValue: "{{ header.x-tenant }}"},
+		"Missing Header":   {inputData: "tenant {{ header.x-tenant }}", expectValue: "tenant {{ header.x-tenant }}", expectVariables: []string{"{{ header.x-tenant }}"}},
+		"Multiple Missing": {inputData: "{{ query.a }} {{ header.testheader }} {{ param.b }}", expectValue: "{{ query.a }} headervalue {{ param.b }}", expectVariables: []string{"{{ query.a }}", "{{ param.b }}"}},
+		"Unknown Prefix":   {inputData: "{{ bad.prefix }}", expectValue: "{{ bad.prefix }}", expectVariables: []string{"{{ bad.prefix }}"}},
+	}
+
+	for name, tc := range testMatrix {
+		t.Run(name, func(t *testing.T) {
+			r := createTestRequestContext()
+			r.SetStrict(true)
+			value, err := r.ReplaceVariables(tc.inputData)
+			assert.Equal(t, tc.expectValue, value)
+			if len(tc.expectVariables) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			var unresolved *UnresolvedError
+			if assert.ErrorAs(t, err, &unresolved) {
+				variables := []string{}
+				for _, v := range unresolved.Variables {
+					variables = append(variables, v.Variable)
+					assert.Error(t, v.Err)
+				}
+				assert.Equal(t, tc.expectVariables, variables)
+			}
+		})
+	}
+
+	t.Run("Not Strict", func(t *testing.T) {
+		r := createTestRequestContext()
+		value, err := r.ReplaceVariables("tenant {{ header.x-tenant }}")
+		assert.NoError(t, err)
+		assert.Equal(t, "tenant {{ header.x-tenant }}", value)
+		if assert.Len(t, r.Unresolved(), 1) {
+			assert.Equal(t, "{{ header.x-tenant }}", r.Unresolved()[0].Variable)
+		}
+
+		_, err = r.ReplaceVariables("{{ header.testheader }}")
+		assert.NoError(t, err)
+		assert.Empty(t, r.Unresolved())
+	})
+
+	t.Run("Wrapped Errors", func(t *testing.T) {
+		r := createTestRequestContext()
+		r.SetStrict(true)
+		_, err := r.ReplaceVariables("{{ environment.MOCKITOUT_MISSING }}")
+		assert.ErrorIs(t, err, ErrEnvironmentVariable)
+		assert.Contains(t, err.Error(), "{{ environment.MOCKITOUT_MISSING }}")
+	})
+}
@@ -0,0 +1,161 @@
+package variable
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidRequestVar is returned when the request variable is not known
+	ErrInvalidRequestVar = errors.New("unknown request variable")
+
+	// ErrNoTLS is returned when a TLS variable is used on a request without TLS
+	ErrNoTLS = errors.New("request is not using tls")
+
+	// ErrNoClientCert is returned when a client certificate variable is used without a client certificate
+	ErrNoClientCert = errors.New("no client certificate provided")
+
+	// ErrCookieVariable is returned when the cookie is not found
+	ErrCookieVariable = errors.New("cookie not found")
+)
+
+// RequestIDHeader is the request header used to provide the request.id variable
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the context key holding the request ID
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx holding the request ID used by the request.id variable
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID held by ctx, an empty string is returned if no ID is set
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// getRequestVariable returns metadata of the request, e.g. {{ request.method }} or {{ request.tls.client_cn }}
+func (r *VariableInstance) getRequestVariable(variable string) (string, error) {
+	switch variable {
+	case "method":
+		return r.r.Method, nil
+	case "path":
+		return r.r.URL.Path, nil
+	case "url":
+		return r.requestURL(), nil
+	case "host":
+		return r.r.Host, nil
+	case "remote_addr":
+		return r.r.RemoteAddr, nil
+	case "proto":
+		return r.r.Proto, nil
+	case "id":
+		return r.requestID(), nil
+	}
+
+	cutVariable, ok := strings.CutPrefix(variable, "tls.")
+	if ok {
+		return getTLSVariable(r.r.TLS, cutVariable)
+	}
+	return "", ErrInvalidRequestVar
+}
+
+// requestURL returns the full URL of the request, server requests only hold the path and query within URL
+func (r *VariableInstance) requestURL() string {
+	if r.r.URL.IsAbs() {
+		return r.r.URL.String()
+	}
+
+	u := *r.r.URL
+	u.Scheme = "http"
+	if r.r.TLS != nil {
+		u.Scheme = "https"
+	}
+	u.Host = r.r.Host
+	return u.String()
+}
+
+// requestID returns the ID of the request from the request context or RequestIDHeader, when neither are set
+// an ID is generated for the instance
+func (r *VariableInstance) requestID() string {
+	if id := RequestID(r.r.Context()); id != "" {
+		return id
+	}
+	if id := r.r.Header.Get(RequestIDHeader); id != "" {
+		return id
+	}
+
+	id := r.getFaker().UUID()
+	r.r = r.r.WithContext(WithRequestID(r.r.Context(), id))
+	return id
+}
+
+// getTLSVariable returns details of the TLS connection and client certificate
+func getTLSVariable(state *tls.ConnectionState, variable string) (string, error) {
+	if state == nil {
+		return "", ErrNoTLS
+	}
+
+	switch variable {
+	case "version":
+		return tls.VersionName(state.Version), nil
+	case "cipher_suite":
+		return tls.CipherSuiteName(state.CipherSuite), nil
+	case "server_name":
+		return state.ServerName, nil
+	}
+
+	cutVariable, ok := strings.CutPrefix(variable, "client_")
+	if !ok {
+		return "", ErrInvalidRequestVar
+	}
+	if len(state.PeerCertificates) == 0 {
+		return "", ErrNoClientCert
+	}
+	cert := state.PeerCertificates[0]
+
+	switch cutVariable {
+	case "cn":
+		return cert.Subject.CommonName, nil
+	case "subject":
+		return cert.Subject.String(), nil
+	case "issuer":
+		return cert.Issuer.String(), nil
+	case "serial":
+		return cert.SerialNumber.String(), nil
+	case "sans":
+		return strings.Join(CertSANs(cert), ","), nil
+	case "verified":
+		return strconv.FormatBool(len(state.VerifiedChains) > 0), nil
+	}
+	return "", ErrInvalidRequestVar
+}
+
+// CertSANs returns the subject alternative names of the certificate
+func CertSANs(cert *x509.Certificate) []string {
+	sans := append([]string{}, cert.DNSNames...)
+	sans = append(sans, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, u := range cert.URIs {
+		sans = append(sans, u.String())
+	}
+	return sans
+}
+
+// getCookieVariable returns the value of the named request cookie
+func (r *VariableInstance) getCookieVariable(variable string) (string, error) {
+	c, err := r.r.Cookie(variable)
+	if err != nil {
+		return "", fmt.Errorf("%w - %s", ErrCookieVariable, err)
+	}
+	return c.Value, nil
+}
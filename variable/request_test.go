@@ -0,0 +1,120 @@
+package variable
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestVariables(t *testing.T) {
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest("PUT", "/users/10?type=admin", nil)
+		r.AddCookie(&http.Cookie{Name: "session", Value: "abc123"})
+		return r
+	}
+
+	clientCert := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "client.example.com", Organization: []string{"MockItOut"}},
+		Issuer:         pkix.Name{CommonName: "Test CA"},
+		SerialNumber:   big.NewInt(42),
+		DNSNames:       []string{"client.example.com"},
+		EmailAddresses: []string{"client@example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("127.0.0.1")},
+		URIs:           []*url.URL{{Scheme: "spiffe", Host: "example.com", Path: "/client"}},
+	}
+	withTLS := func(certs ...*x509.Certificate) *http.Request {
+		r := newRequest()
+		r.TLS = &tls.ConnectionState{
+			Version:          tls.VersionTLS13,
+			CipherSuite:      tls.TLS_AES_128_GCM_SHA256,
+			ServerName:       "example.com",
+			PeerCertificates: certs,
+		}
+		return r
+	}
+
+	testMatrix := map[string]struct {
+		request       *http.Request
+		inputVariable string
+		expectError   bool
+		expectValue   string
+	}{
+		"Method":          {request: newRequest(), inputVariable: "request.method", expectValue: "PUT"},
+		"Path":            {request: newRequest(), inputVariable: "request.path", expectValue: "/users/10"},
+		"URL":             {request: newRequest(), inputVariable: "request.url", expectValue: "http://example.com/users/10?type=admin"},
+		"URL TLS":         {request: withTLS(), inputVariable: "request.url", expectValue: "https://example.com/users/10?type=admin"},
+		"Host":            {request: newRequest(), inputVariable: "request.host", expectValue: "example.com"},
+		"Remote Address":  {request: newRequest(), inputVariable: "request.remote_addr", expectValue: "192.0.2.1:1234"},
+		"Proto":           {request: newRequest(), inputVariable: "request.proto", expectValue: "HTTP/1.1"},
+		"Unknown":         {request: newRequest(), inputVariable: "request.bad", expectError: true},
+		"Cookie":          {request: newRequest(), inputVariable: "cookie.session", expectValue: "abc123"},
+		"Missing Cookie":  {request: newRequest(), inputVariable: "cookie.missing", expectError: true},
+		"TLS Version":     {request: withTLS(), inputVariable: "request.tls.version", expectValue: "TLS 1.3"},
+		"TLS Cipher":      {request: withTLS(), inputVariable: "request.tls.cipher_suite", expectValue: "TLS_AES_128_GCM_SHA256"},
+		"TLS Server Name": {request: withTLS(), inputVariable: "request.tls.server_name", expectValue: "example.com"},
+		"No TLS":          {request: newRequest(), inputVariable: "request.tls.version", expectError: true},
+		"Client CN":       {request: withTLS(clientCert), inputVariable: "request.tls.client_cn", expectValue: "client.example.com"},
+		"Client Subject":  {request: withTLS(clientCert), inputVariable: "request.tls.client_subject", expectValue: "CN=client.example.com,O=MockItOut"},
+		"Client Issuer":   {request: withTLS(clientCert), inputVariable: "request.tls.client_issuer", expectValue: "CN=Test CA"},
+		"Client Serial":   {request: withTLS(clientCert), inputVariable: "request.tls.client_serial", expectValue: "42"},
+		"Client SANs": {
+			request:       withTLS(clientCert),
+			inputVariable: "request.tls.client_sans",
+			expectValue:   "client.example.com,client@example.com,127.0.0.1,spiffe://example.com/client",
+		},
+		"No Client Cert":  {request: withTLS(), inputVariable: "request.tls.client_cn", expectError: true},
+		"Unknown TLS":     {request: withTLS(clientCert), inputVariable: "request.tls.bad", expectError: true},
+		"Unknown Client":  {request: withTLS(clientCert), inputVariable: "request.tls.client_bad", expectError: true},
+		"Client Verified": {request: withTLS(clientCert), inputVariable: "request.tls.client_verified", expectValue: "false"},
+	}
+
+	for name, tc := range testMatrix {
+		t.Run(name, func(t *testing.T) {
+			value, err := NewVariableInstance(tc.request, nil, nil).ParseVariable(tc.inputVariable)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectValue, value)
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	t.Run("Context", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r = r.WithContext(WithRequestID(r.Context(), "ctx-id"))
+		r.Header.Set(RequestIDHeader, "header-id")
+		value, err := NewVariableInstance(r, nil, nil).ReplaceVariables("{{ request.id }}")
+		assert.NoError(t, err)
+		assert.Equal(t, "ctx-id", value)
+	})
+
+	t.Run("Header", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set(RequestIDHeader, "header-id")
+		value, err := NewVariableInstance(r, nil, nil).ReplaceVariables("{{ request.id }}")
+		assert.NoError(t, err)
+		assert.Equal(t, "header-id", value)
+	})
+
+	t.Run("Generated", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		v := NewVariableInstance(r, nil, nil)
+		a, err := v.ReplaceVariables("{{ request.id }}")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, a)
+		b, err := v.ReplaceVariables("{{ request.id }}")
+		assert.NoError(t, err)
+		assert.Equal(t, a, b)
+	})
+}
@@ -0,0 +1,105 @@
+package variable
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrResolverExists is returned when registering a Resolver for a prefix which is already registered
+var ErrResolverExists = errors.New("resolver already registered for prefix")
+
+// Resolver resolves variables for a registered prefix. The variable is provided with the prefix removed, e.g. a
+// Resolver registered as "vault." is given "secret" for the variable {{ vault.secret }}
+type Resolver interface {
+	Resolve(r *VariableInstance, variable string) (string, error)
+}
+
+// ResolverFunc is an adapter to allow ordinary functions to be used as a Resolver
+type ResolverFunc func(r *VariableInstance, variable string) (string, error)
+
+// Resolve calls f(r, variable)
+func (f ResolverFunc) Resolve(r *VariableInstance, variable string) (string, error) {
+	return f(r, variable)
+}
+
+// resolvers is the registry of Resolvers by prefix
+var resolvers = map[string]Resolver{}
+
+// resolversMu protects the resolvers registry
+var resolversMu sync.RWMutex
+
+// resolversGeneration is incremented on every registry change, compiled Templates re-lookup their Resolvers
+// when it has changed
+var resolversGeneration atomic.Uint64
+
+func init() {
+	// register the built-in resolvers
+	resolvers[RandomPrefix] = ResolverFunc((*VariableInstance).getRandomVariable)
+	resolvers[HeaderPrefix] = ResolverFunc((*VariableInstance).getHeaderVariable)
+	resolvers[QueryPrefix] = ResolverFunc((*VariableInstance).getQueryVariable)
+	resolvers[ParamPrefix] = ResolverFunc((*VariableInstance).getParamVariable)
+	resolvers[EnvPrefix] = ResolverFunc((*VariableInstance).getEnvironmentVariable)
+	resolvers[BodyPrefix] = ResolverFunc((*VariableInstance).getBodyJsonVariable)
+	resolvers[FormPrefix] = ResolverFunc((*VariableInstance).getFormVariable)
+	resolvers[MultipartPrefix] = ResolverFunc((*VariableInstance).getMultipartVariable)
+	resolvers[MultipartFilePrefix] = ResolverFunc((*VariableInstance).getMultipartFileVariable)
+	resolvers[XmlPrefix] = ResolverFunc((*VariableInstance).getXmlVariable)
+	resolvers[RequestPrefix] = ResolverFunc((*VariableInstance).getRequestVariable)
+	resolvers[CookiePrefix] = ResolverFunc((*VariableInstance).getCookieVariable)
+	resolvers[NowPrefix] = ResolverFunc((*VariableInstance).getNowVariable)
+	resolvers[DatePrefix] = ResolverFunc((*VariableInstance).getDateVariable)
+	resolvers[JWTPrefix] = ResolverFunc((*VariableInstance).getJWTVariable)
+	resolvers[TextBody] = ResolverFunc(func(r *VariableInstance, variable string) (string, error) {
+		// bracketed selectors such as body['key'] are JSONPath expressions
+		if strings.HasPrefix(variable, "[") {
+			return r.getBodyJsonVariable(variable)
+		}
+		// otherwise only the exact body variable is matched
+		if len(variable) > 0 {
+			return "", ErrInvalidVariablePrefix
+		}
+		return r.getTextBody(TextBody)
+	})
+}
+
+// RegisterResolver registers the Resolver for variables starting with prefix, e.g. "vault.". When multiple
+// prefixes match a variable the longest prefix is used
+func RegisterResolver(prefix string, r Resolver) error {
+	if len(prefix) == 0 {
+		return ErrInvalidVariablePrefix
+	}
+
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	if _, ok := resolvers[prefix]; ok {
+		return ErrResolverExists
+	}
+	resolvers[prefix] = r
+	resolversGeneration.Add(1)
+	return nil
+}
+
+// UnregisterResolver removes the Resolver registered for prefix
+func UnregisterResolver(prefix string) {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	delete(resolvers, prefix)
+	resolversGeneration.Add(1)
+}
+
+// lookupResolver returns the Resolver with the longest prefix matching the variable
+func lookupResolver(variable string) (string, Resolver, bool) {
+	resolversMu.RLock()
+	defer resolversMu.RUnlock()
+
+	var prefix string
+	var resolver Resolver
+	for p, r := range resolvers {
+		if len(p) > len(prefix) && len(variable) >= len(p) && variable[:len(p)] == p {
+			prefix, resolver = p, r
+		}
+	}
+	return prefix, resolver, resolver != nil
+}
@@ -0,0 +1,71 @@
+package variable
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterResolver(t *testing.T) {
+	fixtures := map[string]string{"user": "Jim"}
+	err := RegisterResolver("fixture.", ResolverFunc(func(r *VariableInstance, variable string) (string, error) {
+		v, ok := fixtures[variable]
+		if !ok {
+			return "", ErrInvalidVariableFormat
+		}
+		return v + " " + r.Request().Header.Get("testheader"), nil
+	}))
+	assert.NoError(t, err)
+	defer UnregisterResolver("fixture.")
+
+	err = RegisterResolver("fixture.user.", ResolverFunc(func(r *VariableInstance, variable string) (string, error) {
+		return strings.ToUpper(variable), nil
+	}))
+	assert.NoError(t, err)
+	defer UnregisterResolver("fixture.user.")
+
+	t.Run("Duplicate Prefix", func(t *testing.T) {
+		assert.ErrorIs(t, RegisterResolver("fixture.", ResolverFunc(nil)), ErrResolverExists)
+	})
+
+	t.Run("Empty Prefix", func(t *testing.T) {
+		assert.Error(t, RegisterResolver("", ResolverFunc(nil)))
+	})
+
+	testMatrix := map[string]struct {
+		inputData   string
+		expectValue string
+	}{
+		"Custom Prefix":  {"{{ fixture.user }}", "Jim headervalue"},
+		"Longest Prefix": {"{{ fixture.user.name }}", "NAME"},
+		"Unresolved":     {"{{ fixture.missing }}", "{{ fixture.missing }}"},
+		"Built-in":       {"{{ header.testheader }} {{ body }}", `headervalue {"test": "body", "testint": 10}`},
+		"Text Body Only": {"{{ bodyx }}", "{{ bodyx }}"},
+	}
+
+	for name, tc := range testMatrix {
+		t.Run(name, func(t *testing.T) {
+			r := createTestRequestContext()
+			value, err := r.ReplaceVariables(tc.inputData)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectValue, value)
+		})
+	}
+
+	t.Run("Template", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "http://test:8080/", nil)
+		req.Header.Set("testheader", "headervalue")
+		value, err := NewTemplateInstance(req, nil, nil).ReplaceVariables(`{{ variable "fixture.user" }}`)
+		assert.NoError(t, err)
+		assert.Equal(t, "Jim headervalue", value)
+	})
+
+	t.Run("Unregister", func(t *testing.T) {
+		UnregisterResolver("fixture.user.")
+		r := createTestRequestContext()
+		_, err := r.ParseVariable("fixture.user.name")
+		assert.Error(t, err)
+	})
+}
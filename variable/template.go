@@ -0,0 +1,194 @@
+package variable
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/julienschmidt/httprouter"
+)
+
+// TemplateData is the request data exposed to Go templates.
+type TemplateData struct {
+	// Method is the HTTP method of the request.
+	Method string
+
+	// Path is the URL path of the request.
+	Path string
+
+	// Headers is a map of request headers using their canonical names, only the first
+	// value of each header is kept.
+	Headers map[string]string
+
+	// Query is a map of query parameters, only the first value of each is kept.
+	Query map[string]string
+
+	// Params is a map of router path parameters.
+	Params map[string]string
+
+	// Body is the request body. JSON bodies are decoded into maps and slices, other
+	// bodies are provided as a string.
+	Body interface{}
+
+	// RawBody is the unparsed request body.
+	RawBody string
+
+	// Env is a map of environment variables.
+	Env map[string]string
+}
+
+// templateInstance replaces variables using the Go text/template engine.
+type templateInstance struct {
+	*VariableInstance
+
+	// data is the request data, loaded on first use as the body can only be read once.
+	data *TemplateData
+}
+
+// NewTemplateInstance creates a new template instance with the request, response writer and params
+func NewTemplateInstance(r *http.Request, w http.ResponseWriter, p httprouter.Params) *templateInstance {
+	return &templateInstance{
+		VariableInstance: NewVariableInstance(r, w, p),
+	}
+}
+
+// ReplaceVariables executes data as a Go template against the request data
+func (t *templateInstance) ReplaceVariables(data string) (string, error) {
+	c, err := CompileTemplate(data)
+	if err != nil {
+		return data, err
+	}
+	return t.Render(c)
+}
+
+// ValidateTemplate checks that data is a valid Go template.
+func ValidateTemplate(data string) error {
+	_, err := parseTemplate(data)
+	return err
+}
+
+// parseTemplate parses data as a Go template with the template function library.
+func parseTemplate(data string) (*template.Template, error) {
+	tmpl, err := template.New("").Funcs(templateFuncs).Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template - %w", err)
+	}
+	return tmpl, nil
+}
+
+// templateFuncs is the function library available within templates, this is the sprig
+// library with the addition of the random variables, now uses the virtual clock.
+var templateFuncs = func() template.FuncMap {
+	f := sprig.TxtFuncMap()
+	f["now"] = func() time.Time {
+		return Now()
+	}
+	f["random"] = func(name string, args ...interface{}) (string, error) {
+		return templateRandom(gofakeit.GlobalFaker, name, args...)
+	}
+	f["variable"] = func(name string) (string, error) {
+		return "", ErrInvalidVariablePrefix
+	}
+	f["randomKey"] = func(key interface{}, name string, args ...interface{}) (string, error) {
+		return templateRandom(keyedFaker(name, fmt.Sprint(key)), name, args...)
+	}
+	return f
+}()
+
+// templateRandom returns the named random variable, e.g. {{ random "guid" }} or
+// {{ random "randomInt" 1 500 }}.
+func templateRandom(f *gofakeit.Faker, name string, args ...interface{}) (string, error) {
+	fn, ok := RandomMap[strings.TrimPrefix(name, RandomPrefix)]
+	if !ok {
+		return "", ErrInvalidRandomVariable
+	}
+
+	strArgs := make([]string, len(args))
+	for i, a := range args {
+		strArgs[i] = fmt.Sprint(a)
+	}
+	return fn(f, strArgs...)
+}
+
+// newBoundTemplate returns a function creating boundTemplate copies of tmpl with the
+// execution option set.
+func newBoundTemplate(tmpl *template.Template, option string) func() interface{} {
+	return func() interface{} {
+		b := &boundTemplate{}
+		b.tmpl = template.Must(tmpl.Clone()).Option(option).Funcs(b.funcs())
+		return b
+	}
+}
+
+// boundTemplate is a copy of a compiled template whose functions are bound to the
+// instance executing it.
+type boundTemplate struct {
+	tmpl     *template.Template
+	instance *templateInstance
+}
+
+// funcs returns the template functions bound to the executing instance, random values
+// use the request's Faker and variables are resolved with the registered Resolvers.
+func (b *boundTemplate) funcs() template.FuncMap {
+	return template.FuncMap{
+		"random": func(name string, args ...interface{}) (string, error) {
+			return templateRandom(b.instance.getFaker(), name, args...)
+		},
+		"variable": func(name string) (string, error) {
+			return b.instance.ParseVariable(name)
+		},
+	}
+}
+
+// templateData collects the request data exposed to templates.
+func (t *templateInstance) templateData() *TemplateData {
+	d := &TemplateData{
+		Headers: make(map[string]string),
+		Query:   make(map[string]string),
+		Params:  make(map[string]string),
+		Env:     make(map[string]string),
+	}
+
+	for _, e := range os.Environ() {
+		k, v, _ := strings.Cut(e, "=")
+		d.Env[k] = v
+	}
+
+	for _, p := range t.p {
+		d.Params[p.Key] = p.Value
+	}
+
+	if t.r == nil {
+		return d
+	}
+
+	d.Method = t.r.Method
+	if t.r.URL != nil {
+		d.Path = t.r.URL.Path
+		for k, v := range t.r.URL.Query() {
+			d.Query[k] = v[0]
+		}
+	}
+
+	for k, v := range t.r.Header {
+		d.Headers[k] = v[0]
+	}
+
+	body, err := t.readBody()
+	if err == nil {
+		d.RawBody = string(body)
+		d.Body = d.RawBody
+
+		v, err := t.jsonBody()
+		if err == nil {
+			d.Body = v
+		}
+	}
+
+	return d
+}
@@ -0,0 +1,120 @@
+package variable
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestTemplateInstance() *templateInstance {
+	RandomMap["randomMock"] = RandomWrapper(func(_ *gofakeit.Faker) string {
+		return "randomValue"
+	})
+
+	body := strings.NewReader(`{"name": "Jim", "roles": ["admin", "user"]}`)
+	r, _ := http.NewRequest("POST", "http://test:8080/users/10?type=admin", body)
+	r.Header.Add("X-Test", "headervalue")
+	p := httprouter.Params{{Key: "id", Value: "10"}}
+
+	return NewTemplateInstance(r, nil, p)
+}
+
+func TestTemplateReplaceVariables(t *testing.T) {
+	os.Setenv("testenv", "envvalue")
+	defer os.Unsetenv("testenv")
+
+	testMatrix := map[string]struct {
+		inputData   string
+		expectError bool
+		expectValue string
+	}{
+		"Request Data": {
+			inputData:   `{{ .Method }} {{ .Path }} {{ .Params.id }} {{ index .Headers "X-Test" }} {{ .Env.testenv }}`,
+			expectValue: "POST /users/10 10 headervalue envvalue",
+		},
+		"Conditional": {
+			inputData:   `{{ if eq .Query.type "admin" }}admin{{ else }}user{{ end }}`,
+			expectValue: "admin",
+		},
+		"Range": {
+			inputData:   `{{ .Body.name }}:{{ range $i, $r := .Body.roles }}{{ if $i }},{{ end }}{{ $r }}{{ end }}`,
+			expectValue: "Jim:admin,user",
+		},
+		"Functions": {
+			inputData:   `{{ .Body.name | upper }} {{ .Query.missing | default "none" }} {{ random "randomMock" }}`,
+			expectValue: "JIM none randomValue",
+		},
+		"Invalid Template": {
+			inputData:   `{{ if }}`,
+			expectError: true,
+			expectValue: `{{ if }}`,
+		},
+		"Invalid Random": {
+			inputData:   `{{ random "badRandom" }}`,
+			expectError: true,
+			expectValue: "",
+		},
+	}
+
+	for name, test := range testMatrix {
+		t.Run(name, func(t *testing.T) {
+			r := createTestTemplateInstance()
+			value, err := r.ReplaceVariables(test.inputData)
+			if test.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.expectValue, value)
+		})
+	}
+
+	t.Run("Body Read Once", func(t *testing.T) {
+		r := createTestTemplateInstance()
+		for i := 0; i < 2; i++ {
+			value, err := r.ReplaceVariables(`{{ .Body.name }}`)
+			assert.NoError(t, err)
+			assert.Equal(t, "Jim", value)
+		}
+	})
+
+	t.Run("Clock", func(t *testing.T) {
+		defer func(now func() time.Time) { Now = now }(Now)
+		Now = func() time.Time { return time.Date(2030, time.January, 1, 23, 59, 59, 0, time.UTC) }
+
+		r := createTestTemplateInstance()
+		value, err := r.ReplaceVariables(`{{ (now).UTC.Format "2006-01-02 15:04:05" }}`)
+		assert.NoError(t, err)
+		assert.Equal(t, "2030-01-01 23:59:59", value)
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		r := createTestTemplateInstance()
+		r.SetStrict(true)
+		value, err := r.ReplaceVariables(`{{ .Query.type }}`)
+		assert.NoError(t, err)
+		assert.Equal(t, "admin", value)
+
+		_, err = r.ReplaceVariables(`{{ .Query.page }}`)
+		var unresolved *UnresolvedError
+		if assert.ErrorAs(t, err, &unresolved) {
+			assert.Equal(t, "{{ .Query.page }}", unresolved.Variables[0].Variable)
+		}
+
+		// missing keys are allowed outside of strict mode
+		r.SetStrict(false)
+		_, err = r.ReplaceVariables(`{{ .Query.page }}`)
+		assert.NoError(t, err)
+	})
+}
+
+func TestValidateTemplate(t *testing.T) {
+	assert.NoError(t, ValidateTemplate(`{{ .Body.name | default "x" }}`))
+	assert.Error(t, ValidateTemplate(`{{ .Body.name | notAFunction }}`))
+}
@@ -0,0 +1,289 @@
+package variable
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/madflojo/mockitout/clock"
+)
+
+var (
+	// ErrInvalidTimeArgs is returned when the arguments given to a time variable are invalid
+	ErrInvalidTimeArgs = errors.New("error invalid time variable arguments")
+
+	// ErrInvalidDate is returned when the date given to a date variable cannot be parsed
+	ErrInvalidDate = errors.New("unable to parse date")
+)
+
+const (
+	// NowPrefix is the variable returning the current time, e.g. {{ now "+1h" "2006-01-02" }}
+	NowPrefix = "now"
+
+	// DatePrefix is the variable parsing and shifting a date from another variable, e.g.
+	// {{ date body.start "+30d" }}
+	DatePrefix = "date"
+)
+
+// Now returns the current time used by time variables, by default the virtual clock
+var Now = clock.Now
+
+// timeFormats maps the named formats of time variables to their layouts, unix formats are handled separately
+var timeFormats = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"iso":         time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"rfc1123":     time.RFC1123,
+	"date":        time.DateOnly,
+	"time":        time.TimeOnly,
+	"datetime":    time.DateTime,
+}
+
+// dateLayouts are the layouts tried in order when parsing dates without an explicit layout
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	time.DateTime,
+	time.DateOnly,
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// timeOptions are the parsed arguments of a time variable
+type timeOptions struct {
+	// offsets are applied in order to the time
+	offsets []timeOffset
+
+	// loc is the time zone the time is converted to and naive dates are parsed in
+	loc *time.Location
+
+	// format is the output format, either a named format or a Go time layout
+	format string
+
+	// parse is the layout used to parse dates, when empty dateLayouts are tried
+	parse string
+}
+
+// timeOffset is a relative change to a time, e.g. +1d12h
+type timeOffset struct {
+	years, months, days int
+	duration            time.Duration
+}
+
+// getNowVariable returns the current time, optionally shifted, converted and formatted, e.g.
+// {{ now "-7d" unix_ms }} or {{ now tz=Europe/London "15:04" }}
+func (r *VariableInstance) getNowVariable(variable string) (string, error) {
+	args, err := timeArgs(variable)
+	if err != nil {
+		return "", err
+	}
+	opts, err := parseTimeOptions(args)
+	if err != nil {
+		return "", err
+	}
+	return opts.render(Now()), nil
+}
+
+// getDateVariable parses the date held by another variable, optionally shifting, converting and formatting
+// it, e.g. {{ date body.start "+30d" "2006-01-02" }}
+func (r *VariableInstance) getDateVariable(variable string) (string, error) {
+	args, err := timeArgs(variable)
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "", ErrInvalidTimeArgs
+	}
+	opts, err := parseTimeOptions(args[1:])
+	if err != nil {
+		return "", err
+	}
+
+	value, err := r.ParseVariable(args[0])
+	if err != nil {
+		return "", err
+	}
+	t, err := opts.parseDate(value)
+	if err != nil {
+		return "", err
+	}
+	return opts.render(t), nil
+}
+
+// timeArgs splits the arguments of a time variable, the arguments must be separated from the variable name
+func timeArgs(variable string) ([]string, error) {
+	if variable != "" && !unicode.IsSpace(rune(variable[0])) {
+		return nil, ErrInvalidVariablePrefix
+	}
+	return splitFields(variable)
+}
+
+// validateTimeVariable checks the arguments of now and date variables, other variables are ignored
+func validateTimeVariable(variable string) error {
+	var args []string
+	var err error
+	if rest, ok := strings.CutPrefix(variable, NowPrefix); ok {
+		args, err = timeArgs(rest)
+	} else if rest, ok := strings.CutPrefix(variable, DatePrefix); ok {
+		args, err = timeArgs(rest)
+		if err == nil && len(args) == 0 {
+			return ErrInvalidTimeArgs
+		}
+		if len(args) > 0 {
+			args = args[1:]
+		}
+	} else {
+		return nil
+	}
+	if errors.Is(err, ErrInvalidVariablePrefix) {
+		// a different variable sharing the prefix, e.g. {{ nowhere.x }}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = parseTimeOptions(args)
+	return err
+}
+
+// parseTimeOptions parses time variable arguments. Arguments starting with + or - are offsets, tz= selects
+// the time zone, parse= the layout of parsed dates and any other argument is the output format
+func parseTimeOptions(args []string) (timeOptions, error) {
+	opts := timeOptions{}
+	for _, a := range args {
+		switch {
+		case strings.HasPrefix(a, "tz="):
+			loc, err := time.LoadLocation(strings.TrimPrefix(a, "tz="))
+			if err != nil {
+				return opts, fmt.Errorf("%w - %s", ErrInvalidTimeArgs, err)
+			}
+			opts.loc = loc
+		case strings.HasPrefix(a, "parse="):
+			opts.parse = strings.TrimPrefix(a, "parse=")
+		case len(a) > 1 && (a[0] == '+' || a[0] == '-') && unicode.IsDigit(rune(a[1])):
+			o, err := parseOffset(a)
+			if err != nil {
+				return opts, err
+			}
+			opts.offsets = append(opts.offsets, o)
+		case opts.format != "":
+			return opts, fmt.Errorf("%w - multiple formats %q and %q", ErrInvalidTimeArgs, opts.format, a)
+		default:
+			opts.format = a
+		}
+	}
+	return opts, nil
+}
+
+// parseOffset parses an offset such as +30d, -1h30m or +1y6M. Supported units are y (years), M (months),
+// w (weeks), d (days), h (hours), m (minutes), s (seconds) and ms (milliseconds)
+func parseOffset(s string) (timeOffset, error) {
+	o := timeOffset{}
+	sign := 1
+	if s[0] == '-' {
+		sign = -1
+	}
+
+	rest := s[1:]
+	for len(rest) > 0 {
+		i := strings.IndexFunc(rest, func(c rune) bool { return !unicode.IsDigit(c) })
+		if i <= 0 {
+			return o, fmt.Errorf("%w - invalid offset %q", ErrInvalidTimeArgs, s)
+		}
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil {
+			return o, fmt.Errorf("%w - invalid offset %q", ErrInvalidTimeArgs, s)
+		}
+		n *= sign
+		rest = rest[i:]
+
+		unit := rest[:1]
+		if strings.HasPrefix(rest, "ms") {
+			unit = "ms"
+		}
+		rest = rest[len(unit):]
+
+		switch unit {
+		case "y":
+			o.years += n
+		case "M":
+			o.months += n
+		case "w":
+			o.days += 7 * n
+		case "d":
+			o.days += n
+		case "h":
+			o.duration += time.Duration(n) * time.Hour
+		case "m":
+			o.duration += time.Duration(n) * time.Minute
+		case "s":
+			o.duration += time.Duration(n) * time.Second
+		case "ms":
+			o.duration += time.Duration(n) * time.Millisecond
+		default:
+			return o, fmt.Errorf("%w - invalid offset unit %q", ErrInvalidTimeArgs, unit)
+		}
+	}
+	return o, nil
+}
+
+// parseDate parses the date using the parse layout or the default layouts. Numeric dates are unix timestamps,
+// in seconds or milliseconds when 13 or more digits are given
+func (o timeOptions) parseDate(value string) (time.Time, error) {
+	loc := o.loc
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	if o.parse != "" {
+		t, err := time.ParseInLocation(o.parse, value, loc)
+		if err != nil {
+			return t, fmt.Errorf("%w - %s", ErrInvalidDate, err)
+		}
+		return t, nil
+	}
+
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if len(strings.TrimPrefix(value, "-")) >= 13 {
+			return time.UnixMilli(n).In(loc), nil
+		}
+		return time.Unix(n, 0).In(loc), nil
+	}
+
+	for _, l := range dateLayouts {
+		t, err := time.ParseInLocation(l, value, loc)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w - %q", ErrInvalidDate, value)
+}
+
+// render applies the offsets and time zone to t and formats it, RFC 3339 is used by default
+func (o timeOptions) render(t time.Time) string {
+	if o.loc != nil {
+		t = t.In(o.loc)
+	}
+	for _, off := range o.offsets {
+		t = t.AddDate(off.years, off.months, off.days).Add(off.duration)
+	}
+
+	switch o.format {
+	case "":
+		return t.Format(time.RFC3339)
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "unix_ms":
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	case "http":
+		return t.UTC().Format(http.TimeFormat)
+	}
+	if layout, ok := timeFormats[o.format]; ok {
+		return t.Format(layout)
+	}
+	return t.Format(o.format)
+}
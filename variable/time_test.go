@@ -0,0 +1,82 @@
+package variable
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeVariables(t *testing.T) {
+	fixed := time.Date(2024, time.January, 31, 10, 30, 0, 0, time.UTC)
+	defer func(now func() time.Time) { Now = now }(Now)
+	Now = func() time.Time { return fixed }
+
+	newRequest := func() *VariableInstance {
+		r, _ := http.NewRequest("POST", "http://test:8080/schedule?at=1706697000", strings.NewReader(`{"start": "2024-02-10", "end": "2024-02-10T08:00:00+02:00", "ms": 1706697000000, "uk": "10/02/2024"}`))
+		return NewVariableInstance(r, nil, nil)
+	}
+
+	testMatrix := map[string]struct {
+		inputData   string
+		expectValue string
+	}{
+		"Now":               {`{{ now }}`, "2024-01-31T10:30:00Z"},
+		"Offset And Layout": {`{{ now "+1h" "2006-01-02 15:04" }}`, "2024-01-31 11:30"},
+		"Negative Days":     {`{{ now "-7d" unix_ms }}`, "1706092200000"},
+		"Unix":              {`{{ now unix }}`, "1706697000"},
+		"Compound Offset":   {`{{ now +1d12h30m datetime }}`, "2024-02-01 23:00:00"},
+		"Months":            {`{{ now +1M date }}`, "2024-03-02"},
+		"Years And Weeks":   {`{{ now -1y +2w date }}`, "2023-02-14"},
+		"Milliseconds":      {`{{ now +1500ms rfc3339nano }}`, "2024-01-31T10:30:01.5Z"},
+		"Time Zone":         {`{{ now tz=America/New_York }}`, "2024-01-31T05:30:00-05:00"},
+		"Time Zone Layout":  {`{{ now "tz=Asia/Tokyo" "15:04 MST" }}`, "19:30 JST"},
+		"HTTP Date":         {`{{ now "+1h" http }}`, "Wed, 31 Jan 2024 11:30:00 GMT"},
+		"Date From Body":    {`{{ date body.start "+30d" date }}`, "2024-03-11"},
+		"Date With Zone":    {`{{ date body.end tz=UTC }}`, "2024-02-10T06:00:00Z"},
+		"Date From Unix":    {`{{ date query.at "+1h" }}`, "2024-01-31T11:30:00Z"},
+		"Date From Unix MS": {`{{ date body.ms unix }}`, "1706697000"},
+		"Date Parse Layout": {`{{ date body.uk parse=02/01/2006 "+1d" date }}`, "2024-02-11"},
+		"Date With Filter":  {`{{ date body.start "Jan 2" | upper }}`, "FEB 10"},
+		"Date Missing":      {`{{ date body.missing }}`, "{{ date body.missing }}"},
+		"Date Invalid":      {`{{ date query.none | default "n/a" }}`, "n/a"},
+		"Not Parsable":      {`{{ date body.uk }}`, "{{ date body.uk }}"},
+		"Invalid Offset":    {`{{ now "+1x" }}`, `{{ now "+1x" }}`},
+		"Timestamp":         {`{{ $timestamp }}`, "1706697000"},
+	}
+
+	for name, tc := range testMatrix {
+		t.Run(name, func(t *testing.T) {
+			value, err := newRequest().ReplaceVariables(tc.inputData)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectValue, value)
+		})
+	}
+}
+
+func TestValidateTimeVariables(t *testing.T) {
+	testMatrix := map[string]struct {
+		inputData   string
+		expectError bool
+	}{
+		"Valid":             {inputData: `{{ now "+1h" "2006-01-02" }} {{ date body.start -7d tz=Europe/London unix }}`},
+		"Invalid Offset":    {inputData: `{{ now "+1q" }}`, expectError: true},
+		"Invalid Time Zone": {inputData: `{{ now tz=Mars/Olympus }}`, expectError: true},
+		"Multiple Formats":  {inputData: `{{ now unix date }}`, expectError: true},
+		"Date Without Var":  {inputData: `{{ date }}`, expectError: true},
+		"Other Variable":    {inputData: `{{ header.date }} {{ nowhere.x }}`},
+	}
+
+	for name, tc := range testMatrix {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateVariables(tc.inputData)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
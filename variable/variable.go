@@ -1,15 +1,20 @@
 package variable
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"hash/fnv"
 	"net/http"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/brianvoe/gofakeit/v7"
 	"github.com/julienschmidt/httprouter"
+	"github.com/ohler55/ojg/jp"
 )
 
 var (
@@ -22,6 +27,9 @@ var (
 	// ErrRandomVariable is returned when the random variable is not found
 	ErrInvalidRandomVariable = errors.New("error random variable not found")
 
+	// ErrInvalidRandomArgs is returned when the arguments given to a random variable are invalid
+	ErrInvalidRandomArgs = errors.New("error invalid random variable arguments")
+
 	// ErrEnvironmentVariable is returned when the environment variable is not found
 	ErrEnvironmentVariable = errors.New("environment variable not found")
 
@@ -37,86 +45,212 @@ var (
 
 const (
 	// VariableRegexp is the regular expression to match variables with the format {{ variable }}
-	VariableRegexp = `\{\{[\w\-\s._$]+\}\}`
+	// or {{ variable(arg, ...) key=variable | filter arg ... }}. Bracketed selectors such as [0] or
+	// [?(@.id == 1)], quoted arguments and XPath expressions are allowed within the variable name
+	VariableRegexp = `\{\{(?:[\w\-\s._$*/:@=+]|\[[^\[\]{}]*\]|"[^"{}]*"|'[^'{}]*')+(\([^(){}]*\))?(\s+key=[\w\-.$]+)?` +
+		`(\s*\|\s*\w+(?:\s+(?:"[^"{}]*"|'[^'{}]*'|[^\s"'{}|]+))*)*\s*\}\}`
+
+	// SeedHeader is the request header used to override the Seed for a single request
+	SeedHeader = "X-Random-Seed"
 
 	// Variable prefixes
-	RandomPrefix = "$"
-	HeaderPrefix = "header."
-	QueryPrefix  = "query."
-	ParamPrefix  = "param."
-	EnvPrefix    = "environment."
-	BodyPrefix   = "body."
+	RandomPrefix        = "$"
+	HeaderPrefix        = "header."
+	QueryPrefix         = "query."
+	ParamPrefix         = "param."
+	EnvPrefix           = "environment."
+	BodyPrefix          = "body."
+	TextBody            = "body"
+	FormPrefix          = "form."
+	MultipartPrefix     = "multipart."
+	MultipartFilePrefix = "multipart.file."
+	XmlPrefix           = "xml."
+	RequestPrefix       = "request."
+	CookiePrefix        = "cookie."
 )
 
-// variableInstance is a struct that holds the request, response writer, and params for a request instance
-type variableInstance struct {
+// Seed is the default seed used by random variables, when zero random values are not reproducible
+var Seed uint64
+
+// keyRegex matches the key=variable suffix of random variables
+var keyRegex = regexp.MustCompile(`^(.*?)\s+key=(\S+)$`)
+
+// VariableInstance is a struct that holds the request, response writer, and params for a request instance
+type VariableInstance struct {
 	r *http.Request
 	w http.ResponseWriter
 	p httprouter.Params
+
+	// faker generates random variables, it is created on first use
+	faker *gofakeit.Faker
+
+	// body is the buffered request body, read once on first use and shared by all body variables
+	body *requestBody
+
+	// strict reports variables which cannot be resolved as an UnresolvedError
+	strict bool
+
+	// unresolved are the variables left as is by the last render
+	unresolved []UnresolvedVariable
 }
 
 // NewVariableInstance creates a new variable instance with the request, response writer and params
-func NewVariableInstance(r *http.Request, w http.ResponseWriter, p httprouter.Params) *variableInstance {
-	return &variableInstance{
+func NewVariableInstance(r *http.Request, w http.ResponseWriter, p httprouter.Params) *VariableInstance {
+	return &VariableInstance{
 		r: r,
 		w: w,
 		p: p,
 	}
 }
 
-// ParseVariable takes the variable (with prefix e.g. $, header., ...) and returns the corresponding value or an error
-func (r *variableInstance) ParseVariable(variable string) (string, error) {
+// ParseVariable takes the variable (with prefix e.g. $, header., ...) and returns the corresponding value or an error.
+// The variable is resolved by the Resolver registered with the longest matching prefix
+func (r *VariableInstance) ParseVariable(variable string) (string, error) {
 	if len(variable) == 0 {
 		return "", ErrInvalidVariablePrefix
 	}
 
-	cutVariable, ok := strings.CutPrefix(variable, RandomPrefix)
-	if ok {
-		return getRandomVariable(cutVariable)
+	prefix, resolver, ok := lookupResolver(variable)
+	if !ok {
+		return "", ErrInvalidVariablePrefix
 	}
+	return resolver.Resolve(r, strings.TrimPrefix(variable, prefix))
+}
 
-	cutVariable, ok = strings.CutPrefix(variable, HeaderPrefix)
-	if ok {
-		return r.getHeaderVariable(cutVariable)
-	}
+// SetStrict enables or disables strict mode. In strict mode variables which cannot be resolved are still left
+// as is, but are reported as an UnresolvedError
+func (r *VariableInstance) SetStrict(strict bool) {
+	r.strict = strict
+}
 
-	cutVariable, ok = strings.CutPrefix(variable, QueryPrefix)
-	if ok {
-		return r.getQueryVariable(cutVariable)
+// Unresolved returns the variables which could not be resolved by the last ReplaceVariables or Render call,
+// including those left as is outside strict mode
+func (r *VariableInstance) Unresolved() []UnresolvedVariable {
+	return r.unresolved
+}
+
+// Request returns the HTTP request of the instance
+func (r *VariableInstance) Request() *http.Request {
+	return r.r
+}
+
+// ResponseWriter returns the HTTP response writer of the instance, this may be nil
+func (r *VariableInstance) ResponseWriter() http.ResponseWriter {
+	return r.w
+}
+
+// Params returns the router params of the instance
+func (r *VariableInstance) Params() httprouter.Params {
+	return r.p
+}
+
+// getRandomVariable generates the random variable using the request's Faker. When a key is given, e.g.
+// {{ $randomFirstName key=param.id }}, the value is generated from the key and is stable across requests
+func (r *VariableInstance) getRandomVariable(variable string) (string, error) {
+	m := keyRegex.FindStringSubmatch(variable)
+	if m == nil {
+		return getRandomVariable(r.getFaker(), variable)
 	}
 
-	cutVariable, ok = strings.CutPrefix(variable, ParamPrefix)
-	if ok {
-		return r.getParamVariable(cutVariable)
+	key, err := r.ParseVariable(m[2])
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve key %s - %w", m[2], err)
 	}
+	return getRandomVariable(keyedFaker(m[1], key), m[1])
+}
 
-	cutVariable, ok = strings.CutPrefix(variable, EnvPrefix)
-	if ok {
-		return r.getEnvironmentVariable(cutVariable)
+// getFaker returns the Faker used for the request, seeded from the SeedHeader or Seed
+func (r *VariableInstance) getFaker() *gofakeit.Faker {
+	if r.faker != nil {
+		return r.faker
 	}
 
-	cutVariable, ok = strings.CutPrefix(variable, BodyPrefix)
-	if ok {
-		return r.getBodyJsonVariable(cutVariable)
+	seed := Seed
+	if r.r != nil {
+		if v := r.r.Header.Get(SeedHeader); v != "" {
+			s, err := strconv.ParseUint(v, 10, 64)
+			if err == nil {
+				seed = s
+			}
+		}
 	}
 
-	if strings.Compare(variable, "body") == 0 {
-		return r.getTextBody(variable)
+	r.faker = gofakeit.GlobalFaker
+	if seed != 0 {
+		r.faker = gofakeit.New(seed)
 	}
+	return r.faker
+}
 
-	return "", ErrInvalidVariablePrefix
+// keyedFaker returns a Faker seeded from the variable, key and Seed
+func keyedFaker(variable, key string) *gofakeit.Faker {
+	h := fnv.New64a()
+	_ = binary.Write(h, binary.BigEndian, Seed)
+	h.Write([]byte(strings.TrimSpace(variable)))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return gofakeit.New(h.Sum64())
 }
 
-func getRandomVariable(variable string) (string, error) {
-	randVariableFunction, ok := RandomMap[variable]
+func getRandomVariable(f *gofakeit.Faker, variable string) (string, error) {
+	name, args, err := parseArgs(variable)
+	if err != nil {
+		return "", err
+	}
+
+	randVariableFunction, ok := RandomMap[name]
 	if !ok {
 		return "", ErrInvalidRandomVariable
 	}
 
-	return randVariableFunction(), nil
+	return randVariableFunction(f, args...)
+}
+
+// parseArgs splits a variable with the format name(arg, ...) into its name and arguments.
+// Arguments may be quoted with single or double quotes to include commas or spaces.
+func parseArgs(variable string) (string, []string, error) {
+	name, rest, ok := strings.Cut(variable, "(")
+	if !ok {
+		return strings.TrimSpace(variable), nil, nil
+	}
+	name = strings.TrimSpace(name)
+
+	rest = strings.TrimSpace(rest)
+	if !strings.HasSuffix(rest, ")") {
+		return name, nil, ErrInvalidVariableFormat
+	}
+	rest = strings.TrimSuffix(rest, ")")
+	if strings.TrimSpace(rest) == "" {
+		return name, nil, nil
+	}
+
+	var args []string
+	var arg strings.Builder
+	var quote rune
+	for _, c := range rest {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(c)
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			args = append(args, strings.TrimSpace(arg.String()))
+			arg.Reset()
+		default:
+			arg.WriteRune(c)
+		}
+	}
+	if quote != 0 {
+		return name, nil, ErrInvalidVariableFormat
+	}
+	args = append(args, strings.TrimSpace(arg.String()))
+
+	return name, args, nil
 }
 
-func (r *variableInstance) getHeaderVariable(variable string) (string, error) {
+func (r *VariableInstance) getHeaderVariable(variable string) (string, error) {
 	header := r.r.Header.Get(variable)
 	if len(header) == 0 {
 		return "", ErrInvalidVariableFormat
@@ -124,7 +258,7 @@ func (r *variableInstance) getHeaderVariable(variable string) (string, error) {
 	return header, nil
 }
 
-func (r *variableInstance) getQueryVariable(variable string) (string, error) {
+func (r *VariableInstance) getQueryVariable(variable string) (string, error) {
 	query := r.r.URL.Query().Get(variable)
 	if len(query) == 0 {
 		return "", ErrInvalidVariableFormat
@@ -132,7 +266,7 @@ func (r *variableInstance) getQueryVariable(variable string) (string, error) {
 	return query, nil
 }
 
-func (r *variableInstance) getParamVariable(variable string) (string, error) {
+func (r *VariableInstance) getParamVariable(variable string) (string, error) {
 	param := r.p.ByName(variable)
 	if len(param) == 0 {
 		return "", ErrInvalidVariableFormat
@@ -140,7 +274,7 @@ func (r *variableInstance) getParamVariable(variable string) (string, error) {
 	return param, nil
 }
 
-func (r *variableInstance) getEnvironmentVariable(variable string) (string, error) {
+func (r *VariableInstance) getEnvironmentVariable(variable string) (string, error) {
 	val := os.Getenv(variable)
 	if len(val) == 0 {
 		return "", ErrEnvironmentVariable
@@ -148,13 +282,8 @@ func (r *variableInstance) getEnvironmentVariable(variable string) (string, erro
 	return val, nil
 }
 
-func (r *variableInstance) getTextBody(variable string) (string, error) {
-	if r.r.Body == nil || r.r.ContentLength < 1 {
-		return "", ErrNoBody
-	}
-
-	reader := io.Reader(r.r.Body)
-	body, err := io.ReadAll(reader)
+func (r *VariableInstance) getTextBody(variable string) (string, error) {
+	body, err := r.readBody()
 	if err != nil {
 		return "", err
 	}
@@ -162,45 +291,43 @@ func (r *variableInstance) getTextBody(variable string) (string, error) {
 	return string(body), nil
 }
 
-func (r *variableInstance) getBodyJsonVariable(variable string) (string, error) {
-	if r.r.Body == nil || r.r.ContentLength < 1 {
-		return "", ErrNoBody
+// getBodyJsonVariable selects values from the JSON body using a JSONPath expression relative to the root,
+// e.g. {{ body.items[0].id }} or {{ body.users[?(@.admin)].name }}. A single result is returned as a string,
+// multiple results are returned as a JSON array
+func (r *VariableInstance) getBodyJsonVariable(variable string) (string, error) {
+	path := "$." + variable
+	if strings.HasPrefix(variable, "[") {
+		path = "$" + variable
 	}
-
-	jsonPath := strings.Split(variable, ".")
-	if len(jsonPath) == 0 {
-		return "", ErrInvalidJsonVar
+	x, err := jp.ParseString(path)
+	if err != nil {
+		return "", fmt.Errorf("%w - %s", ErrInvalidJsonVar, err)
 	}
 
-	var data interface{}
-	err := json.NewDecoder(r.r.Body).Decode(&data)
+	data, err := r.jsonBody()
 	if err != nil {
-		return "", ErrInvalidJsonBody
+		return "", err
 	}
 
-	for _, path := range jsonPath {
-		dataMap, ok := data.(map[string]interface{})
-		if !ok {
-			return "", ErrInvalidJsonBody
-		}
-
-		val, ok := dataMap[path]
-		if !ok {
-			return "", ErrInvalidJsonVar
-		}
+	results := x.Get(data)
+	if len(results) == 0 {
+		return "", ErrInvalidJsonVar
+	}
 
-		data = val
+	var value interface{} = results
+	if len(results) == 1 {
+		value = results[0]
 	}
 
 	// try return as string
-	if val, ok := data.(string); ok {
+	if val, ok := value.(string); ok {
 		return val, nil
 	}
 
 	// try return as json (or default to string)
-	jsonValue, err := json.Marshal(data)
+	jsonValue, err := json.Marshal(value)
 	if err != nil {
-		return fmt.Sprintf("%v", data), nil
+		return fmt.Sprintf("%v", value), nil
 	} else {
 		return string(jsonValue), nil
 	}
@@ -6,16 +6,17 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/brianvoe/gofakeit/v7"
 	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/assert"
 )
 
-func createTestRequestContext() *variableInstance {
-	r := variableInstance{}
+func createTestRequestContext() *VariableInstance {
+	r := VariableInstance{}
 
-	RandomMap["randomMock"] = func() string {
+	RandomMap["randomMock"] = RandomWrapper(func(_ *gofakeit.Faker) string {
 		return "randomValue"
-	}
+	})
 
 	mockBody := strings.NewReader(`{"test": "body", "testint": 10}`)
 	r.r, _ = http.NewRequest("GET", "test:8080/url/:param?testquery=queryvalue", mockBody)
@@ -217,6 +218,42 @@ func TestGetBodyJsonVariable(t *testing.T) {
 			expectError:   false,
 			expectValue:   "1",
 		},
+		"Valid Array Index": {
+			inputBody:     `{"items": [{"id": 1}, {"id": 2}]}`,
+			inputVariable: "items[1].id",
+			expectError:   false,
+			expectValue:   "2",
+		},
+		"Valid Key With Dots": {
+			inputBody:     `{"a.b": "value"}`,
+			inputVariable: "['a.b']",
+			expectError:   false,
+			expectValue:   "value",
+		},
+		"Valid Filter": {
+			inputBody:     `{"users": [{"name": "Jim", "admin": true}, {"name": "Andre", "admin": false}]}`,
+			inputVariable: "users[?(@.admin == true)].name",
+			expectError:   false,
+			expectValue:   "Jim",
+		},
+		"Valid Wildcard": {
+			inputBody:     `{"users": [{"name": "Jim"}, {"name": "Andre"}]}`,
+			inputVariable: "users[*].name",
+			expectError:   false,
+			expectValue:   `["Jim","Andre"]`,
+		},
+		"Valid Recursive Descent": {
+			inputBody:     `{"a": {"b": {"id": "value"}}}`,
+			inputVariable: ".id",
+			expectError:   false,
+			expectValue:   "value",
+		},
+		"Invalid Path": {
+			inputBody:     `{"test": "value"}`,
+			inputVariable: "test[",
+			expectError:   true,
+			expectValue:   "",
+		},
 		"Invalid Json": {
 			inputBody:     `{"test": "value"`,
 			inputVariable: "test",